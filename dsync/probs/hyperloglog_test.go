@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/alextanhongpin/core/dsync/probs"
+	"github.com/alextanhongpin/core/dsync/probs/inmem"
 	"github.com/alextanhongpin/core/storage/redis/redistest"
 	"github.com/stretchr/testify/assert"
 )
@@ -44,4 +45,28 @@ func TestHyperLogLog(t *testing.T) {
 		is.Nil(err)
 		is.Equal(int64(4), n)
 	})
+
+	t.Run("export", func(t *testing.T) {
+		hll := probs.NewHyperLogLog(redistest.Client(t))
+		src := inmem.NewHyperLogLog()
+
+		key := t.Name() + ":hll:page_views"
+
+		is := assert.New(t)
+		_, err := hll.Add(ctx, key, "a", "b")
+		is.Nil(err)
+		_, err = src.Add(ctx, key, "b", "c", 1, 2.5)
+		is.Nil(err)
+
+		is.Nil(src.Export(ctx, key, hll, key))
+
+		n, err := hll.Count(ctx, key)
+		is.Nil(err)
+		is.Equal(int64(5), n)
+
+		// The same values hash to the same registers.
+		n, err = hll.Add(ctx, key, "a", "b", "c", 1, 2.5)
+		is.Nil(err)
+		is.Equal(int64(0), n)
+	})
 }
@@ -0,0 +1,273 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/alextanhongpin/core/dsync/probs"
+)
+
+var _ probs.BloomFilterStore = (*BloomFilter)(nil)
+
+// Defaults follows redis BF.ADD.
+const (
+	bloomErrorRate = 0.01
+	bloomCapacity  = 100
+	bloomExpansion = 2
+	// Each new layer has a tighter error rate, so that the overall error rate
+	// stays within the configured error rate.
+	bloomTightening = 0.5
+)
+
+type bloomLayer struct {
+	Bits     []uint64
+	M        uint64 // Number of bits.
+	K        uint64 // Number of hash functions.
+	Capacity int64
+	Count    int64
+}
+
+func newBloomLayer(errorRate float64, capacity int64) *bloomLayer {
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(errorRate) / (math.Ln2 * math.Ln2)))
+	m = max(m, 64)
+	k := uint64(math.Ceil(-math.Log2(errorRate)))
+	k = max(k, 1)
+
+	return &bloomLayer{
+		Bits:     make([]uint64, (m+63)/64),
+		M:        m,
+		K:        k,
+		Capacity: capacity,
+	}
+}
+
+func (l *bloomLayer) add(h1, h2 uint64) {
+	for i := range l.K {
+		n := (h1 + i*h2) % l.M
+		l.Bits[n/64] |= 1 << (n % 64)
+	}
+	l.Count++
+}
+
+func (l *bloomLayer) clone() *bloomLayer {
+	c := *l
+	c.Bits = append([]uint64(nil), l.Bits...)
+
+	return &c
+}
+
+func (l *bloomLayer) exists(h1, h2 uint64) bool {
+	for i := range l.K {
+		n := (h1 + i*h2) % l.M
+		if l.Bits[n/64]&(1<<(n%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// bloom is a scalable bloom filter. A new layer is added when the last
+// layer is full, similar to redis.
+type bloom struct {
+	ErrorRate float64
+	Layers    []*bloomLayer
+}
+
+func newBloom(errorRate float64, capacity int64) *bloom {
+	return &bloom{
+		ErrorRate: errorRate,
+		Layers:    []*bloomLayer{newBloomLayer(errorRate, capacity)},
+	}
+}
+
+func (b *bloom) add(v any) bool {
+	h1, h2 := hash(v)
+	if b.exists(h1, h2) {
+		return false
+	}
+
+	last := b.Layers[len(b.Layers)-1]
+	if last.Count >= last.Capacity {
+		errorRate := b.ErrorRate * math.Pow(bloomTightening, float64(len(b.Layers)))
+		last = newBloomLayer(errorRate, last.Capacity*bloomExpansion)
+		b.Layers = append(b.Layers, last)
+	}
+	last.add(h1, h2)
+
+	return true
+}
+
+func (b *bloom) exists(h1, h2 uint64) bool {
+	for _, l := range b.Layers {
+		if l.exists(h1, h2) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b *bloom) clone() *bloom {
+	c := &bloom{
+		ErrorRate: b.ErrorRate,
+		Layers:    make([]*bloomLayer, len(b.Layers)),
+	}
+	for i, l := range b.Layers {
+		c.Layers[i] = l.clone()
+	}
+
+	return c
+}
+
+// merge sets the bits of the other filter. Both filters must be created with
+// the same error rate and capacity.
+func (b *bloom) merge(o *bloom) error {
+	for i, l := range o.Layers {
+		if i >= len(b.Layers) {
+			b.Layers = append(b.Layers, l.clone())
+			continue
+		}
+
+		dst := b.Layers[i]
+		if dst.M != l.M || dst.K != l.K {
+			return ErrMismatch
+		}
+		for j := range l.Bits {
+			dst.Bits[j] |= l.Bits[j]
+		}
+		// The count is an upper bound, since both filters may share the same
+		// entries.
+		dst.Count += l.Count
+	}
+
+	return nil
+}
+
+func (b *bloom) validate() error {
+	if len(b.Layers) == 0 {
+		return fmt.Errorf("%w: missing layers", ErrInvalidDump)
+	}
+	for _, l := range b.Layers {
+		if l == nil || l.M == 0 || l.K == 0 || uint64(len(l.Bits)) != (l.M+63)/64 {
+			return fmt.Errorf("%w: bits do not match the size", ErrInvalidDump)
+		}
+	}
+
+	return nil
+}
+
+// BloomFilter is the in-memory version of probs.BloomFilter.
+type BloomFilter struct {
+	store *store[bloom]
+}
+
+func NewBloomFilter() *BloomFilter {
+	return &BloomFilter{
+		store: newStore[bloom](),
+	}
+}
+
+func (bf *BloomFilter) Add(ctx context.Context, key string, value any) (bool, error) {
+	bf.store.mu.Lock()
+	defer bf.store.mu.Unlock()
+
+	return bf.getOrCreate(key).add(value), nil
+}
+
+func (bf *BloomFilter) MAdd(ctx context.Context, key string, values ...any) ([]bool, error) {
+	bf.store.mu.Lock()
+	defer bf.store.mu.Unlock()
+
+	b := bf.getOrCreate(key)
+	res := make([]bool, len(values))
+	for i, v := range values {
+		res[i] = b.add(v)
+	}
+
+	return res, nil
+}
+
+func (bf *BloomFilter) Exists(ctx context.Context, key string, value any) (bool, error) {
+	res, err := bf.MExists(ctx, key, value)
+	if err != nil {
+		return false, err
+	}
+
+	return res[0], nil
+}
+
+func (bf *BloomFilter) MExists(ctx context.Context, key string, values ...any) ([]bool, error) {
+	bf.store.mu.Lock()
+	defer bf.store.mu.Unlock()
+
+	res := make([]bool, len(values))
+	b, ok := bf.store.items[key]
+	if !ok {
+		return res, nil
+	}
+
+	for i, v := range values {
+		res[i] = b.exists(hash(v))
+	}
+
+	return res, nil
+}
+
+func (bf *BloomFilter) Reserve(ctx context.Context, key string, errorRate float64, capacity int64) (string, error) {
+	bf.store.mu.Lock()
+	defer bf.store.mu.Unlock()
+
+	if _, ok := bf.store.items[key]; ok {
+		return "", ErrKeyAlreadyExists
+	}
+	bf.store.items[key] = newBloom(errorRate, capacity)
+
+	return OK, nil
+}
+
+// Merge merges the source keys into the destination key. The destination key
+// is created if it does not exist.
+func (bf *BloomFilter) Merge(ctx context.Context, destKey string, sourceKeys ...string) (string, error) {
+	bf.store.mu.Lock()
+	defer bf.store.mu.Unlock()
+
+	dst, ok := bf.store.items[destKey]
+	for _, key := range sourceKeys {
+		src, exists := bf.store.items[key]
+		if !exists {
+			return "", ErrKeyDoesNotExist
+		}
+		if !ok {
+			dst, ok = src.clone(), true
+			continue
+		}
+		if err := dst.merge(src); err != nil {
+			return "", err
+		}
+	}
+	if ok {
+		bf.store.items[destKey] = dst
+	}
+
+	return OK, nil
+}
+
+func (bf *BloomFilter) Dump(ctx context.Context, key string) ([]byte, error) {
+	return bf.store.dump(key)
+}
+
+func (bf *BloomFilter) Restore(ctx context.Context, key string, b []byte) error {
+	return bf.store.restore(key, b)
+}
+
+func (bf *BloomFilter) getOrCreate(key string) *bloom {
+	b, ok := bf.store.items[key]
+	if !ok {
+		b = newBloom(bloomErrorRate, bloomCapacity)
+		bf.store.items[key] = b
+	}
+
+	return b
+}
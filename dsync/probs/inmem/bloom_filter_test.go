@@ -0,0 +1,91 @@
+package inmem_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/alextanhongpin/core/dsync/probs"
+	"github.com/alextanhongpin/core/dsync/probs/inmem"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBloomFilter(t *testing.T) {
+	bf := inmem.NewBloomFilter()
+	key := t.Name()
+
+	is := assert.New(t)
+	added, err := bf.Add(ctx, key, "foo")
+	is.Nil(err)
+	is.True(added)
+
+	added, err = bf.Add(ctx, key, "foo")
+	is.Nil(err)
+	is.False(added)
+
+	exists, err := bf.MExists(ctx, key, "foo", "bar")
+	is.Nil(err)
+	is.Equal([]bool{true, false}, exists)
+
+	_, err = bf.Reserve(ctx, key, 0.01, 100)
+	is.True(probs.KeyAlreadyExistsError(err))
+}
+
+func TestBloomFilter_Scale(t *testing.T) {
+	bf := inmem.NewBloomFilter()
+	key := t.Name()
+
+	is := assert.New(t)
+	_, err := bf.Reserve(ctx, key, 0.01, 100)
+	is.Nil(err)
+
+	n := 1000
+	for i := range n {
+		_, err := bf.Add(ctx, key, i)
+		is.Nil(err)
+	}
+
+	// No false negatives.
+	for i := range n {
+		exists, err := bf.Exists(ctx, key, i)
+		is.Nil(err)
+		is.True(exists)
+	}
+
+	var fp int
+	for i := n; i < 2*n; i++ {
+		exists, err := bf.Exists(ctx, key, i)
+		is.Nil(err)
+		if exists {
+			fp++
+		}
+	}
+	// Each new layer halves the error rate, so the compounded error rate is
+	// bounded by twice the error rate.
+	is.LessOrEqual(float64(fp)/float64(n), 0.02)
+}
+
+func TestBloomFilter_MergeDump(t *testing.T) {
+	bf := inmem.NewBloomFilter()
+
+	is := assert.New(t)
+	for i := range 10 {
+		_, err := bf.Add(ctx, "a", fmt.Sprint("a", i))
+		is.Nil(err)
+		_, err = bf.Add(ctx, "b", fmt.Sprint("b", i))
+		is.Nil(err)
+	}
+
+	status, err := bf.Merge(ctx, "c", "a", "b")
+	is.Nil(err)
+	is.Equal("OK", status)
+
+	b, err := bf.Dump(ctx, "c")
+	is.Nil(err)
+
+	restored := inmem.NewBloomFilter()
+	is.Nil(restored.Restore(ctx, "c", b))
+
+	exists, err := restored.MExists(ctx, "c", "a0", "b9", "c0")
+	is.Nil(err)
+	is.Equal([]bool{true, true, false}, exists)
+}
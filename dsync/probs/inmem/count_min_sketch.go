@@ -0,0 +1,242 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/alextanhongpin/core/dsync/probs"
+)
+
+var _ probs.CountMinSketchStore = (*CountMinSketch)(nil)
+
+type sketch struct {
+	Width    uint64
+	Depth    uint64
+	Counters []int64
+}
+
+func newSketch(width, depth uint64) *sketch {
+	return &sketch{
+		Width:    width,
+		Depth:    depth,
+		Counters: make([]int64, width*depth),
+	}
+}
+
+// newSketchByProb follows the dimensions used by redis CMS.INITBYPROB.
+func newSketchByProb(errorRate, errorProbability float64) *sketch {
+	width := uint64(math.Ceil(2 / errorRate))
+	depth := uint64(math.Ceil(math.Log10(errorProbability) / math.Log10(0.5)))
+
+	return newSketch(width, depth)
+}
+
+func (s *sketch) incrBy(v any, n int64) int64 {
+	h1, h2 := hash(v)
+
+	count := int64(math.MaxInt64)
+	for i := range s.Depth {
+		j := i*s.Width + (h1+i*h2)%s.Width
+		s.Counters[j] += n
+		count = min(count, s.Counters[j])
+	}
+
+	return count
+}
+
+func (s *sketch) query(v any) int64 {
+	h1, h2 := hash(v)
+
+	count := int64(math.MaxInt64)
+	for i := range s.Depth {
+		j := i*s.Width + (h1+i*h2)%s.Width
+		count = min(count, s.Counters[j])
+	}
+
+	return count
+}
+
+func (s *sketch) merge(o *sketch, weight int64) error {
+	if s.Width != o.Width || s.Depth != o.Depth {
+		return ErrMismatch
+	}
+
+	for i, n := range o.Counters {
+		s.Counters[i] += n * weight
+	}
+
+	return nil
+}
+
+func (s *sketch) validate() error {
+	if s.Width == 0 || s.Depth == 0 {
+		return fmt.Errorf("%w: width and depth must be positive", ErrInvalidDump)
+	}
+	if n := uint64(len(s.Counters)); n%s.Width != 0 || n/s.Width != s.Depth {
+		return fmt.Errorf("%w: counters do not match the width and depth", ErrInvalidDump)
+	}
+
+	return nil
+}
+
+// CountMinSketch is the in-memory version of probs.CountMinSketch.
+type CountMinSketch struct {
+	store *store[sketch]
+}
+
+func NewCountMinSketch() *CountMinSketch {
+	return &CountMinSketch{
+		store: newStore[sketch](),
+	}
+}
+
+func (cms *CountMinSketch) Init(ctx context.Context, key string) (status string, exists bool, err error) {
+	errorRate := 0.001
+	errorProb := 0.002
+	return cms.InitByProb(ctx, key, errorRate, errorProb)
+}
+
+func (cms *CountMinSketch) InitByProb(ctx context.Context, key string, errorRate, errorProbability float64) (status string, exists bool, err error) {
+	return cms.init(key, newSketchByProb(errorRate, errorProbability))
+}
+
+func (cms *CountMinSketch) InitByDim(ctx context.Context, key string, width, depth int64) (status string, exists bool, err error) {
+	return cms.init(key, newSketch(uint64(width), uint64(depth)))
+}
+
+func (cms *CountMinSketch) init(key string, s *sketch) (status string, exists bool, err error) {
+	cms.store.mu.Lock()
+	defer cms.store.mu.Unlock()
+
+	if _, ok := cms.store.items[key]; ok {
+		return OK, true, nil
+	}
+	cms.store.items[key] = s
+
+	return OK, false, nil
+}
+
+// IncrBy increments the count of the keys, and returns the counts sorted by
+// the keys.
+func (cms *CountMinSketch) IncrBy(ctx context.Context, key string, kvs map[string]int64) ([]int64, bool, error) {
+	keys := make([]string, 0, len(kvs))
+	for k := range kvs {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	cms.store.mu.Lock()
+	defer cms.store.mu.Unlock()
+
+	s, ok := cms.store.items[key]
+	if !ok {
+		s = newSketchByProb(0.001, 0.002)
+		cms.store.items[key] = s
+	}
+
+	counts := make([]int64, len(keys))
+	for i, k := range keys {
+		counts[i] = s.incrBy(k, kvs[k])
+	}
+
+	return counts, !ok, nil
+}
+
+func (cms *CountMinSketch) Merge(ctx context.Context, destKey string, sourceKeys ...string) (string, error) {
+	weights := make(map[string]int64, len(sourceKeys))
+	for _, k := range sourceKeys {
+		weights[k] = 1
+	}
+
+	return cms.MergeWithWeight(ctx, destKey, weights)
+}
+
+// MergeWithWeight replaces the destination key with the weighted sum of the
+// source keys, similar to redis CMS.MERGE.
+// The destination key is created with the dimensions of the source keys if
+// it does not exist.
+func (cms *CountMinSketch) MergeWithWeight(ctx context.Context, destKey string, sourceKeys map[string]int64) (string, error) {
+	cms.store.mu.Lock()
+	defer cms.store.mu.Unlock()
+
+	var dst *sketch
+	for key, weight := range sourceKeys {
+		src, ok := cms.store.items[key]
+		if !ok {
+			return "", ErrKeyDoesNotExist
+		}
+		if dst == nil {
+			dst = newSketch(src.Width, src.Depth)
+		}
+		if err := dst.merge(src, weight); err != nil {
+			return "", err
+		}
+	}
+	if dst == nil {
+		return OK, nil
+	}
+
+	if old, ok := cms.store.items[destKey]; ok && (old.Width != dst.Width || old.Depth != dst.Depth) {
+		return "", ErrMismatch
+	}
+	cms.store.items[destKey] = dst
+
+	return OK, nil
+}
+
+func (cms *CountMinSketch) Query(ctx context.Context, key string, values ...any) ([]int64, error) {
+	cms.store.mu.Lock()
+	defer cms.store.mu.Unlock()
+
+	s, ok := cms.store.items[key]
+	if !ok {
+		return nil, ErrKeyDoesNotExist
+	}
+
+	counts := make([]int64, len(values))
+	for i, v := range values {
+		counts[i] = s.query(v)
+	}
+
+	return counts, nil
+}
+
+func (cms *CountMinSketch) Dump(ctx context.Context, key string) ([]byte, error) {
+	return cms.store.dump(key)
+}
+
+func (cms *CountMinSketch) Restore(ctx context.Context, key string, b []byte) error {
+	return cms.store.restore(key, b)
+}
+
+// Export replays the estimated counts of the values at the key into the
+// destination key, e.g. a probs.CountMinSketch in redis. The sketch does not
+// keep the values, so the values to export must be known, e.g. from a top-k
+// list. The estimates may overcount, but never undercount.
+func (cms *CountMinSketch) Export(ctx context.Context, key string, dst probs.CountMinSketchStore, destKey string, values ...string) error {
+	args := make([]any, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+
+	counts, err := cms.Query(ctx, key, args...)
+	if err != nil {
+		return err
+	}
+
+	kvs := make(map[string]int64, len(values))
+	for i, v := range values {
+		if counts[i] > 0 {
+			kvs[v] += counts[i]
+		}
+	}
+	if len(kvs) == 0 {
+		return nil
+	}
+
+	_, _, err = dst.IncrBy(ctx, destKey, kvs)
+
+	return err
+}
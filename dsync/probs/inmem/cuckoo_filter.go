@@ -0,0 +1,394 @@
+package inmem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/bits"
+	"math/rand/v2"
+
+	"github.com/alextanhongpin/core/dsync/probs"
+)
+
+var _ probs.CuckooFilterStore = (*CuckooFilter)(nil)
+
+var ErrFilterFull = errors.New("inmem: filter is full")
+
+// Defaults follows redis CF.ADD.
+const (
+	cuckooCapacity   = 1024
+	cuckooBucketSize = 4
+	cuckooMaxKicks   = 500
+	cuckooExpansion  = 2
+)
+
+type cuckooBucket [cuckooBucketSize]uint16
+
+type cuckooLayer struct {
+	Buckets []cuckooBucket
+	Mask    uint64
+	Count   int64
+}
+
+func newCuckooLayer(capacity int64) *cuckooLayer {
+	n := uint64(max(capacity/cuckooBucketSize, 1))
+	// Round up to the power of two, so that the alternate index can be
+	// computed with xor.
+	n = 1 << bits.Len64(n-1)
+
+	return &cuckooLayer{
+		Buckets: make([]cuckooBucket, n),
+		Mask:    n - 1,
+	}
+}
+
+func (l *cuckooLayer) indexes(h uint64, fp uint16) (uint64, uint64) {
+	i := h & l.Mask
+	return i, l.alt(i, fp)
+}
+
+func (l *cuckooLayer) alt(i uint64, fp uint16) uint64 {
+	return (i ^ mix(uint64(fp))) & l.Mask
+}
+
+func (l *cuckooLayer) place(i uint64, fp uint16) bool {
+	b := &l.Buckets[i]
+	for j, v := range b {
+		if v == 0 {
+			b[j] = fp
+			l.Count++
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func (l *cuckooLayer) insert(h uint64, fp uint16) bool {
+	i1, i2 := l.indexes(h, fp)
+	if l.place(i1, fp) || l.place(i2, fp) {
+		return true
+	}
+
+	return l.relocate(i1, fp)
+}
+
+// relocate kicks out existing fingerprints to their alternate bucket to make
+// room. The kicks are reverted when no room is found.
+func (l *cuckooLayer) relocate(i uint64, fp uint16) bool {
+	type kick struct {
+		i  uint64
+		j  int
+		fp uint16
+	}
+	kicks := make([]kick, 0, cuckooMaxKicks)
+
+	for range cuckooMaxKicks {
+		j := rand.IntN(cuckooBucketSize)
+		kicks = append(kicks, kick{i: i, j: j, fp: l.Buckets[i][j]})
+		fp, l.Buckets[i][j] = l.Buckets[i][j], fp
+
+		i = l.alt(i, fp)
+		if l.place(i, fp) {
+			return true
+		}
+	}
+
+	for k := len(kicks) - 1; k >= 0; k-- {
+		l.Buckets[kicks[k].i][kicks[k].j] = kicks[k].fp
+	}
+
+	return false
+}
+
+func (l *cuckooLayer) count(h uint64, fp uint16) int64 {
+	i1, i2 := l.indexes(h, fp)
+
+	var n int64
+	for _, v := range l.Buckets[i1] {
+		if v == fp {
+			n++
+		}
+	}
+	if i1 == i2 {
+		return n
+	}
+	for _, v := range l.Buckets[i2] {
+		if v == fp {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (l *cuckooLayer) delete(h uint64, fp uint16) bool {
+	i1, i2 := l.indexes(h, fp)
+	for _, i := range []uint64{i1, i2} {
+		b := &l.Buckets[i]
+		for j, v := range b {
+			if v == fp {
+				b[j] = 0
+				l.Count--
+
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// cuckoo is a scalable cuckoo filter. A new layer is added when the last
+// layer is full, similar to redis.
+type cuckoo struct {
+	Layers []*cuckooLayer
+}
+
+func newCuckoo(capacity int64) *cuckoo {
+	return &cuckoo{
+		Layers: []*cuckooLayer{newCuckooLayer(capacity)},
+	}
+}
+
+func (c *cuckoo) add(v any) bool {
+	h, fp := fingerprint(v)
+	last := c.Layers[len(c.Layers)-1]
+	if last.insert(h, fp) {
+		return true
+	}
+
+	last = newCuckooLayer(int64(len(last.Buckets)) * cuckooBucketSize * cuckooExpansion)
+	c.Layers = append(c.Layers, last)
+
+	return last.insert(h, fp)
+}
+
+func (c *cuckoo) count(v any) int64 {
+	h, fp := fingerprint(v)
+
+	var n int64
+	for _, l := range c.Layers {
+		n += l.count(h, fp)
+	}
+
+	return n
+}
+
+func (c *cuckoo) delete(v any) bool {
+	h, fp := fingerprint(v)
+	for i := len(c.Layers) - 1; i >= 0; i-- {
+		if c.Layers[i].delete(h, fp) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *cuckoo) clone() *cuckoo {
+	o := &cuckoo{
+		Layers: make([]*cuckooLayer, len(c.Layers)),
+	}
+	for i, l := range c.Layers {
+		cl := *l
+		cl.Buckets = append([]cuckooBucket(nil), l.Buckets...)
+		o.Layers[i] = &cl
+	}
+
+	return o
+}
+
+// merge inserts the fingerprints of the other filter. Both filters must be
+// created with the same capacity.
+func (c *cuckoo) merge(o *cuckoo) error {
+	for i, l := range o.Layers {
+		if i >= len(c.Layers) {
+			c.Layers = append(c.Layers, newCuckooLayer(int64(len(l.Buckets))*cuckooBucketSize))
+		}
+
+		dst := c.Layers[i]
+		if dst.Mask != l.Mask {
+			return ErrMismatch
+		}
+		for idx, b := range l.Buckets {
+			for _, fp := range b {
+				if fp == 0 {
+					continue
+				}
+				if !dst.place(uint64(idx), fp) && !dst.place(dst.alt(uint64(idx), fp), fp) && !dst.relocate(uint64(idx), fp) {
+					return ErrFilterFull
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *cuckoo) validate() error {
+	if len(c.Layers) == 0 {
+		return fmt.Errorf("%w: missing layers", ErrInvalidDump)
+	}
+	for _, l := range c.Layers {
+		if l == nil || len(l.Buckets) == 0 || uint64(len(l.Buckets)) != l.Mask+1 || l.Mask&(l.Mask+1) != 0 {
+			return fmt.Errorf("%w: buckets do not match the mask", ErrInvalidDump)
+		}
+	}
+
+	return nil
+}
+
+// fingerprint returns the hash and the non-zero fingerprint of the value.
+func fingerprint(v any) (uint64, uint16) {
+	h, _ := hash(v)
+	fp := uint16(h >> 48)
+	if fp == 0 {
+		fp = 1
+	}
+
+	return h, fp
+}
+
+// CuckooFilter is the in-memory version of probs.CuckooFilter.
+type CuckooFilter struct {
+	store *store[cuckoo]
+}
+
+func NewCuckooFilter() *CuckooFilter {
+	return &CuckooFilter{
+		store: newStore[cuckoo](),
+	}
+}
+
+func (cf *CuckooFilter) Add(ctx context.Context, key, value string) (bool, error) {
+	cf.store.mu.Lock()
+	defer cf.store.mu.Unlock()
+
+	if !cf.getOrCreate(key).add(value) {
+		return false, ErrFilterFull
+	}
+
+	return true, nil
+}
+
+func (cf *CuckooFilter) AddNX(ctx context.Context, key, value string) (bool, error) {
+	cf.store.mu.Lock()
+	defer cf.store.mu.Unlock()
+
+	c := cf.getOrCreate(key)
+	if c.count(value) > 0 {
+		return false, nil
+	}
+
+	if !c.add(value) {
+		return false, ErrFilterFull
+	}
+
+	return true, nil
+}
+
+func (cf *CuckooFilter) Exists(ctx context.Context, key, value string) (bool, error) {
+	n, err := cf.Count(ctx, key, value)
+	return n > 0, err
+}
+
+func (cf *CuckooFilter) Count(ctx context.Context, key, value string) (int64, error) {
+	cf.store.mu.Lock()
+	defer cf.store.mu.Unlock()
+
+	c, ok := cf.store.items[key]
+	if !ok {
+		return 0, nil
+	}
+
+	return c.count(value), nil
+}
+
+func (cf *CuckooFilter) MExists(ctx context.Context, key string, values ...any) ([]bool, error) {
+	cf.store.mu.Lock()
+	defer cf.store.mu.Unlock()
+
+	res := make([]bool, len(values))
+	c, ok := cf.store.items[key]
+	if !ok {
+		return res, nil
+	}
+
+	for i, v := range values {
+		res[i] = c.count(v) > 0
+	}
+
+	return res, nil
+}
+
+func (cf *CuckooFilter) Delete(ctx context.Context, key, value string) (bool, error) {
+	cf.store.mu.Lock()
+	defer cf.store.mu.Unlock()
+
+	c, ok := cf.store.items[key]
+	if !ok {
+		return false, ErrKeyDoesNotExist
+	}
+
+	return c.delete(value), nil
+}
+
+// Reserve creates an empty filter with the given capacity.
+func (cf *CuckooFilter) Reserve(ctx context.Context, key string, capacity int64) (string, error) {
+	cf.store.mu.Lock()
+	defer cf.store.mu.Unlock()
+
+	if _, ok := cf.store.items[key]; ok {
+		return "", ErrKeyAlreadyExists
+	}
+	cf.store.items[key] = newCuckoo(capacity)
+
+	return OK, nil
+}
+
+// Merge merges the source keys into the destination key. The destination key
+// is created if it does not exist.
+func (cf *CuckooFilter) Merge(ctx context.Context, destKey string, sourceKeys ...string) (string, error) {
+	cf.store.mu.Lock()
+	defer cf.store.mu.Unlock()
+
+	dst, ok := cf.store.items[destKey]
+	for _, key := range sourceKeys {
+		src, exists := cf.store.items[key]
+		if !exists {
+			return "", ErrKeyDoesNotExist
+		}
+		if !ok {
+			dst, ok = src.clone(), true
+			continue
+		}
+		if err := dst.merge(src); err != nil {
+			return "", err
+		}
+	}
+	if ok {
+		cf.store.items[destKey] = dst
+	}
+
+	return OK, nil
+}
+
+func (cf *CuckooFilter) Dump(ctx context.Context, key string) ([]byte, error) {
+	return cf.store.dump(key)
+}
+
+func (cf *CuckooFilter) Restore(ctx context.Context, key string, b []byte) error {
+	return cf.store.restore(key, b)
+}
+
+func (cf *CuckooFilter) getOrCreate(key string) *cuckoo {
+	c, ok := cf.store.items[key]
+	if !ok {
+		c = newCuckoo(cuckooCapacity)
+		cf.store.items[key] = c
+	}
+
+	return c
+}
@@ -0,0 +1,92 @@
+package inmem_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/alextanhongpin/core/dsync/probs/inmem"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCuckooFilter(t *testing.T) {
+	cf := inmem.NewCuckooFilter()
+	key := t.Name()
+
+	is := assert.New(t)
+	added, err := cf.Add(ctx, key, "foo")
+	is.Nil(err)
+	is.True(added)
+
+	added, err = cf.AddNX(ctx, key, "foo")
+	is.Nil(err)
+	is.False(added)
+
+	added, err = cf.Add(ctx, key, "foo")
+	is.Nil(err)
+	is.True(added)
+
+	n, err := cf.Count(ctx, key, "foo")
+	is.Nil(err)
+	is.Equal(int64(2), n)
+
+	deleted, err := cf.Delete(ctx, key, "foo")
+	is.Nil(err)
+	is.True(deleted)
+
+	exists, err := cf.MExists(ctx, key, "foo", "bar")
+	is.Nil(err)
+	is.Equal([]bool{true, false}, exists)
+
+	deleted, err = cf.Delete(ctx, key, "foo")
+	is.Nil(err)
+	is.True(deleted)
+
+	ok, err := cf.Exists(ctx, key, "foo")
+	is.Nil(err)
+	is.False(ok)
+}
+
+func TestCuckooFilter_Scale(t *testing.T) {
+	cf := inmem.NewCuckooFilter()
+	key := t.Name()
+
+	is := assert.New(t)
+	_, err := cf.Reserve(ctx, key, 64)
+	is.Nil(err)
+
+	n := 1000
+	for i := range n {
+		_, err := cf.Add(ctx, key, fmt.Sprint(i))
+		is.Nil(err)
+	}
+
+	// No false negatives.
+	for i := range n {
+		exists, err := cf.Exists(ctx, key, fmt.Sprint(i))
+		is.Nil(err)
+		is.True(exists)
+	}
+}
+
+func TestCuckooFilter_MergeDump(t *testing.T) {
+	cf := inmem.NewCuckooFilter()
+
+	is := assert.New(t)
+	_, err := cf.Add(ctx, "a", "foo")
+	is.Nil(err)
+	_, err = cf.Add(ctx, "b", "bar")
+	is.Nil(err)
+
+	_, err = cf.Merge(ctx, "c", "a", "b")
+	is.Nil(err)
+
+	b, err := cf.Dump(ctx, "c")
+	is.Nil(err)
+
+	restored := inmem.NewCuckooFilter()
+	is.Nil(restored.Restore(ctx, "c", b))
+
+	exists, err := restored.MExists(ctx, "c", "foo", "bar", "baz")
+	is.Nil(err)
+	is.Equal([]bool{true, true, false}, exists)
+}
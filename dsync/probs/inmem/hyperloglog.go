@@ -0,0 +1,297 @@
+package inmem
+
+import (
+	"context"
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"time"
+
+	"github.com/alextanhongpin/core/dsync/probs"
+	redis "github.com/redis/go-redis/v9"
+)
+
+var _ probs.HyperLogLogStore = (*HyperLogLog)(nil)
+
+// The precision and the hash follows redis, which has a standard error of
+// 0.81%, so that the registers can be exported to redis.
+// See https://github.com/redis/redis/blob/unstable/src/hyperloglog.c.
+const (
+	hllPrecision = 14
+	hllRegisters = 1 << hllPrecision
+	hllMaxRank   = 64 - hllPrecision + 1
+	hllSeed      = 0xadc83b19
+
+	// The dense representation packs each register in 6 bits, after a 16
+	// bytes header.
+	hllBits       = 6
+	hllHeaderSize = 16
+	hllDenseSize  = hllHeaderSize + (hllRegisters*hllBits+7)/8
+)
+
+type hll struct {
+	Registers []uint8
+}
+
+func newHLL() *hll {
+	return &hll{
+		Registers: make([]uint8, hllRegisters),
+	}
+}
+
+// add returns true if the estimated cardinality may have changed.
+func (h *hll) add(v any) bool {
+	x := murmurHash64A(element(v), hllSeed)
+	i := x & (hllRegisters - 1)
+	// Count the trailing zeros of the remaining bits. The sentinel bit ensures
+	// the rank does not exceed the remaining bits.
+	w := x>>hllPrecision | 1<<(64-hllPrecision)
+	rank := uint8(bits.TrailingZeros64(w) + 1)
+	if rank > h.Registers[i] {
+		h.Registers[i] = rank
+
+		return true
+	}
+
+	return false
+}
+
+func (h *hll) merge(o *hll) {
+	for i, r := range o.Registers {
+		h.Registers[i] = max(h.Registers[i], r)
+	}
+}
+
+func (h *hll) count() int64 {
+	m := float64(hllRegisters)
+
+	var sum float64
+	var zeros int
+	for _, r := range h.Registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	alpha := 0.7213 / (1 + 1.079/m)
+	est := alpha * m * m / sum
+
+	// Use linear counting for small cardinalities.
+	if est <= 5*m/2 && zeros > 0 {
+		est = m * math.Log(m/float64(zeros))
+	}
+
+	return int64(math.Round(est))
+}
+
+func (h *hll) clone() *hll {
+	return &hll{
+		Registers: append([]uint8(nil), h.Registers...),
+	}
+}
+
+// dense encodes the registers in the redis dense representation. The cached
+// cardinality in the header is marked as invalid, so that redis recomputes it.
+func (h *hll) dense() []byte {
+	b := make([]byte, hllDenseSize)
+	copy(b, "HYLL")
+	b[15] = 1 << 7
+
+	regs := b[hllHeaderSize:]
+	for i, r := range h.Registers {
+		n, shift := i*hllBits/8, i*hllBits%8
+		regs[n] |= r << shift
+		if shift > 8-hllBits {
+			regs[n+1] |= r >> (8 - shift)
+		}
+	}
+
+	return b
+}
+
+func (h *hll) validate() error {
+	if len(h.Registers) != hllRegisters {
+		return fmt.Errorf("%w: expected %d registers, got %d", ErrInvalidDump, hllRegisters, len(h.Registers))
+	}
+	for _, r := range h.Registers {
+		if r > hllMaxRank {
+			return fmt.Errorf("%w: register exceeds the max rank", ErrInvalidDump)
+		}
+	}
+
+	return nil
+}
+
+// HyperLogLog is the in-memory version of probs.HyperLogLog.
+type HyperLogLog struct {
+	store *store[hll]
+}
+
+func NewHyperLogLog() *HyperLogLog {
+	return &HyperLogLog{
+		store: newStore[hll](),
+	}
+}
+
+// Add returns 1 if at least one internal register was altered, similar to
+// redis PFADD.
+func (c *HyperLogLog) Add(ctx context.Context, key string, values ...any) (int64, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	h, ok := c.store.items[key]
+	if !ok {
+		h = newHLL()
+		c.store.items[key] = h
+	}
+
+	altered := !ok
+	for _, v := range values {
+		if h.add(v) {
+			altered = true
+		}
+	}
+
+	if altered {
+		return 1, nil
+	}
+
+	return 0, nil
+}
+
+// Count returns the approximated cardinality of the union of the keys.
+func (c *HyperLogLog) Count(ctx context.Context, keys ...string) (int64, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	union := newHLL()
+	for _, key := range keys {
+		if h, ok := c.store.items[key]; ok {
+			union.merge(h)
+		}
+	}
+
+	return union.count(), nil
+}
+
+// Merge merges the source keys into the destination key, similar to redis
+// PFMERGE.
+func (c *HyperLogLog) Merge(ctx context.Context, destKey string, srcKeys ...string) (string, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	dst := newHLL()
+	if h, ok := c.store.items[destKey]; ok {
+		dst = h.clone()
+	}
+	for _, key := range srcKeys {
+		if h, ok := c.store.items[key]; ok {
+			dst.merge(h)
+		}
+	}
+	c.store.items[destKey] = dst
+
+	return OK, nil
+}
+
+func (c *HyperLogLog) Dump(ctx context.Context, key string) ([]byte, error) {
+	return c.store.dump(key)
+}
+
+func (c *HyperLogLog) Restore(ctx context.Context, key string, b []byte) error {
+	return c.store.restore(key, b)
+}
+
+// Export merges the registers at the key into the destination key in redis,
+// similar to PFMERGE. The registers are written to a temporary key in the
+// redis dense representation, which is removed after the merge.
+func (c *HyperLogLog) Export(ctx context.Context, key string, dst *probs.HyperLogLog, destKey string) error {
+	c.store.mu.Lock()
+	h, ok := c.store.items[key]
+	var b []byte
+	if ok {
+		b = h.dense()
+	}
+	c.store.mu.Unlock()
+	if !ok {
+		return ErrKeyDoesNotExist
+	}
+
+	tmp := destKey + ":inmem"
+	_, err := dst.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, tmp, b, 0)
+		pipe.PFMerge(ctx, destKey, tmp)
+		pipe.Del(ctx, tmp)
+
+		return nil
+	})
+
+	return err
+}
+
+// element returns the bytes of the value as sent by the redis client, so that
+// the same value hashes to the same register in redis.
+func element(v any) []byte {
+	switch v := v.(type) {
+	case string:
+		return []byte(v)
+	case []byte:
+		return v
+	case float32:
+		return strconv.AppendFloat(nil, float64(v), 'f', -1, 64)
+	case float64:
+		return strconv.AppendFloat(nil, v, 'f', -1, 64)
+	case bool:
+		if v {
+			return []byte("1")
+		}
+		return []byte("0")
+	case time.Time:
+		return v.AppendFormat(nil, time.RFC3339Nano)
+	case time.Duration:
+		return strconv.AppendInt(nil, v.Nanoseconds(), 10)
+	case encoding.BinaryMarshaler:
+		b, err := v.MarshalBinary()
+		if err == nil {
+			return b
+		}
+	}
+
+	return []byte(fmt.Sprint(v))
+}
+
+// murmurHash64A is the hash used by redis hyperloglog.
+func murmurHash64A(b []byte, seed uint64) uint64 {
+	const (
+		m = 0xc6a4a7935bd1e995
+		r = 47
+	)
+
+	h := seed ^ uint64(len(b))*m
+	for ; len(b) >= 8; b = b[8:] {
+		k := binary.LittleEndian.Uint64(b)
+		k *= m
+		k ^= k >> r
+		k *= m
+
+		h ^= k
+		h *= m
+	}
+
+	if len(b) > 0 {
+		for i := len(b) - 1; i >= 0; i-- {
+			h ^= uint64(b[i]) << (8 * i)
+		}
+		h *= m
+	}
+
+	h ^= h >> r
+	h *= m
+	h ^= h >> r
+
+	return h
+}
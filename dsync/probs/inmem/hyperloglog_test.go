@@ -0,0 +1,67 @@
+package inmem_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/alextanhongpin/core/dsync/probs/inmem"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	hll := inmem.NewHyperLogLog()
+
+	is := assert.New(t)
+	n, err := hll.Add(ctx, "a", "foo", "bar")
+	is.Nil(err)
+	is.Equal(int64(1), n)
+
+	n, err = hll.Add(ctx, "a", "foo")
+	is.Nil(err)
+	is.Equal(int64(0), n)
+
+	n, err = hll.Count(ctx, "a")
+	is.Nil(err)
+	is.Equal(int64(2), n)
+
+	_, err = hll.Add(ctx, "b", "bar", "baz")
+	is.Nil(err)
+
+	n, err = hll.Count(ctx, "a", "b")
+	is.Nil(err)
+	is.Equal(int64(3), n)
+
+	status, err := hll.Merge(ctx, "c", "a", "b")
+	is.Nil(err)
+	is.Equal("OK", status)
+
+	b, err := hll.Dump(ctx, "c")
+	is.Nil(err)
+
+	restored := inmem.NewHyperLogLog()
+	is.Nil(restored.Restore(ctx, "c", b))
+
+	n, err = restored.Count(ctx, "c")
+	is.Nil(err)
+	is.Equal(int64(3), n)
+}
+
+func TestHyperLogLog_Accuracy(t *testing.T) {
+	hll := inmem.NewHyperLogLog()
+
+	is := assert.New(t)
+	for _, n := range []int{1_000, 10_000, 100_000} {
+		key := fmt.Sprint(n)
+		for i := range n {
+			_, err := hll.Add(ctx, key, i)
+			is.Nil(err)
+		}
+
+		count, err := hll.Count(ctx, key)
+		is.Nil(err)
+
+		// Standard error is 0.81%, allow 3 standard errors.
+		is.Less(math.Abs(float64(count)-float64(n))/float64(n), 0.025, n)
+	}
+}
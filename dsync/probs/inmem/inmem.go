@@ -0,0 +1,116 @@
+// Package inmem implements the probabilistic data structures in package probs
+// in pure Go, without redis.
+//
+// The structures share the same method sets as their redis counterparts, so
+// they can be swapped through the interfaces in package probs. Each structure
+// can be serialised with Dump and Restore, e.g. to take snapshots. The dumps
+// are gob encoded, so they can only be restored by this package.
+//
+// To ship the structures to redis, use Export. The count-min sketch and the
+// top-k replay their counts into any store in package probs, while the
+// hyperloglog writes its registers in the redis dense representation.
+package inmem
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
+const OK = "OK"
+
+// The error messages share the same suffix as redis, so that
+// probs.KeyAlreadyExistsError and probs.KeyDoesNotExistError works.
+var (
+	ErrKeyAlreadyExists = errors.New("inmem: key already exists")
+	ErrKeyDoesNotExist  = errors.New("inmem: key does not exist")
+	ErrMismatch         = errors.New("inmem: mismatched dimensions")
+	ErrInvalidDump      = errors.New("inmem: invalid dump")
+)
+
+// validator checks the decoded structure before it is restored, so that a
+// corrupt dump does not panic on use.
+type validator interface {
+	validate() error
+}
+
+// store holds the structures by key.
+type store[T any] struct {
+	mu    sync.Mutex
+	items map[string]*T
+}
+
+func newStore[T any]() *store[T] {
+	return &store[T]{
+		items: make(map[string]*T),
+	}
+}
+
+// dump encodes the structure at the key.
+func (s *store[T]) dump(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.items[key]
+	if !ok {
+		return nil, ErrKeyDoesNotExist
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// restore decodes the structure into the key, replacing the existing one.
+func (s *store[T]) restore(key string, b []byte) error {
+	v := new(T)
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(v); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidDump, err)
+	}
+	if v, ok := any(v).(validator); ok {
+		if err := v.validate(); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	s.items[key] = v
+	s.mu.Unlock()
+
+	return nil
+}
+
+// hash returns two independent hashes of the value, used for double hashing.
+func hash(v any) (uint64, uint64) {
+	b := []byte(fmt.Sprint(v))
+
+	h := fnv.New64a()
+	h.Write(b)
+	h1 := mix(h.Sum64())
+
+	h.Reset()
+	h.Write(b)
+	h.Write([]byte{0xff})
+	h2 := mix(h.Sum64())
+
+	// Ensure h2 is odd, so that it does not cycle early.
+	return h1, h2 | 1
+}
+
+// mix improves the distribution of the lower bits.
+// See splitmix64.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x
+}
@@ -0,0 +1,88 @@
+package inmem_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"testing"
+
+	"github.com/alextanhongpin/core/dsync/probs/inmem"
+	"github.com/stretchr/testify/assert"
+)
+
+var ctx = context.Background()
+
+func TestRestore_Invalid(t *testing.T) {
+	// The dumps are gob encoded, which decodes the fields by name.
+	type sketch struct {
+		Width    uint64
+		Depth    uint64
+		Counters []int64
+	}
+	type hll struct {
+		Registers []uint8
+	}
+	type heavyKeeper struct {
+		K       int64
+		Width   uint64
+		Depth   uint64
+		Decay   float64
+		Buckets []struct{ Count int64 }
+	}
+	type layer struct {
+		Bits []uint64
+		M    uint64
+		K    uint64
+	}
+	type bloom struct {
+		ErrorRate float64
+		Layers    []layer
+	}
+	type cuckooLayer struct {
+		Buckets [][4]uint16
+		Mask    uint64
+	}
+	type cuckoo struct {
+		Layers []cuckooLayer
+	}
+	type digest struct {
+		Compression float64
+		Count       float64
+	}
+
+	type restorer interface {
+		Restore(ctx context.Context, key string, b []byte) error
+	}
+
+	tests := []struct {
+		name string
+		r    restorer
+		v    any
+	}{
+		{"count-min sketch", inmem.NewCountMinSketch(), sketch{Width: 10, Depth: 2, Counters: make([]int64, 10)}},
+		{"count-min sketch zero width", inmem.NewCountMinSketch(), sketch{Depth: 2, Counters: make([]int64, 1)}},
+		{"hyperloglog", inmem.NewHyperLogLog(), hll{Registers: make([]uint8, 10)}},
+		{"hyperloglog rank", inmem.NewHyperLogLog(), hll{Registers: bytes.Repeat([]byte{64}, 1<<14)}},
+		{"top-k", inmem.NewTopK(), heavyKeeper{K: 1, Width: 8, Depth: 2, Decay: 0.9, Buckets: make([]struct{ Count int64 }, 8)}},
+		{"top-k zero k", inmem.NewTopK(), heavyKeeper{Width: 1, Depth: 1, Decay: 0.9, Buckets: make([]struct{ Count int64 }, 1)}},
+		{"bloom filter", inmem.NewBloomFilter(), bloom{ErrorRate: 0.01, Layers: []layer{{Bits: make([]uint64, 1), M: 1000, K: 7}}}},
+		{"cuckoo filter", inmem.NewCuckooFilter(), cuckoo{Layers: []cuckooLayer{{Buckets: make([][4]uint16, 3), Mask: 3}}}},
+		{"t-digest", inmem.NewTDigest(), digest{Count: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			is := assert.New(t)
+			is.Nil(gob.NewEncoder(&buf).Encode(tt.v))
+
+			err := tt.r.Restore(ctx, t.Name(), buf.Bytes())
+			is.ErrorIs(err, inmem.ErrInvalidDump)
+		})
+	}
+
+	t.Run("malformed", func(t *testing.T) {
+		err := inmem.NewCountMinSketch().Restore(ctx, t.Name(), []byte("foo"))
+		assert.ErrorIs(t, err, inmem.ErrInvalidDump)
+	})
+}
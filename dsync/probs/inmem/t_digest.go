@@ -0,0 +1,438 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/alextanhongpin/core/dsync/probs"
+)
+
+var _ probs.TDigestStore = (*TDigest)(nil)
+
+// Defaults follows redis TDIGEST.CREATE.
+const tdigestCompression = 100
+
+type centroid struct {
+	Mean   float64
+	Weight float64
+}
+
+// digest is a merging t-digest.
+// See https://github.com/tdunning/t-digest.
+type digest struct {
+	Compression float64
+	Centroids   []centroid
+	Buffer      []centroid
+	Count       float64
+	Min         float64
+	Max         float64
+}
+
+func newDigest(compression float64) *digest {
+	return &digest{
+		Compression: compression,
+		Min:         math.NaN(),
+		Max:         math.NaN(),
+	}
+}
+
+func (d *digest) add(x, w float64) {
+	if math.IsNaN(x) {
+		return
+	}
+
+	d.Buffer = append(d.Buffer, centroid{Mean: x, Weight: w})
+	d.Count += w
+	if math.IsNaN(d.Min) || x < d.Min {
+		d.Min = x
+	}
+	if math.IsNaN(d.Max) || x > d.Max {
+		d.Max = x
+	}
+
+	if len(d.Buffer) >= int(5*d.Compression) {
+		d.compress()
+	}
+}
+
+// compress merges the buffered values into the centroids.
+func (d *digest) compress() {
+	if len(d.Buffer) == 0 {
+		return
+	}
+
+	all := append(d.Centroids, d.Buffer...)
+	slices.SortFunc(all, func(a, b centroid) int {
+		switch {
+		case a.Mean < b.Mean:
+			return -1
+		case a.Mean > b.Mean:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	merged := make([]centroid, 0, len(d.Centroids))
+	curr := all[0]
+	var sofar float64
+	for _, c := range all[1:] {
+		proposed := curr.Weight + c.Weight
+		q := (sofar + proposed/2) / d.Count
+		limit := 4 * d.Count * q * (1 - q) / d.Compression
+		if proposed <= max(limit, 1) {
+			curr.Mean += (c.Mean - curr.Mean) * c.Weight / proposed
+			curr.Weight = proposed
+
+			continue
+		}
+
+		sofar += curr.Weight
+		merged = append(merged, curr)
+		curr = c
+	}
+	merged = append(merged, curr)
+
+	d.Centroids = merged
+	d.Buffer = nil
+}
+
+func (d *digest) merge(o *digest) {
+	o.compress()
+	for _, c := range o.Centroids {
+		d.Buffer = append(d.Buffer, c)
+		d.Count += c.Weight
+	}
+	if math.IsNaN(d.Min) || o.Min < d.Min {
+		d.Min = o.Min
+	}
+	if math.IsNaN(d.Max) || o.Max > d.Max {
+		d.Max = o.Max
+	}
+	d.compress()
+}
+
+func (d *digest) quantile(q float64) float64 {
+	d.compress()
+	if d.Count == 0 || q < 0 || q > 1 {
+		return math.NaN()
+	}
+	if q == 0 {
+		return d.Min
+	}
+	if q == 1 {
+		return d.Max
+	}
+
+	target := q * d.Count
+
+	// Interpolate between the centers of the centroids, and between the
+	// min/max and the first/last centroid.
+	prevMean, prevPos := d.Min, 0.0
+	var sofar float64
+	for _, c := range d.Centroids {
+		pos := sofar + c.Weight/2
+		if target < pos {
+			return lerp(prevMean, c.Mean, (target-prevPos)/(pos-prevPos))
+		}
+		prevMean, prevPos = c.Mean, pos
+		sofar += c.Weight
+	}
+
+	return lerp(prevMean, d.Max, (target-prevPos)/(d.Count-prevPos))
+}
+
+func (d *digest) cdf(x float64) float64 {
+	d.compress()
+	if d.Count == 0 {
+		return math.NaN()
+	}
+	if x < d.Min {
+		return 0
+	}
+	if x >= d.Max {
+		return 1
+	}
+
+	prevMean, prevPos := d.Min, 0.0
+	var sofar float64
+	for _, c := range d.Centroids {
+		pos := sofar + c.Weight/2
+		if x < c.Mean {
+			if c.Mean == prevMean {
+				return prevPos / d.Count
+			}
+			return lerp(prevPos, pos, (x-prevMean)/(c.Mean-prevMean)) / d.Count
+		}
+		prevMean, prevPos = c.Mean, pos
+		sofar += c.Weight
+	}
+
+	return lerp(prevPos, d.Count, (x-prevMean)/(d.Max-prevMean)) / d.Count
+}
+
+// rank follows redis TDIGEST.RANK, which returns -1 when the value is less
+// than the min, and the count when the value is more than the max.
+func (d *digest) rank(x float64) int64 {
+	if d.Count == 0 {
+		return -2
+	}
+	if x < d.Min {
+		return -1
+	}
+	if x > d.Max {
+		return int64(d.Count)
+	}
+
+	return int64(math.Round(d.cdf(x) * d.Count))
+}
+
+// revRank follows redis TDIGEST.REVRANK, which returns -1 when the value is
+// more than the max, and the count when the value is less than the min.
+func (d *digest) revRank(x float64) int64 {
+	if d.Count == 0 {
+		return -2
+	}
+	if x > d.Max {
+		return -1
+	}
+	if x < d.Min {
+		return int64(d.Count)
+	}
+
+	return int64(math.Round((1 - d.cdf(x)) * d.Count))
+}
+
+func (d *digest) byRank(r uint64) float64 {
+	if d.Count == 0 {
+		return math.NaN()
+	}
+	if float64(r) >= d.Count {
+		return math.Inf(1)
+	}
+
+	return d.quantile((float64(r) + 0.5) / d.Count)
+}
+
+func (d *digest) trimmedMean(lo, hi float64) float64 {
+	d.compress()
+	if d.Count == 0 {
+		return math.NaN()
+	}
+
+	lower, upper := lo*d.Count, hi*d.Count
+
+	var sum, weight, sofar float64
+	for _, c := range d.Centroids {
+		// Include the overlapping portion of the centroid.
+		start, end := sofar, sofar+c.Weight
+		w := min(end, upper) - max(start, lower)
+		if w > 0 {
+			sum += c.Mean * w
+			weight += w
+		}
+		sofar = end
+	}
+	if weight == 0 {
+		return math.NaN()
+	}
+
+	return sum / weight
+}
+
+func (d *digest) clone() *digest {
+	c := *d
+	c.Centroids = append([]centroid(nil), d.Centroids...)
+	c.Buffer = append([]centroid(nil), d.Buffer...)
+
+	return &c
+}
+
+func (d *digest) validate() error {
+	if !(d.Compression > 0) {
+		return fmt.Errorf("%w: compression must be positive", ErrInvalidDump)
+	}
+
+	return nil
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+// TDigest is the in-memory version of probs.TDigest.
+type TDigest struct {
+	store *store[digest]
+}
+
+func NewTDigest() *TDigest {
+	return &TDigest{
+		store: newStore[digest](),
+	}
+}
+
+func (t *TDigest) CreateWithCompression(ctx context.Context, key string, compression int64) (status string, exists bool, err error) {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	if _, ok := t.store.items[key]; ok {
+		return OK, true, nil
+	}
+	t.store.items[key] = newDigest(float64(compression))
+
+	return OK, false, nil
+}
+
+func (t *TDigest) Create(ctx context.Context, key string) (status string, exists bool, err error) {
+	return t.CreateWithCompression(ctx, key, tdigestCompression)
+}
+
+func (t *TDigest) Add(ctx context.Context, key string, values ...float64) (string, error) {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	d, ok := t.store.items[key]
+	if !ok {
+		d = newDigest(tdigestCompression)
+		t.store.items[key] = d
+	}
+
+	for _, v := range values {
+		d.add(v, 1)
+	}
+
+	return OK, nil
+}
+
+func (t *TDigest) CDF(ctx context.Context, key string, values ...float64) ([]float64, error) {
+	return each(t, key, values, (*digest).cdf)
+}
+
+func (t *TDigest) Quantile(ctx context.Context, key string, values ...float64) ([]float64, error) {
+	return each(t, key, values, (*digest).quantile)
+}
+
+func (t *TDigest) Min(ctx context.Context, key string) (float64, error) {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	d, ok := t.store.items[key]
+	if !ok {
+		return 0, ErrKeyDoesNotExist
+	}
+
+	return d.Min, nil
+}
+
+func (t *TDigest) Max(ctx context.Context, key string) (float64, error) {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	d, ok := t.store.items[key]
+	if !ok {
+		return 0, ErrKeyDoesNotExist
+	}
+
+	return d.Max, nil
+}
+
+func (t *TDigest) Rank(ctx context.Context, key string, values ...float64) ([]int64, error) {
+	return each(t, key, values, (*digest).rank)
+}
+
+func (t *TDigest) RevRank(ctx context.Context, key string, values ...float64) ([]int64, error) {
+	return each(t, key, values, (*digest).revRank)
+}
+
+func (t *TDigest) ByRank(ctx context.Context, key string, values ...uint64) ([]float64, error) {
+	return each(t, key, values, (*digest).byRank)
+}
+
+func (t *TDigest) ByRevRank(ctx context.Context, key string, values ...uint64) ([]float64, error) {
+	return each(t, key, values, func(d *digest, r uint64) float64 {
+		if d.Count > 0 && float64(r) >= d.Count {
+			return math.Inf(-1)
+		}
+
+		return d.byRank(uint64(d.Count) - 1 - r)
+	})
+}
+
+func (t *TDigest) TrimmedMean(ctx context.Context, key string, lo, hi float64) (float64, error) {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	d, ok := t.store.items[key]
+	if !ok {
+		return 0, ErrKeyDoesNotExist
+	}
+
+	return d.trimmedMean(lo, hi), nil
+}
+
+// Merge merges the source keys into the destination key. The destination key
+// is overwritten, similar to probs.TDigest.Merge.
+func (t *TDigest) Merge(ctx context.Context, destKey string, sourceKeys ...string) (string, error) {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	dst := newDigest(tdigestCompression)
+	for _, key := range sourceKeys {
+		src, ok := t.store.items[key]
+		if !ok {
+			return "", ErrKeyDoesNotExist
+		}
+		dst.merge(src.clone())
+	}
+	t.store.items[destKey] = dst
+
+	return OK, nil
+}
+
+// MergeQuantile returns the quantiles of the merged source keys, without
+// keeping the merged t-digest, similar to probs.TDigest.MergeQuantile.
+func (t *TDigest) MergeQuantile(ctx context.Context, sourceKeys []string, values ...float64) ([]float64, error) {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	dst := newDigest(tdigestCompression)
+	for _, key := range sourceKeys {
+		if src, ok := t.store.items[key]; ok {
+			dst.merge(src.clone())
+		}
+	}
+
+	res := make([]float64, len(values))
+	for i, v := range values {
+		res[i] = dst.quantile(v)
+	}
+
+	return res, nil
+}
+
+func (t *TDigest) Dump(ctx context.Context, key string) ([]byte, error) {
+	return t.store.dump(key)
+}
+
+func (t *TDigest) Restore(ctx context.Context, key string, b []byte) error {
+	return t.store.restore(key, b)
+}
+
+func each[V, R any](t *TDigest, key string, values []V, fn func(*digest, V) R) ([]R, error) {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	d, ok := t.store.items[key]
+	if !ok {
+		return nil, ErrKeyDoesNotExist
+	}
+
+	res := make([]R, len(values))
+	for i, v := range values {
+		res[i] = fn(d, v)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,127 @@
+package inmem_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/alextanhongpin/core/dsync/probs/inmem"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTDigest(t *testing.T) {
+	td := inmem.NewTDigest()
+	key := t.Name()
+
+	is := assert.New(t)
+	_, exists, err := td.Create(ctx, key)
+	is.Nil(err)
+	is.False(exists)
+
+	// Uniform distribution from 1 to 10_000.
+	n := 10_000
+	values := make([]float64, n)
+	for i := range n {
+		values[i] = float64(i + 1)
+	}
+	_, err = td.Add(ctx, key, values...)
+	is.Nil(err)
+
+	lo, err := td.Min(ctx, key)
+	is.Nil(err)
+	is.Equal(1.0, lo)
+
+	hi, err := td.Max(ctx, key)
+	is.Nil(err)
+	is.Equal(10_000.0, hi)
+
+	qs, err := td.Quantile(ctx, key, 0, 0.5, 0.9, 0.99, 1)
+	is.Nil(err)
+	is.Equal(1.0, qs[0])
+	is.InDelta(5_000, qs[1], 50)
+	is.InDelta(9_000, qs[2], 50)
+	is.InDelta(9_900, qs[3], 10)
+	is.Equal(10_000.0, qs[4])
+
+	cdf, err := td.CDF(ctx, key, 0, 5_000, 10_000)
+	is.Nil(err)
+	is.Equal(0.0, cdf[0])
+	is.InDelta(0.5, cdf[1], 0.01)
+	is.Equal(1.0, cdf[2])
+
+	ranks, err := td.Rank(ctx, key, 0, 5_000, 20_000)
+	is.Nil(err)
+	is.Equal(int64(-1), ranks[0])
+	is.InDelta(5_000, ranks[1], 50)
+	is.Equal(int64(n), ranks[2])
+
+	revRanks, err := td.RevRank(ctx, key, 0, 20_000)
+	is.Nil(err)
+	is.Equal([]int64{int64(n), -1}, revRanks)
+
+	byRank, err := td.ByRank(ctx, key, 0, uint64(n))
+	is.Nil(err)
+	is.InDelta(1, byRank[0], 1)
+	is.True(math.IsInf(byRank[1], 1))
+
+	byRevRank, err := td.ByRevRank(ctx, key, 0, uint64(n))
+	is.Nil(err)
+	is.InDelta(10_000, byRevRank[0], 1)
+	is.True(math.IsInf(byRevRank[1], -1))
+
+	mean, err := td.TrimmedMean(ctx, key, 0.1, 0.9)
+	is.Nil(err)
+	is.InDelta(5_000, mean, 50)
+}
+
+func TestTDigest_MergeDump(t *testing.T) {
+	td := inmem.NewTDigest()
+
+	is := assert.New(t)
+	for i := range 1_000 {
+		_, err := td.Add(ctx, "a", float64(i))
+		is.Nil(err)
+		_, err = td.Add(ctx, "b", float64(i+1_000))
+		is.Nil(err)
+	}
+
+	status, err := td.Merge(ctx, "c", "a", "b")
+	is.Nil(err)
+	is.Equal("OK", status)
+
+	b, err := td.Dump(ctx, "c")
+	is.Nil(err)
+
+	restored := inmem.NewTDigest()
+	is.Nil(restored.Restore(ctx, "c", b))
+
+	qs, err := restored.Quantile(ctx, "c", 0, 0.5, 1)
+	is.Nil(err)
+	is.Equal(0.0, qs[0])
+	is.InDelta(1_000, qs[1], 20)
+	is.Equal(1_999.0, qs[2])
+}
+
+func TestTDigest_MergeQuantile(t *testing.T) {
+	td := inmem.NewTDigest()
+
+	is := assert.New(t)
+	for i := range 1_000 {
+		_, err := td.Add(ctx, "a", float64(i))
+		is.Nil(err)
+		_, err = td.Add(ctx, "b", float64(i+1_000))
+		is.Nil(err)
+	}
+
+	qs, err := td.MergeQuantile(ctx, []string{"a", "b", "missing"}, 0, 0.5, 1)
+	is.Nil(err)
+	is.Equal(0.0, qs[0])
+	is.InDelta(1_000, qs[1], 20)
+	is.Equal(1_999.0, qs[2])
+
+	_, err = td.Min(ctx, "missing")
+	is.ErrorIs(err, inmem.ErrKeyDoesNotExist, "the merged t-digest is not kept")
+
+	qs, err = td.MergeQuantile(ctx, []string{"missing"}, 0.5)
+	is.Nil(err)
+	is.True(math.IsNaN(qs[0]))
+}
@@ -0,0 +1,425 @@
+package inmem
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/alextanhongpin/core/dsync/probs"
+)
+
+var _ probs.TopKStore = (*TopK)(nil)
+
+// Defaults follows redis TOPK.RESERVE.
+const (
+	topKWidth = 8
+	topKDepth = 7
+	topKDecay = 0.9
+)
+
+type topKBucket struct {
+	Fingerprint uint64
+	Count       int64
+}
+
+type topKItem struct {
+	Item  string
+	Count int64
+}
+
+// heavyKeeper implements the HeavyKeeper algorithm, which is also used by
+// redis.
+// See https://www.usenix.org/conference/atc18/presentation/gong.
+type heavyKeeper struct {
+	K       int64
+	Width   uint64
+	Depth   uint64
+	Decay   float64
+	Buckets []topKBucket
+	Items   []topKItem
+}
+
+func newHeavyKeeper(k, width, depth int64, decay float64) *heavyKeeper {
+	return &heavyKeeper{
+		K:       k,
+		Width:   uint64(width),
+		Depth:   uint64(depth),
+		Decay:   decay,
+		Buckets: make([]topKBucket, width*depth),
+	}
+}
+
+// incrBy increments the count of the item, and returns the item that is
+// expelled from the top-k list, if any.
+func (hk *heavyKeeper) incrBy(item string, n int64) string {
+	h1, h2 := hash(item)
+
+	var count int64
+	for i := range hk.Depth {
+		b := &hk.Buckets[i*hk.Width+(h1+i*h2)%hk.Width]
+		switch {
+		case b.Count == 0:
+			b.Fingerprint = h1
+			b.Count = n
+		case b.Fingerprint == h1:
+			b.Count += n
+		default:
+			// Decay the count of the existing item with probability of
+			// decay^count, and take over the bucket once it reaches zero.
+			for r := n; r > 0; r-- {
+				if rand.Float64() >= math.Pow(hk.Decay, float64(b.Count)) {
+					continue
+				}
+
+				b.Count--
+				if b.Count == 0 {
+					b.Fingerprint = h1
+					b.Count = r
+
+					break
+				}
+			}
+		}
+
+		if b.Fingerprint == h1 {
+			count = max(count, b.Count)
+		}
+	}
+
+	return hk.update(item, count)
+}
+
+func (hk *heavyKeeper) update(item string, count int64) string {
+	if i := hk.index(item); i >= 0 {
+		hk.Items[i].Count = max(hk.Items[i].Count, count)
+
+		return ""
+	}
+
+	if int64(len(hk.Items)) < hk.K {
+		hk.Items = append(hk.Items, topKItem{Item: item, Count: count})
+
+		return ""
+	}
+
+	var i int
+	for j, it := range hk.Items {
+		if it.Count < hk.Items[i].Count {
+			i = j
+		}
+	}
+	if count <= hk.Items[i].Count {
+		return ""
+	}
+
+	expelled := hk.Items[i].Item
+	hk.Items[i] = topKItem{Item: item, Count: count}
+
+	return expelled
+}
+
+func (hk *heavyKeeper) count(item string) int64 {
+	h1, h2 := hash(item)
+
+	var count int64
+	for i := range hk.Depth {
+		b := hk.Buckets[i*hk.Width+(h1+i*h2)%hk.Width]
+		if b.Fingerprint == h1 {
+			count = max(count, b.Count)
+		}
+	}
+
+	return count
+}
+
+func (hk *heavyKeeper) index(item string) int {
+	return slices.IndexFunc(hk.Items, func(it topKItem) bool {
+		return it.Item == item
+	})
+}
+
+func (hk *heavyKeeper) list() []topKItem {
+	items := slices.Clone(hk.Items)
+	slices.SortFunc(items, func(a, b topKItem) int {
+		return cmp.Or(-byCount(a, b), cmp.Compare(a.Item, b.Item))
+	})
+
+	return items
+}
+
+func (hk *heavyKeeper) clone() *heavyKeeper {
+	c := *hk
+	c.Buckets = slices.Clone(hk.Buckets)
+	c.Items = slices.Clone(hk.Items)
+
+	return &c
+}
+
+// merge combines the buckets of the other sketch. Buckets with the same
+// fingerprint are summed, otherwise the larger count wins after subtracting
+// the smaller count.
+func (hk *heavyKeeper) merge(o *heavyKeeper) error {
+	if hk.Width != o.Width || hk.Depth != o.Depth {
+		return ErrMismatch
+	}
+
+	for i, b := range o.Buckets {
+		a := &hk.Buckets[i]
+		switch {
+		case a.Fingerprint == b.Fingerprint:
+			a.Count += b.Count
+		case a.Count >= b.Count:
+			a.Count -= b.Count
+		default:
+			a.Fingerprint = b.Fingerprint
+			a.Count = b.Count - a.Count
+		}
+	}
+
+	items := append(slices.Clone(hk.Items), o.Items...)
+	hk.Items = nil
+	for _, it := range items {
+		hk.update(it.Item, hk.count(it.Item))
+	}
+
+	return nil
+}
+
+func (hk *heavyKeeper) validate() error {
+	if hk.K <= 0 || hk.Width == 0 || hk.Depth == 0 {
+		return fmt.Errorf("%w: k, width and depth must be positive", ErrInvalidDump)
+	}
+	if n := uint64(len(hk.Buckets)); n%hk.Width != 0 || n/hk.Width != hk.Depth {
+		return fmt.Errorf("%w: buckets do not match the width and depth", ErrInvalidDump)
+	}
+	if int64(len(hk.Items)) > hk.K {
+		return fmt.Errorf("%w: items exceed k", ErrInvalidDump)
+	}
+	if hk.Decay <= 0 || hk.Decay > 1 {
+		return fmt.Errorf("%w: decay must be within (0, 1]", ErrInvalidDump)
+	}
+
+	return nil
+}
+
+func byCount(a, b topKItem) int {
+	return cmp.Compare(a.Count, b.Count)
+}
+
+// TopK is the in-memory version of probs.TopK.
+type TopK struct {
+	store *store[heavyKeeper]
+}
+
+func NewTopK() *TopK {
+	return &TopK{
+		store: newStore[heavyKeeper](),
+	}
+}
+
+func (t *TopK) Add(ctx context.Context, key string, values ...any) ([]string, error) {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	hk := t.getOrCreate(key)
+	res := make([]string, len(values))
+	for i, v := range values {
+		res[i] = hk.incrBy(fmt.Sprint(v), 1)
+	}
+
+	return res, nil
+}
+
+func (t *TopK) Count(ctx context.Context, key string, values ...any) ([]int64, error) {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	hk, ok := t.store.items[key]
+	if !ok {
+		return nil, ErrKeyDoesNotExist
+	}
+
+	res := make([]int64, len(values))
+	for i, v := range values {
+		res[i] = hk.count(fmt.Sprint(v))
+	}
+
+	return res, nil
+}
+
+// IncrBy increments the count of the keys sorted by the keys, and returns
+// the expelled items.
+func (t *TopK) IncrBy(ctx context.Context, key string, kvs map[string]int64) ([]string, error) {
+	keys := make([]string, 0, len(kvs))
+	for k := range kvs {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	hk := t.getOrCreate(key)
+	res := make([]string, len(keys))
+	for i, k := range keys {
+		res[i] = hk.incrBy(k, kvs[k])
+	}
+
+	return res, nil
+}
+
+func (t *TopK) List(ctx context.Context, key string) ([]string, error) {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	hk, ok := t.store.items[key]
+	if !ok {
+		return nil, ErrKeyDoesNotExist
+	}
+
+	items := hk.list()
+	res := make([]string, len(items))
+	for i, it := range items {
+		res[i] = it.Item
+	}
+
+	return res, nil
+}
+
+func (t *TopK) ListWithCount(ctx context.Context, key string) (map[string]int64, error) {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	hk, ok := t.store.items[key]
+	if !ok {
+		return nil, ErrKeyDoesNotExist
+	}
+
+	res := make(map[string]int64, len(hk.Items))
+	for _, it := range hk.Items {
+		res[it.Item] = it.Count
+	}
+
+	return res, nil
+}
+
+// Query returns if the values exists in the top list.
+func (t *TopK) Query(ctx context.Context, key string, values ...any) ([]bool, error) {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	hk, ok := t.store.items[key]
+	if !ok {
+		return nil, ErrKeyDoesNotExist
+	}
+
+	res := make([]bool, len(values))
+	for i, v := range values {
+		res[i] = hk.index(fmt.Sprint(v)) >= 0
+	}
+
+	return res, nil
+}
+
+func (t *TopK) Reserve(ctx context.Context, key string, k int64) (string, error) {
+	return t.ReserveWithOptions(ctx, key, k, topKWidth, topKDepth, topKDecay)
+}
+
+func (t *TopK) ReserveWithOptions(ctx context.Context, key string, k, width, depth int64, decay float64) (string, error) {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	if _, ok := t.store.items[key]; ok {
+		return "", ErrKeyAlreadyExists
+	}
+	t.store.items[key] = newHeavyKeeper(k, width, depth, decay)
+
+	return OK, nil
+}
+
+func (t *TopK) Create(ctx context.Context, key string, k int64) (string, error) {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	if _, ok := t.store.items[key]; !ok {
+		t.store.items[key] = newTopKHeavyKeeper(k)
+	}
+
+	return OK, nil
+}
+
+// Merge merges the source keys into the destination key. The destination key
+// is created if it does not exist.
+func (t *TopK) Merge(ctx context.Context, destKey string, sourceKeys ...string) (string, error) {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	dst, ok := t.store.items[destKey]
+	if ok {
+		dst = dst.clone()
+	}
+	for _, key := range sourceKeys {
+		src, exists := t.store.items[key]
+		if !exists {
+			return "", ErrKeyDoesNotExist
+		}
+		if !ok {
+			dst, ok = src.clone(), true
+			continue
+		}
+		if err := dst.merge(src); err != nil {
+			return "", err
+		}
+	}
+	if ok {
+		t.store.items[destKey] = dst
+	}
+
+	return OK, nil
+}
+
+func (t *TopK) Dump(ctx context.Context, key string) ([]byte, error) {
+	return t.store.dump(key)
+}
+
+func (t *TopK) Restore(ctx context.Context, key string, b []byte) error {
+	return t.store.restore(key, b)
+}
+
+func (t *TopK) getOrCreate(key string) *heavyKeeper {
+	hk, ok := t.store.items[key]
+	if !ok {
+		hk = newTopKHeavyKeeper(10)
+		t.store.items[key] = hk
+	}
+
+	return hk
+}
+
+// newTopKHeavyKeeper follows the dimensions used by probs.TopK.Create.
+func newTopKHeavyKeeper(k int64) *heavyKeeper {
+	logK := math.Log(float64(k))
+	width := int64(float64(k) * logK)
+	depth := int64(max(logK, 5))
+
+	return newHeavyKeeper(k, max(width, 1), depth, 0.9)
+}
+
+// Export replays the counts of the top-k items at the key into the
+// destination key, e.g. a probs.TopK in redis.
+func (t *TopK) Export(ctx context.Context, key string, dst probs.TopKStore, destKey string) error {
+	kvs, err := t.ListWithCount(ctx, key)
+	if err != nil {
+		return err
+	}
+	if len(kvs) == 0 {
+		return nil
+	}
+
+	_, err = dst.IncrBy(ctx, destKey, kvs)
+
+	return err
+}
@@ -0,0 +1,106 @@
+package inmem_test
+
+import (
+	"testing"
+
+	"github.com/alextanhongpin/core/dsync/probs/inmem"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopK(t *testing.T) {
+	// Find top k hashtag
+	topK := inmem.NewTopK()
+	key := t.Name() + ":top_k:hashtag"
+
+	is := assert.New(t)
+	status, err := topK.Create(ctx, key, 5)
+	is.Nil(err)
+	is.Equal("OK", status)
+
+	_, err = topK.Add(ctx, key,
+		"ai",
+		"ml", "ml",
+		"js", "js",
+		"python", "python",
+		"ts", "ts", "ts",
+		"go", "go", "go", "go", "go", "go",
+	)
+	is.Nil(err)
+
+	counts, err := topK.Count(ctx, key, "go", "ts")
+	is.Nil(err)
+	is.Equal([]int64{6, 3}, counts)
+
+	_, err = topK.IncrBy(ctx, key, map[string]int64{
+		"go": 10,
+		"ts": 10,
+	})
+	is.Nil(err)
+
+	list, err := topK.List(ctx, key)
+	is.Nil(err)
+	is.Len(list, 5)
+	is.Equal([]string{"go", "ts"}, list[:2])
+
+	listWithCount, err := topK.ListWithCount(ctx, key)
+	is.Nil(err)
+	is.Equal(int64(16), listWithCount["go"])
+	is.Equal(int64(13), listWithCount["ts"])
+
+	found, err := topK.Query(ctx, key, "go", "unknown")
+	is.Nil(err)
+	is.Equal([]bool{true, false}, found)
+}
+
+func TestTopK_Expelled(t *testing.T) {
+	topK := inmem.NewTopK()
+	key := t.Name()
+
+	is := assert.New(t)
+	_, err := topK.Reserve(ctx, key, 1)
+	is.Nil(err)
+
+	expelled, err := topK.Add(ctx, key, "foo", "bar", "bar")
+	is.Nil(err)
+	is.Equal([]string{"", "", "foo"}, expelled)
+}
+
+func TestTopK_MergeDump(t *testing.T) {
+	topK := inmem.NewTopK()
+
+	is := assert.New(t)
+	_, err := topK.IncrBy(ctx, "a", map[string]int64{"go": 10, "ts": 5})
+	is.Nil(err)
+	_, err = topK.IncrBy(ctx, "b", map[string]int64{"go": 1, "ts": 10})
+	is.Nil(err)
+
+	_, err = topK.Merge(ctx, "c", "a", "b")
+	is.Nil(err)
+
+	b, err := topK.Dump(ctx, "c")
+	is.Nil(err)
+
+	restored := inmem.NewTopK()
+	is.Nil(restored.Restore(ctx, "c", b))
+
+	listWithCount, err := restored.ListWithCount(ctx, "c")
+	is.Nil(err)
+	is.Equal(map[string]int64{"go": 11, "ts": 15}, listWithCount)
+}
+
+func TestTopK_Export(t *testing.T) {
+	src := inmem.NewTopK()
+	dst := inmem.NewTopK()
+
+	is := assert.New(t)
+	_, err := src.IncrBy(ctx, "src", map[string]int64{"go": 10, "ts": 5})
+	is.Nil(err)
+	_, err = dst.IncrBy(ctx, "dst", map[string]int64{"go": 1})
+	is.Nil(err)
+
+	is.Nil(src.Export(ctx, "src", dst, "dst"))
+
+	listWithCount, err := dst.ListWithCount(ctx, "dst")
+	is.Nil(err)
+	is.Equal(map[string]int64{"go": 11, "ts": 5}, listWithCount)
+}
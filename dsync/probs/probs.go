@@ -1,3 +1,81 @@
 package probs
 
+import (
+	"context"
+)
+
 const OK = "OK"
+
+// The interfaces below are implemented by both the redis-backed structures in
+// this package, and the in-memory structures in package inmem.
+
+type BloomFilterStore interface {
+	Add(ctx context.Context, key string, value any) (bool, error)
+	MAdd(ctx context.Context, key string, values ...any) ([]bool, error)
+	Exists(ctx context.Context, key string, value any) (bool, error)
+	MExists(ctx context.Context, key string, values ...any) ([]bool, error)
+	Reserve(ctx context.Context, key string, errorRate float64, capacity int64) (string, error)
+}
+
+type CuckooFilterStore interface {
+	Add(ctx context.Context, key, value string) (bool, error)
+	AddNX(ctx context.Context, key, value string) (bool, error)
+	Exists(ctx context.Context, key, value string) (bool, error)
+	Count(ctx context.Context, key, value string) (int64, error)
+	MExists(ctx context.Context, key string, values ...any) ([]bool, error)
+	Delete(ctx context.Context, key, value string) (bool, error)
+}
+
+type CountMinSketchStore interface {
+	Init(ctx context.Context, key string) (status string, exists bool, err error)
+	InitByProb(ctx context.Context, key string, errorRate, errorProbability float64) (status string, exists bool, err error)
+	InitByDim(ctx context.Context, key string, width, depth int64) (status string, exists bool, err error)
+	IncrBy(ctx context.Context, key string, kvs map[string]int64) ([]int64, bool, error)
+	Merge(ctx context.Context, destKey string, sourceKeys ...string) (string, error)
+	MergeWithWeight(ctx context.Context, destKey string, sourceKeys map[string]int64) (string, error)
+	Query(ctx context.Context, key string, values ...any) ([]int64, error)
+}
+
+type HyperLogLogStore interface {
+	Add(ctx context.Context, key string, values ...any) (int64, error)
+	Count(ctx context.Context, keys ...string) (int64, error)
+	Merge(ctx context.Context, destKey string, srcKeys ...string) (string, error)
+}
+
+type TDigestStore interface {
+	Create(ctx context.Context, key string) (status string, exists bool, err error)
+	CreateWithCompression(ctx context.Context, key string, compression int64) (status string, exists bool, err error)
+	Add(ctx context.Context, key string, values ...float64) (string, error)
+	CDF(ctx context.Context, key string, values ...float64) ([]float64, error)
+	Quantile(ctx context.Context, key string, values ...float64) ([]float64, error)
+	Min(ctx context.Context, key string) (float64, error)
+	Max(ctx context.Context, key string) (float64, error)
+	Rank(ctx context.Context, key string, values ...float64) ([]int64, error)
+	RevRank(ctx context.Context, key string, values ...float64) ([]int64, error)
+	ByRank(ctx context.Context, key string, values ...uint64) ([]float64, error)
+	ByRevRank(ctx context.Context, key string, values ...uint64) ([]float64, error)
+	TrimmedMean(ctx context.Context, key string, lo, hi float64) (float64, error)
+	Merge(ctx context.Context, destKey string, sourceKeys ...string) (string, error)
+	MergeQuantile(ctx context.Context, sourceKeys []string, values ...float64) ([]float64, error)
+}
+
+type TopKStore interface {
+	Add(ctx context.Context, key string, values ...any) ([]string, error)
+	Count(ctx context.Context, key string, values ...any) ([]int64, error)
+	IncrBy(ctx context.Context, key string, kvs map[string]int64) ([]string, error)
+	List(ctx context.Context, key string) ([]string, error)
+	ListWithCount(ctx context.Context, key string) (map[string]int64, error)
+	Query(ctx context.Context, key string, values ...any) ([]bool, error)
+	Reserve(ctx context.Context, key string, k int64) (string, error)
+	ReserveWithOptions(ctx context.Context, key string, k, width, depth int64, decay float64) (string, error)
+	Create(ctx context.Context, key string, k int64) (string, error)
+}
+
+var (
+	_ BloomFilterStore    = (*BloomFilter)(nil)
+	_ CuckooFilterStore   = (*CuckooFilter)(nil)
+	_ CountMinSketchStore = (*CountMinSketch)(nil)
+	_ HyperLogLogStore    = (*HyperLogLog)(nil)
+	_ TDigestStore        = (*TDigest)(nil)
+	_ TopKStore           = (*TopK)(nil)
+)
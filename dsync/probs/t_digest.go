@@ -2,6 +2,9 @@ package probs
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"math"
 
 	redis "github.com/redis/go-redis/v9"
 	"golang.org/x/sync/singleflight"
@@ -95,3 +98,72 @@ func (t *TDigest) ByRevRank(ctx context.Context, key string, values ...uint64) (
 func (t *TDigest) TrimmedMean(ctx context.Context, key string, lo, hi float64) (float64, error) {
 	return t.Client.TDigestTrimmedMean(ctx, key, lo, hi).Result()
 }
+
+// Merge merges the source keys into the destination key. The destination key
+// is overwritten, similar to CMS.MERGE.
+func (t *TDigest) Merge(ctx context.Context, destKey string, sourceKeys ...string) (string, error) {
+	return t.Client.TDigestMerge(ctx, destKey, &redis.TDigestMergeOptions{
+		Override: true,
+	}, sourceKeys...).Result()
+}
+
+// MergeQuantile returns the quantiles of the merged source keys, without
+// keeping the merged t-digest. The source keys that does not exist are
+// skipped.
+// The keys are merged into a temporary key, which is deleted in the same
+// transaction.
+func (t *TDigest) MergeQuantile(ctx context.Context, sourceKeys []string, values ...float64) ([]float64, error) {
+	var keys []string
+	if len(sourceKeys) > 0 {
+		cmds, err := t.Client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range sourceKeys {
+				pipe.Exists(ctx, key)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for i, cmd := range cmds {
+			if cmd.(*redis.IntCmd).Val() > 0 {
+				keys = append(keys, sourceKeys[i])
+			}
+		}
+	}
+
+	switch len(keys) {
+	case 0:
+		res := make([]float64, len(values))
+		for i := range res {
+			res[i] = math.NaN()
+		}
+
+		return res, nil
+	case 1:
+		return t.Quantile(ctx, keys[0], values...)
+	}
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	tmp := "tdigest:merge:" + hex.EncodeToString(b)
+
+	var quantile *redis.FloatSliceCmd
+	_, err := t.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.TDigestMerge(ctx, tmp, &redis.TDigestMergeOptions{
+			Override: true,
+		}, keys...)
+		quantile = pipe.TDigestQuantile(ctx, tmp, values...)
+		pipe.Del(ctx, tmp)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return quantile.Result()
+}
@@ -86,6 +86,23 @@ func TestTDigest(t *testing.T) {
 		is.Equal([]float64{30, 10}, ranks)
 	})
 
+	t.Run("merge quantile", func(t *testing.T) {
+		client := redistest.Client(t)
+		td := probs.NewTDigest(client)
+
+		is := assert.New(t)
+		_, err := td.Add(ctx, key+":other", 40, 50)
+		is.Nil(err)
+
+		qs, err := td.MergeQuantile(ctx, []string{key, key + ":other", key + ":missing"}, 0, 1)
+		is.Nil(err)
+		is.Equal([]float64{10, 50}, qs)
+
+		keys, err := client.Keys(ctx, "tdigest:merge:*").Result()
+		is.Nil(err)
+		is.Empty(keys, "the merged t-digest is not kept")
+	})
+
 	t.Run("trimmed mean", func(t *testing.T) {
 		td := probs.NewTDigest(redistest.Client(t))
 
@@ -0,0 +1,293 @@
+package probs
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	redis "github.com/redis/go-redis/v9"
+)
+
+var ErrWindowExceeded = errors.New("probs: window exceeds retention")
+
+// tempKeyTTL is the expiry of the temporary keys, which are deleted once used.
+const tempKeyTTL = time.Minute
+
+// Window partitions a key into time buckets, e.g. key:1714521600.
+// Each bucket expires after the retention, so queries can be made over any
+// trailing window up to the retention.
+type Window struct {
+	Client    *redis.Client
+	Size      time.Duration // The duration of each bucket.
+	Retention time.Duration // How long each bucket is kept.
+	Now       func() time.Time
+}
+
+func NewWindow(client *redis.Client, size, retention time.Duration) *Window {
+	return &Window{
+		Client:    client,
+		Size:      size,
+		Retention: retention,
+		Now:       time.Now,
+	}
+}
+
+// Bucket returns the bucket key at the given time.
+func (w *Window) Bucket(key string, t time.Time) string {
+	return key + ":" + strconv.FormatInt(t.Truncate(w.Size).Unix(), 10)
+}
+
+// Buckets returns the bucket keys covering the trailing window, starting from
+// the oldest bucket.
+func (w *Window) Buckets(key string, window time.Duration) ([]string, error) {
+	if window > w.Retention {
+		return nil, ErrWindowExceeded
+	}
+
+	n := max(int((window+w.Size-1)/w.Size), 1)
+	now := w.Now()
+	keys := make([]string, n)
+	for i := range n {
+		keys[i] = w.Bucket(key, now.Add(-time.Duration(n-1-i)*w.Size))
+	}
+
+	return keys, nil
+}
+
+// expire extends the TTL of the current bucket, so that it is kept for the
+// whole retention after the bucket ends.
+func (w *Window) expire(ctx context.Context, key string) error {
+	return w.Client.PExpire(ctx, key, w.Retention+w.Size).Err()
+}
+
+// exists filters the keys that exists.
+func (w *Window) exists(ctx context.Context, keys []string) ([]string, error) {
+	cmds := make([]*redis.IntCmd, len(keys))
+	_, err := w.Client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Exists(ctx, key)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var res []string
+	for i, cmd := range cmds {
+		if cmd.Val() > 0 {
+			res = append(res, keys[i])
+		}
+	}
+
+	return res, nil
+}
+
+// WindowHyperLogLog tracks unique occurences over a trailing window, e.g.
+// unique users in the last 15 minutes.
+type WindowHyperLogLog struct {
+	*Window
+	HyperLogLog HyperLogLogStore
+}
+
+func NewWindowHyperLogLog(client *redis.Client, size, retention time.Duration) *WindowHyperLogLog {
+	return &WindowHyperLogLog{
+		Window:      NewWindow(client, size, retention),
+		HyperLogLog: NewHyperLogLog(client),
+	}
+}
+
+func (w *WindowHyperLogLog) Add(ctx context.Context, key string, values ...any) (int64, error) {
+	bucket := w.Bucket(key, w.Now())
+	n, err := w.HyperLogLog.Add(ctx, bucket, values...)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, w.expire(ctx, bucket)
+}
+
+// Count returns the approximated cardinality of the union of the buckets in
+// the trailing window.
+func (w *WindowHyperLogLog) Count(ctx context.Context, key string, window time.Duration) (int64, error) {
+	keys, err := w.Buckets(key, window)
+	if err != nil {
+		return 0, err
+	}
+
+	return w.HyperLogLog.Count(ctx, keys...)
+}
+
+// Merge merges the buckets in the trailing window into the destination key.
+// The destination key is overwritten, since PFMERGE includes the existing
+// destination key in the union.
+func (w *WindowHyperLogLog) Merge(ctx context.Context, destKey, key string, window time.Duration) (string, error) {
+	keys, err := w.Buckets(key, window)
+	if err != nil {
+		return "", err
+	}
+
+	if err := w.Client.Del(ctx, destKey).Err(); err != nil {
+		return "", err
+	}
+
+	return w.HyperLogLog.Merge(ctx, destKey, keys...)
+}
+
+// WindowCountMinSketch tracks the frequency of occurences over a trailing
+// window, e.g. number of API calls in the last hour.
+type WindowCountMinSketch struct {
+	*Window
+	CountMinSketch CountMinSketchStore
+}
+
+func NewWindowCountMinSketch(client *redis.Client, size, retention time.Duration) *WindowCountMinSketch {
+	return &WindowCountMinSketch{
+		Window:         NewWindow(client, size, retention),
+		CountMinSketch: NewCountMinSketch(client),
+	}
+}
+
+func (w *WindowCountMinSketch) IncrBy(ctx context.Context, key string, kvs map[string]int64) ([]int64, error) {
+	bucket := w.Bucket(key, w.Now())
+	counts, _, err := w.CountMinSketch.IncrBy(ctx, bucket, kvs)
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, w.expire(ctx, bucket)
+}
+
+// Merge merges the buckets in the trailing window into the destination key.
+// Missing buckets are skipped, and the destination key is deleted if all
+// buckets are missing.
+func (w *WindowCountMinSketch) Merge(ctx context.Context, destKey, key string, window time.Duration) (string, error) {
+	keys, err := w.Buckets(key, window)
+	if err != nil {
+		return "", err
+	}
+
+	keys, err = w.exists(ctx, keys)
+	if err != nil {
+		return "", err
+	}
+	if len(keys) == 0 {
+		return OK, w.Client.Del(ctx, destKey).Err()
+	}
+
+	weights := make(map[string]int64, len(keys))
+	for _, k := range keys {
+		weights[k] = 1
+	}
+
+	return w.CountMinSketch.MergeWithWeight(ctx, destKey, weights)
+}
+
+// Query returns the counts of the values over the trailing window.
+// The buckets are merged into a unique temporary key, so that concurrent
+// queries do not overwrite each other. The key is deleted after the query,
+// and expires shortly in case the deletion fails.
+func (w *WindowCountMinSketch) Query(ctx context.Context, key string, window time.Duration, values ...any) (counts []int64, err error) {
+	destKey := key + ":window:" + uuid.NewString()
+	if _, err := w.Merge(ctx, destKey, key, window); err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, w.Client.Del(context.WithoutCancel(ctx), destKey).Err())
+	}()
+
+	if err := w.Client.PExpire(ctx, destKey, tempKeyTTL).Err(); err != nil {
+		return nil, err
+	}
+
+	counts, err = w.CountMinSketch.Query(ctx, destKey, values...)
+	if KeyDoesNotExistError(err) {
+		return make([]int64, len(values)), nil
+	}
+
+	return counts, err
+}
+
+// WindowTopK tracks the top-k items over a trailing window, e.g. top paths in
+// the last hour.
+// Since TopK does not support merging, the counts of each bucket are summed
+// instead.
+type WindowTopK struct {
+	*Window
+	TopK TopKStore
+}
+
+func NewWindowTopK(client *redis.Client, size, retention time.Duration) *WindowTopK {
+	return &WindowTopK{
+		Window: NewWindow(client, size, retention),
+		TopK:   NewTopK(client),
+	}
+}
+
+func (w *WindowTopK) Add(ctx context.Context, key string, values ...any) ([]string, error) {
+	bucket := w.Bucket(key, w.Now())
+	expelled, err := w.TopK.Add(ctx, bucket, values...)
+	if err != nil {
+		return nil, err
+	}
+
+	return expelled, w.expire(ctx, bucket)
+}
+
+func (w *WindowTopK) IncrBy(ctx context.Context, key string, kvs map[string]int64) ([]string, error) {
+	bucket := w.Bucket(key, w.Now())
+	expelled, err := w.TopK.IncrBy(ctx, bucket, kvs)
+	if err != nil {
+		return nil, err
+	}
+
+	return expelled, w.expire(ctx, bucket)
+}
+
+// ListWithCount returns the summed counts of the items in the trailing window.
+func (w *WindowTopK) ListWithCount(ctx context.Context, key string, window time.Duration) (map[string]int64, error) {
+	keys, err := w.Buckets(key, window)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]int64)
+	for _, k := range keys {
+		kvs, err := w.TopK.ListWithCount(ctx, k)
+		if KeyDoesNotExistError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for item, n := range kvs {
+			res[item] += n
+		}
+	}
+
+	return res, nil
+}
+
+// List returns the top k items in the trailing window, sorted by the count in
+// descending order.
+func (w *WindowTopK) List(ctx context.Context, key string, window time.Duration, k int) ([]string, error) {
+	kvs, err := w.ListWithCount(ctx, key, window)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]string, 0, len(kvs))
+	for item := range kvs {
+		items = append(items, item)
+	}
+	slices.SortFunc(items, func(a, b string) int {
+		return cmp.Or(cmp.Compare(kvs[b], kvs[a]), cmp.Compare(a, b))
+	})
+
+	return items[:min(k, len(items))], nil
+}
@@ -0,0 +1,188 @@
+package probs_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/core/dsync/probs"
+	"github.com/alextanhongpin/core/storage/redis/redistest"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestWindow(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+
+	w := probs.NewWindow(nil, time.Minute, time.Hour)
+	w.Now = func() time.Time {
+		return now
+	}
+
+	is := assert.New(t)
+	keys, err := w.Buckets("key", 3*time.Minute)
+	is.Nil(err)
+	is.Equal([]string{
+		w.Bucket("key", now.Add(-2*time.Minute)),
+		w.Bucket("key", now.Add(-time.Minute)),
+		w.Bucket("key", now),
+	}, keys)
+	is.Equal("key:1714559400", keys[2])
+
+	_, err = w.Buckets("key", 2*time.Hour)
+	is.ErrorIs(err, probs.ErrWindowExceeded)
+}
+
+func TestWindowHyperLogLog(t *testing.T) {
+	now := time.Now()
+
+	hll := probs.NewWindowHyperLogLog(redistest.Client(t), time.Minute, time.Hour)
+	hll.Now = func() time.Time {
+		return now
+	}
+	key := t.Name()
+
+	is := assert.New(t)
+	_, err := hll.Add(ctx, key, "a", "b")
+	is.Nil(err)
+
+	now = now.Add(time.Minute)
+	_, err = hll.Add(ctx, key, "b", "c")
+	is.Nil(err)
+
+	n, err := hll.Count(ctx, key, time.Minute)
+	is.Nil(err)
+	is.Equal(int64(2), n)
+
+	n, err = hll.Count(ctx, key, 2*time.Minute)
+	is.Nil(err)
+	is.Equal(int64(3), n)
+
+	_, err = hll.Merge(ctx, key+":last_hour", key, time.Hour)
+	is.Nil(err)
+
+	n, err = hll.HyperLogLog.Count(ctx, key+":last_hour")
+	is.Nil(err)
+	is.Equal(int64(3), n)
+
+	// The destination key is overwritten.
+	now = now.Add(10 * time.Minute)
+	_, err = hll.Merge(ctx, key+":last_hour", key, time.Minute)
+	is.Nil(err)
+
+	n, err = hll.HyperLogLog.Count(ctx, key+":last_hour")
+	is.Nil(err)
+	is.Equal(int64(0), n)
+}
+
+func TestWindowCountMinSketch(t *testing.T) {
+	now := time.Now()
+
+	cms := probs.NewWindowCountMinSketch(redistest.Client(t), time.Minute, time.Hour)
+	cms.Now = func() time.Time {
+		return now
+	}
+	key := t.Name()
+
+	is := assert.New(t)
+	_, err := cms.IncrBy(ctx, key, map[string]int64{"foo": 1, "bar": 2})
+	is.Nil(err)
+
+	now = now.Add(2 * time.Minute)
+	_, err = cms.IncrBy(ctx, key, map[string]int64{"foo": 3})
+	is.Nil(err)
+
+	counts, err := cms.Query(ctx, key, time.Minute, "foo", "bar")
+	is.Nil(err)
+	is.Equal([]int64{3, 0}, counts)
+
+	counts, err = cms.Query(ctx, key, 3*time.Minute, "foo", "bar")
+	is.Nil(err)
+	is.Equal([]int64{4, 2}, counts)
+
+	// The previous query is not returned when the buckets are missing.
+	now = now.Add(10 * time.Minute)
+	counts, err = cms.Query(ctx, key, time.Minute, "foo", "bar")
+	is.Nil(err)
+	is.Equal([]int64{0, 0}, counts)
+
+	counts, err = cms.Query(ctx, "unknown", time.Hour, "foo")
+	is.Nil(err)
+	is.Equal([]int64{0}, counts)
+}
+
+func TestWindowCountMinSketch_QueryConcurrent(t *testing.T) {
+	now := time.Now()
+
+	client := redistest.Client(t)
+	cms := probs.NewWindowCountMinSketch(client, time.Minute, time.Hour)
+	cms.Now = func() time.Time {
+		return now
+	}
+	key := t.Name()
+
+	is := assert.New(t)
+	_, err := cms.IncrBy(ctx, key, map[string]int64{"foo": 1})
+	is.Nil(err)
+
+	now = now.Add(2 * time.Minute)
+	_, err = cms.IncrBy(ctx, key, map[string]int64{"foo": 3})
+	is.Nil(err)
+
+	// Queries with different windows do not overwrite each other.
+	var g errgroup.Group
+	for i := range 10 {
+		window, want := time.Minute, int64(3)
+		if i%2 == 0 {
+			window, want = 3*time.Minute, 4
+		}
+
+		g.Go(func() error {
+			counts, err := cms.Query(ctx, key, window, "foo")
+			if err != nil {
+				return err
+			}
+			if counts[0] != want {
+				return fmt.Errorf("window %s: want %d, got %d", window, want, counts[0])
+			}
+
+			return nil
+		})
+	}
+	is.Nil(g.Wait())
+
+	// The temporary keys are deleted.
+	keys, err := client.Keys(ctx, key+":window:*").Result()
+	is.Nil(err)
+	is.Empty(keys)
+}
+
+func TestWindowTopK(t *testing.T) {
+	now := time.Now()
+
+	topK := probs.NewWindowTopK(redistest.Client(t), time.Minute, time.Hour)
+	topK.Now = func() time.Time {
+		return now
+	}
+	key := t.Name()
+
+	is := assert.New(t)
+	_, err := topK.Add(ctx, key, "go", "go", "go", "js")
+	is.Nil(err)
+
+	now = now.Add(time.Minute)
+	_, err = topK.IncrBy(ctx, key, map[string]int64{"js": 3, "ts": 1})
+	is.Nil(err)
+
+	list, err := topK.List(ctx, key, time.Minute, 2)
+	is.Nil(err)
+	is.Equal([]string{"js", "ts"}, list)
+
+	list, err = topK.List(ctx, key, 2*time.Minute, 2)
+	is.Nil(err)
+	is.Equal([]string{"js", "go"}, list)
+
+	kvs, err := topK.ListWithCount(ctx, key, 2*time.Minute)
+	is.Nil(err)
+	is.Equal(map[string]int64{"go": 3, "js": 4, "ts": 1}, kvs)
+}
@@ -0,0 +1,12 @@
+local key = KEYS[1]
+
+local ttl = tonumber(ARGV[1])
+
+-- Only extend the cooldown, never shorten it.
+local curr = tonumber(redis.call('PTTL', key))
+if curr < ttl then
+	redis.call('SET', key, 1, 'PX', ttl)
+	return 1
+end
+
+return 0
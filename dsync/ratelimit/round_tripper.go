@@ -0,0 +1,241 @@
+package ratelimit
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+//go:embed cooldown.lua
+var cooldownScript string
+
+var cooldown = redis.NewScript(cooldownScript)
+
+var ErrThrottled = errors.New("ratelimit: throttled")
+
+const (
+	headerRetryAfter         = "Retry-After"
+	headerRateLimitRemaining = "X-RateLimit-Remaining"
+	headerRateLimitReset     = "X-RateLimit-Reset"
+)
+
+// Policy decides what happens when the limit is exceeded.
+type Policy int
+
+const (
+	// FailFast returns ErrThrottled immediately.
+	FailFast Policy = iota
+	// Wait blocks until the request is allowed, or until MaxWait is exceeded.
+	Wait
+)
+
+type transporter interface {
+	RoundTrip(r *http.Request) (*http.Response, error)
+}
+
+type limiter interface {
+	AllowN(ctx context.Context, key string, n int) (bool, error)
+}
+
+var (
+	_ limiter = (*FixedWindow)(nil)
+	_ limiter = (*GCRA)(nil)
+)
+
+// RoundTripper throttles outgoing requests using a distributed limiter, so
+// that the quota is shared by all replicas.
+// When the upstream responds with Retry-After, or X-RateLimit-Remaining of
+// 0, all replicas will hold off the key until the upstream resets.
+//
+// The RoundTripper can be composed with retry.RoundTripper and
+// circuitbreaker.Transporter. Place it inside the retry, so that each retry
+// is counted against the limit:
+//
+//	retry(ratelimit(circuitbreaker(http.DefaultTransport)))
+type RoundTripper struct {
+	Transport transporter
+	Limiter   limiter
+	// Key returns the limiter key for the request, e.g. the host or account.
+	Key    func(r *http.Request) string
+	Policy Policy
+	// MaxWait is the maximum duration to wait when the Policy is Wait.
+	MaxWait time.Duration
+	// Interval is the duration between attempts when the Policy is Wait.
+	Interval time.Duration
+	// Cooldown is used when the upstream returns X-RateLimit-Remaining of 0
+	// without X-RateLimit-Reset.
+	Cooldown time.Duration
+	// OnHoldError is called when the hold fails after the upstream responded.
+	// The response is still returned. Defaults to logging with slog.
+	OnHoldError func(r *http.Request, key string, err error)
+	client      *redis.Client
+}
+
+func NewRoundTripper(client *redis.Client, t transporter, l limiter) *RoundTripper {
+	return &RoundTripper{
+		Transport:   t,
+		Limiter:     l,
+		Key:         ByHost,
+		Policy:      FailFast,
+		MaxWait:     5 * time.Second,
+		Interval:    100 * time.Millisecond,
+		Cooldown:    time.Second,
+		OnHoldError: logHoldError,
+		client:      client,
+	}
+}
+
+func (t *RoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx := r.Context()
+	key := t.Key(r)
+
+	if err := t.wait(ctx, key); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.Transport.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// The upstream has already handled the request, so a failed hold must
+	// not discard the response.
+	if d := t.backOff(resp); d > 0 {
+		if err := t.hold(ctx, key, d); err != nil && t.OnHoldError != nil {
+			t.OnHoldError(r, key, err)
+		}
+	}
+
+	return resp, nil
+}
+
+// hold prevents requests for the given key from being sent for the duration.
+// Existing holds are only extended, never shortened.
+func (t *RoundTripper) hold(ctx context.Context, key string, d time.Duration) error {
+	keys := []string{cooldownKey(key)}
+	argv := []any{d.Milliseconds()}
+	return cooldown.Run(ctx, t.client, keys, argv...).Err()
+}
+
+func (t *RoundTripper) wait(ctx context.Context, key string) error {
+	start := time.Now()
+
+	for {
+		d, err := t.client.PTTL(ctx, cooldownKey(key)).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+
+		if d <= 0 {
+			ok, err := t.Limiter.AllowN(ctx, key, 1)
+			if err != nil {
+				return err
+			}
+			if ok {
+				return nil
+			}
+
+			d = t.Interval
+		}
+
+		if t.Policy != Wait || time.Since(start)+d > t.MaxWait {
+			return ErrThrottled
+		}
+
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-time.After(d):
+		}
+	}
+}
+
+// backOff returns the duration the upstream asks us to back off.
+func (t *RoundTripper) backOff(resp *http.Response) time.Duration {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		if d, ok := parseRetryAfter(resp.Header.Get(headerRetryAfter)); ok {
+			return d
+		}
+	}
+
+	remaining, err := strconv.Atoi(resp.Header.Get(headerRateLimitRemaining))
+	if err != nil || remaining > 0 {
+		return 0
+	}
+
+	if d, ok := parseRateLimitReset(resp.Header.Get(headerRateLimitReset)); ok {
+		return d
+	}
+
+	return t.Cooldown
+}
+
+func logHoldError(r *http.Request, key string, err error) {
+	slog.ErrorContext(r.Context(), "ratelimit: failed to hold", slog.String("key", key), slog.Any("err", err))
+}
+
+// ByHost limits the requests by the host.
+func ByHost(r *http.Request) string {
+	return r.URL.Host
+}
+
+// ByHeader limits the requests by the value of the header, e.g. the account
+// ID. Requests without the header are limited by host.
+func ByHeader(name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		if v := r.Header.Get(name); v != "" {
+			return r.URL.Host + ":" + v
+		}
+
+		return ByHost(r)
+	}
+}
+
+func cooldownKey(key string) string {
+	return key + ":cooldown"
+}
+
+// parseRetryAfter parses the Retry-After header, which is either the delay
+// in seconds or a HTTP date.
+func parseRetryAfter(s string) (time.Duration, bool) {
+	if s == "" {
+		return 0, false
+	}
+
+	if n, err := strconv.Atoi(s); err == nil {
+		return time.Duration(n) * time.Second, n > 0
+	}
+
+	t, err := http.ParseTime(s)
+	if err != nil {
+		return 0, false
+	}
+
+	d := time.Until(t)
+	return d, d > 0
+}
+
+// parseRateLimitReset parses the X-RateLimit-Reset header, which is either
+// the delay in seconds, or the unix epoch in seconds.
+func parseRateLimitReset(s string) (time.Duration, bool) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+
+	// Anything larger than a year is treated as unix epoch.
+	const year = 365 * 24 * 60 * 60
+	if n > year {
+		d := time.Until(time.Unix(n, 0))
+		return d, d > 0
+	}
+
+	return time.Duration(n) * time.Second, true
+}
@@ -0,0 +1,147 @@
+package ratelimit_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/core/dsync/ratelimit"
+	"github.com/alextanhongpin/core/storage/redis/redistest"
+	redis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundTripper(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(ts.Close)
+
+	client := newClient(t)
+	rl := ratelimit.NewFixedWindow(client, 2, time.Second)
+
+	hc := ts.Client()
+	hc.Transport = ratelimit.NewRoundTripper(client, hc.Transport, rl)
+
+	is := assert.New(t)
+	for range 2 {
+		resp, err := hc.Get(ts.URL)
+		is.Nil(err)
+		is.Equal(http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	_, err := hc.Get(ts.URL)
+	is.True(errors.Is(err, ratelimit.ErrThrottled))
+}
+
+func TestRoundTripper_Wait(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(ts.Close)
+
+	client := newClient(t)
+	rl := ratelimit.NewFixedWindow(client, 1, 100*time.Millisecond)
+
+	rt := ratelimit.NewRoundTripper(client, ts.Client().Transport, rl)
+	rt.Policy = ratelimit.Wait
+	rt.Interval = 10 * time.Millisecond
+
+	hc := ts.Client()
+	hc.Transport = rt
+
+	is := assert.New(t)
+	start := time.Now()
+	for range 2 {
+		resp, err := hc.Get(ts.URL)
+		is.Nil(err)
+		resp.Body.Close()
+	}
+	is.GreaterOrEqual(time.Since(start), 100*time.Millisecond)
+}
+
+func TestRoundTripper_RetryAfter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "10")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	t.Cleanup(ts.Close)
+
+	client := newClient(t)
+	rl := ratelimit.NewFixedWindow(client, 10, time.Second)
+
+	hc := ts.Client()
+	hc.Transport = ratelimit.NewRoundTripper(client, hc.Transport, rl)
+
+	is := assert.New(t)
+	resp, err := hc.Get(ts.URL)
+	is.Nil(err)
+	is.Equal(http.StatusTooManyRequests, resp.StatusCode)
+	resp.Body.Close()
+
+	// Other replicas sharing the same redis are throttled too.
+	other := ts.Client()
+	other.Transport = ratelimit.NewRoundTripper(client, other.Transport, rl)
+	_, err = other.Get(ts.URL)
+	is.True(errors.Is(err, ratelimit.ErrThrottled))
+}
+
+func TestRoundTripper_RateLimitRemaining(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", "10")
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(ts.Close)
+
+	client := newClient(t)
+	rl := ratelimit.NewFixedWindow(client, 10, time.Second)
+
+	hc := ts.Client()
+	hc.Transport = ratelimit.NewRoundTripper(client, hc.Transport, rl)
+
+	is := assert.New(t)
+	resp, err := hc.Get(ts.URL)
+	is.Nil(err)
+	resp.Body.Close()
+
+	_, err = hc.Get(ts.URL)
+	is.True(errors.Is(err, ratelimit.ErrThrottled))
+}
+
+func TestRoundTripper_HoldError(t *testing.T) {
+	// The round tripper has its own client, which is closed by the upstream
+	// to fail the hold.
+	client := redis.NewClient(&redis.Options{
+		Addr: redistest.Addr(),
+	})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client.Close()
+
+		w.Header().Set("Retry-After", "10")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	t.Cleanup(ts.Close)
+
+	rl := ratelimit.NewFixedWindow(newClient(t), 10, time.Second)
+
+	var holdErr error
+	rt := ratelimit.NewRoundTripper(client, ts.Client().Transport, rl)
+	rt.OnHoldError = func(r *http.Request, key string, err error) {
+		holdErr = err
+	}
+
+	hc := ts.Client()
+	hc.Transport = rt
+
+	is := assert.New(t)
+	resp, err := hc.Get(ts.URL)
+	is.Nil(err)
+	is.Equal(http.StatusTooManyRequests, resp.StatusCode)
+	resp.Body.Close()
+	is.ErrorIs(holdErr, redis.ErrClosed)
+}
@@ -12,7 +12,7 @@ import (
 
 type Cache[T any] struct {
 	Client  *redis.Client
-	Group   *Group
+	Group   *GroupT[T]
 	LockTTL time.Duration
 	WaitTTL time.Duration
 	Suffix  string
@@ -21,7 +21,7 @@ type Cache[T any] struct {
 func NewCache[T any](client *redis.Client) *Cache[T] {
 	return &Cache[T]{
 		Client:  client,
-		Group:   New(client),
+		Group:   NewGroupT[T](client),
 		LockTTL: 10 * time.Second,
 		WaitTTL: 10 * time.Second,
 		Suffix:  "fetch",
@@ -38,24 +38,21 @@ func (c *Cache[T]) LoadOrStore(ctx context.Context, key string, getter func(ctx
 		return t, false, err
 	}
 
-	did, err := c.Group.Do(ctx, fmt.Sprintf("%s:%s", key, c.Suffix), func(ctx context.Context) error {
+	// The result is shared with the waiters, so there is no need to load it
+	// again.
+	t, shared, err := c.Group.Do(ctx, fmt.Sprintf("%s:%s", key, c.Suffix), func(ctx context.Context) (T, error) {
 		v, err := getter(ctx)
 		if err != nil {
-			return err
+			return v, err
 		}
 
-		return c.store(ctx, key, v, ttl)
+		return v, c.store(ctx, key, v, ttl)
 	}, c.LockTTL, c.WaitTTL)
 	if err != nil {
 		return t, false, err
 	}
 
-	t, err = c.load(ctx, key)
-	if err != nil {
-		return t, false, err
-	}
-
-	return t, !did, nil
+	return t, shared, nil
 }
 
 func (c *Cache[T]) load(ctx context.Context, key string) (t T, err error) {
@@ -0,0 +1,249 @@
+package singleflight
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/alextanhongpin/core/dsync/lock"
+	"github.com/alextanhongpin/core/sync/singleflight"
+	redis "github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrNoResult = errors.New("group: no result")
+	ErrRemote   = errors.New("group: remote error")
+)
+
+// message is the encoded result published by the process that executed the
+// function.
+type message[T any] struct {
+	Data T      `json:"data"`
+	Err  string `json:"error,omitempty"`
+}
+
+type result[T any] struct {
+	data   T
+	shared bool
+}
+
+// GroupT is similar to Group, but the result of the function is published to
+// the waiting processes, so that they do not need to fetch it again.
+type GroupT[T any] struct {
+	BackOff BackOff
+	Client  *redis.Client
+	Locker  *lock.Locker
+	// ResultTTL is how long the result is kept for waiters that did not
+	// receive the published message.
+	ResultTTL time.Duration
+	// OnPublishError is called when the result could not be stored or
+	// published to the waiters. The result is still returned to the caller.
+	// Defaults to logging with slog.
+	OnPublishError func(ctx context.Context, key string, err error)
+
+	group *singleflight.Group[result[T]]
+}
+
+func NewGroupT[T any](client *redis.Client) *GroupT[T] {
+	return &GroupT[T]{
+		Client:         client,
+		Locker:         lock.New(client),
+		ResultTTL:      10 * time.Second,
+		OnPublishError: logPublishError,
+		group:          singleflight.New[result[T]](),
+	}
+}
+
+// Do executes the function once across all processes for the given key.
+// Shared is true when the result is returned by another caller, either in
+// the same process or in another process.
+// Errors returned by another process wraps ErrRemote.
+func (g *GroupT[T]) Do(ctx context.Context, key string, fn func(context.Context) (T, error), lockTTL, waitTTL time.Duration) (v T, shared bool, err error) {
+	res, shared, err := g.group.Do(ctx, key, func(ctx context.Context) (result[T], error) {
+		return g.doOrWait(ctx, key, fn, lockTTL, waitTTL)
+	})
+	if err != nil {
+		return v, false, err
+	}
+
+	return res.data, shared || res.shared, nil
+}
+
+func (g *GroupT[T]) doOrWait(ctx context.Context, key string, fn func(context.Context) (T, error), lockTTL, waitTTL time.Duration) (result[T], error) {
+	token, err := g.Locker.Lock(ctx, key, lockTTL)
+	if errors.Is(err, lock.ErrLocked) {
+		v, err := g.wait(ctx, key, waitTTL)
+		return result[T]{data: v, shared: true}, err
+	}
+
+	if err != nil {
+		return result[T]{}, err
+	}
+
+	v, err := g.do(ctx, key, token, fn, lockTTL)
+	return result[T]{data: v}, err
+}
+
+func (g *GroupT[T]) do(ctx context.Context, key string, token string, fn func(context.Context) (T, error), lockTTL time.Duration) (v T, err error) {
+	// Remove the result of the previous execution, so that waiters do not
+	// receive a stale result.
+	if err := g.Client.Del(ctx, resultKey(key)).Err(); err != nil {
+		g.Locker.Unlock(context.WithoutCancel(ctx), key, token)
+
+		return v, err
+	}
+
+	type output struct {
+		v   T
+		err error
+	}
+
+	ch := make(chan output, 1)
+	go func() {
+		defer close(ch)
+
+		v, err := fn(ctx)
+		ch <- output{v: v, err: err}
+	}()
+
+	t := time.NewTicker(lockTTL * 3 / 4)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			err := context.Cause(ctx)
+			g.unlock(context.WithoutCancel(ctx), key, token, message[T]{Err: err.Error()})
+
+			return v, err
+		case <-t.C:
+			if err := g.Locker.Extend(ctx, key, token, lockTTL); err != nil {
+				g.unlock(context.WithoutCancel(ctx), key, token, message[T]{Err: err.Error()})
+
+				return v, err
+			}
+		case out := <-ch:
+			msg := message[T]{Data: out.v}
+			if out.err != nil {
+				msg.Err = out.err.Error()
+			}
+
+			// The function has completed, so the failure to publish should
+			// not fail the caller. The waiters will time out or retry.
+			if err := g.unlock(context.WithoutCancel(ctx), key, token, msg); err != nil && g.OnPublishError != nil {
+				g.OnPublishError(ctx, key, err)
+			}
+
+			return out.v, out.err
+		}
+	}
+}
+
+func (g *GroupT[T]) wait(ctx context.Context, key string, waitTTL time.Duration) (v T, err error) {
+	// Subscribe before checking the result, so that the published result is
+	// not missed.
+	sub := g.Client.Subscribe(ctx, resultKey(key))
+	defer sub.Close()
+
+	if _, err := sub.Receive(ctx); err != nil {
+		return v, err
+	}
+
+	// Timeout after expiry.
+	timeout := time.After(waitTTL)
+
+	var i int
+	for {
+		select {
+		case <-time.After(g.backOffDuration(i)):
+			b, err := g.Client.Get(ctx, resultKey(key)).Bytes()
+			if err == nil {
+				return g.decode(b)
+			}
+			if !errors.Is(err, redis.Nil) {
+				return v, err
+			}
+
+			// The lock is released without result, e.g. the result expired.
+			exists, err := g.Client.Exists(ctx, key).Result()
+			if err != nil {
+				return v, err
+			}
+			if exists == 0 {
+				// The result may be stored and the lock released after the
+				// first check, so check the result again.
+				b, err := g.Client.Get(ctx, resultKey(key)).Bytes()
+				if errors.Is(err, redis.Nil) {
+					return v, ErrNoResult
+				}
+				if err != nil {
+					return v, err
+				}
+
+				return g.decode(b)
+			}
+			i++
+		case msg, ok := <-sub.Channel():
+			if !ok {
+				return v, ErrSubscriptionClosed
+			}
+
+			return g.decode([]byte(msg.Payload))
+		case <-timeout:
+			return v, ErrTimeout
+		case <-ctx.Done():
+			return v, context.Cause(ctx)
+		}
+	}
+}
+
+func (g *GroupT[T]) unlock(ctx context.Context, key, token string, msg message[T]) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Join(err, g.Locker.Unlock(ctx, key, token))
+	}
+
+	// Store the result before unlocking, so that waiters that polls will find
+	// the result once the lock is released.
+	if err := g.Client.Set(ctx, resultKey(key), b, g.ResultTTL).Err(); err != nil {
+		return err
+	}
+
+	if err := g.Locker.Unlock(ctx, key, token); err != nil {
+		return err
+	}
+
+	return g.Client.Publish(ctx, resultKey(key), b).Err()
+}
+
+func (g *GroupT[T]) decode(b []byte) (v T, err error) {
+	var msg message[T]
+	if err := json.Unmarshal(b, &msg); err != nil {
+		return v, err
+	}
+
+	if msg.Err != "" {
+		return v, fmt.Errorf("%w: %s", ErrRemote, msg.Err)
+	}
+
+	return msg.Data, nil
+}
+
+func (g *GroupT[T]) backOffDuration(i int) time.Duration {
+	if g.BackOff != nil {
+		return g.BackOff.Duration(i)
+	}
+
+	return NewExponentialBackOff(time.Second, time.Minute).Duration(i)
+}
+
+func logPublishError(ctx context.Context, key string, err error) {
+	slog.ErrorContext(ctx, "singleflight: failed to publish result", slog.String("key", key), slog.Any("err", err))
+}
+
+func resultKey(key string) string {
+	return key + ":result"
+}
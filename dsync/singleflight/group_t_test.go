@@ -0,0 +1,132 @@
+package singleflight_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/core/dsync/singleflight"
+	"github.com/alextanhongpin/core/storage/redis/redistest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupT(t *testing.T) {
+	var (
+		client  = redistest.New(t).Client()
+		lockTTL = 10 * time.Second
+		waitTTL = 10 * time.Second
+	)
+
+	t.Run("sync", func(t *testing.T) {
+		g := singleflight.NewGroupT[string](client)
+		key := t.Name()
+		v, shared, err := g.Do(ctx, key, func(ctx context.Context) (string, error) {
+			return "foo", nil
+		}, lockTTL, waitTTL)
+		is := assert.New(t)
+		is.Nil(err)
+		is.False(shared)
+		is.Equal("foo", v)
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		key := t.Name()
+		is := assert.New(t)
+
+		var did atomic.Int64
+		var shared atomic.Int64
+
+		n := 10
+
+		ch := make(chan bool)
+		var wg sync.WaitGroup
+		wg.Add(n)
+
+		// Each instance acts as a separate process.
+		for range n {
+			go func() {
+				defer wg.Done()
+				<-ch
+
+				g := singleflight.NewGroupT[int](client)
+				v, ok, err := g.Do(ctx, key, func(ctx context.Context) (int, error) {
+					did.Add(1)
+					time.Sleep(100 * time.Millisecond)
+					return 42, nil
+				}, lockTTL, waitTTL)
+				is.Nil(err)
+				is.Equal(42, v)
+				if ok {
+					shared.Add(1)
+				}
+			}()
+		}
+		close(ch)
+		wg.Wait()
+		is.Equal(int64(1), did.Load())
+		is.Equal(int64(n-1), shared.Load())
+	})
+
+	t.Run("publish error", func(t *testing.T) {
+		var publishErr error
+		g := singleflight.NewGroupT[chan int](client)
+		g.OnPublishError = func(ctx context.Context, key string, err error) {
+			publishErr = err
+		}
+
+		// Channels can not be encoded, so the result is not published.
+		ch := make(chan int)
+		v, _, err := g.Do(ctx, t.Name(), func(ctx context.Context) (chan int, error) {
+			return ch, nil
+		}, lockTTL, waitTTL)
+
+		is := assert.New(t)
+		is.Nil(err)
+		is.Equal(ch, v)
+
+		var jsonErr *json.UnsupportedTypeError
+		is.ErrorAs(publishErr, &jsonErr)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		key := t.Name()
+		is := assert.New(t)
+
+		wantErr := errors.New("want error")
+
+		errs := make(chan error, 2)
+		var wg sync.WaitGroup
+		wg.Add(2)
+
+		for range 2 {
+			go func() {
+				defer wg.Done()
+
+				g := singleflight.NewGroupT[int](client)
+				_, _, err := g.Do(ctx, key, func(ctx context.Context) (int, error) {
+					time.Sleep(100 * time.Millisecond)
+					return 0, wantErr
+				}, lockTTL, waitTTL)
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		var local, remote int
+		for err := range errs {
+			if errors.Is(err, wantErr) {
+				local++
+			}
+			if errors.Is(err, singleflight.ErrRemote) {
+				remote++
+			}
+		}
+		is.Equal(1, local)
+		is.Equal(1, remote)
+	})
+}
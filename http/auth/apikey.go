@@ -0,0 +1,287 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+var (
+	ErrAPIKeyInvalid  = errors.New("auth: invalid api key")
+	ErrAPIKeyNotFound = errors.New("auth: api key not found")
+)
+
+// APIKey is the stored API key. Only the hash of the secret is stored.
+type APIKey struct {
+	ID        string
+	Hash      string
+	Subject   string
+	Scopes    []string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+func (k *APIKey) GetScopes() []string {
+	return k.Scopes
+}
+
+// Expired reports whether the key is expired. Keys without expiry never
+// expire.
+func (k *APIKey) Expired(now time.Time) bool {
+	return !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt)
+}
+
+// Claims returns the key as claims, so that the policies, e.g. RequireScopes
+// applies to both the API keys and the JWTs.
+func (k *APIKey) Claims() *AccessClaims {
+	c := &AccessClaims{
+		Claims: Claims{
+			ID:       k.ID,
+			Subject:  k.Subject,
+			IssuedAt: jwt.NewNumericDate(k.CreatedAt),
+		},
+		Scope: strings.Join(k.Scopes, " "),
+	}
+	if !k.ExpiresAt.IsZero() {
+		c.ExpiresAt = jwt.NewNumericDate(k.ExpiresAt)
+	}
+
+	return c
+}
+
+// APIKeyStore stores the API keys by id.
+type APIKeyStore interface {
+	// Save creates or updates the key.
+	Save(ctx context.Context, key *APIKey) error
+
+	// Find returns ErrAPIKeyNotFound if the key does not exist.
+	Find(ctx context.Context, id string) (*APIKey, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// APIKeys issues and verifies the API keys in the format
+// <prefix>_<id>_<secret>, e.g. sk_3f1c..._9a0b....
+// The prefix identifies the keys, e.g. in secret scanners, and the id is used
+// to find the key.
+type APIKeys struct {
+	Store  APIKeyStore
+	Prefix string
+	Now    func() time.Time
+}
+
+func NewAPIKeys(store APIKeyStore, prefix string) *APIKeys {
+	return &APIKeys{
+		Store:  store,
+		Prefix: prefix,
+		Now:    time.Now,
+	}
+}
+
+// Issue issues the API key for the subject. The key does not expire if the
+// ttl is zero.
+// The returned key is only available once, since only the hash is stored.
+func (a *APIKeys) Issue(ctx context.Context, subject string, scopes []string, ttl time.Duration) (string, *APIKey, error) {
+	if subject == "" {
+		return "", nil, fmt.Errorf("%w: subject is required", ErrClaimsInvalid)
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return "", nil, err
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := a.Now()
+	key := &APIKey{
+		ID:        id,
+		Hash:      hashSecret(secret),
+		Subject:   subject,
+		Scopes:    slices.Clone(scopes),
+		CreatedAt: now,
+	}
+	if ttl > 0 {
+		key.ExpiresAt = now.Add(ttl)
+	}
+
+	if err := a.Store.Save(ctx, key); err != nil {
+		return "", nil, err
+	}
+
+	return a.Prefix + "_" + id + "_" + secret, key, nil
+}
+
+// Verify returns the API key, or ErrAPIKeyInvalid if the key is malformed,
+// not found, expired, or the secret does not match.
+func (a *APIKeys) Verify(ctx context.Context, apiKey string) (*APIKey, error) {
+	rest, ok := strings.CutPrefix(apiKey, a.Prefix+"_")
+	if !ok {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || id == "" || secret == "" {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	key, err := a.Store.Find(ctx, id)
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		return nil, ErrAPIKeyInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashSecret(secret))) != 1 {
+		return nil, ErrAPIKeyInvalid
+	}
+	if key.Expired(a.Now()) {
+		return nil, fmt.Errorf("%w: expired", ErrAPIKeyInvalid)
+	}
+
+	return key, nil
+}
+
+// Rotate issues the new key with the same subject, scopes and ttl. The old
+// key remains valid for the overlap, so that the clients can switch to the
+// new key without downtime.
+func (a *APIKeys) Rotate(ctx context.Context, id string, overlap time.Duration) (string, *APIKey, error) {
+	old, err := a.Store.Find(ctx, id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := a.Now()
+	if old.Expired(now) {
+		return "", nil, fmt.Errorf("%w: expired", ErrAPIKeyInvalid)
+	}
+
+	var ttl time.Duration
+	if !old.ExpiresAt.IsZero() {
+		ttl = old.ExpiresAt.Sub(old.CreatedAt)
+	}
+
+	apiKey, key, err := a.Issue(ctx, old.Subject, old.Scopes, ttl)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if exp := now.Add(overlap); old.ExpiresAt.IsZero() || exp.Before(old.ExpiresAt) {
+		old.ExpiresAt = exp
+		if err := a.Store.Save(ctx, old); err != nil {
+			return "", nil, err
+		}
+	}
+
+	return apiKey, key, nil
+}
+
+// Revoke deletes the key immediately.
+func (a *APIKeys) Revoke(ctx context.Context, id string) error {
+	return a.Store.Delete(ctx, id)
+}
+
+// APIKeyAuth returns the API key from the X-API-Key header.
+func APIKeyAuth(r *http.Request) (string, bool) {
+	key := r.Header.Get(APIKeyHeader)
+	return key, key != ""
+}
+
+// APIKeyHandler verifies the API key from the X-API-Key header.
+// The key is set in the APIKeyContext, and the claims of the key in the
+// ClaimsFromContext, so that Authorize, RequireScopes and
+// RequireBearerHandler works for the API keys too.
+func APIKeyHandler(h http.Handler, keys *APIKeys) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiKey, ok := APIKeyAuth(r); ok {
+			key, err := keys.Verify(r.Context(), apiKey)
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+
+				if logger, ok := LoggerContext.Value(r.Context()); ok {
+					logger.Error("failed to verify api key", slog.String("err", err.Error()))
+				}
+
+				return
+			}
+
+			ctx := APIKeyContext.WithValue(r.Context(), key)
+			ctx = claimsContext.WithValue(ctx, key.Claims())
+			r = r.WithContext(ctx)
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// MemoryAPIKeyStore is the in-memory APIKeyStore, for tests.
+type MemoryAPIKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]APIKey
+}
+
+var _ APIKeyStore = (*MemoryAPIKeyStore)(nil)
+
+func NewMemoryAPIKeyStore() *MemoryAPIKeyStore {
+	return &MemoryAPIKeyStore{
+		keys: make(map[string]APIKey),
+	}
+}
+
+func (s *MemoryAPIKeyStore) Save(ctx context.Context, key *APIKey) error {
+	s.mu.Lock()
+	s.keys[key.ID] = *key
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *MemoryAPIKeyStore) Find(ctx context.Context, id string) (*APIKey, error) {
+	s.mu.RLock()
+	key, ok := s.keys[id]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	return &key, nil
+}
+
+func (s *MemoryAPIKeyStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	delete(s.keys, id)
+	s.mu.Unlock()
+
+	return nil
+}
+
+func hashSecret(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,112 @@
+package auth_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/core/http/auth"
+	"github.com/alextanhongpin/core/http/chain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeys(t *testing.T) {
+	ctx := context.Background()
+
+	now := time.Now()
+	keys := auth.NewAPIKeys(auth.NewMemoryAPIKeyStore(), "sk_test")
+	keys.Now = func() time.Time {
+		return now
+	}
+
+	apiKey, key, err := keys.Issue(ctx, "billing-service", []string{"orders:read"}, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	is := assert.New(t)
+	is.True(strings.HasPrefix(apiKey, "sk_test_"+key.ID+"_"))
+	is.NotContains(key.Hash, strings.TrimPrefix(apiKey, "sk_test_"+key.ID+"_"), "only the hash is stored")
+
+	got, err := keys.Verify(ctx, apiKey)
+	is.Nil(err)
+	is.Equal("billing-service", got.Subject)
+
+	t.Run("invalid", func(t *testing.T) {
+		is := assert.New(t)
+		for _, k := range []string{"", "sk_test_", "pk_" + apiKey, apiKey + "x", "sk_test_unknown_secret"} {
+			_, err := keys.Verify(ctx, k)
+			is.ErrorIs(err, auth.ErrAPIKeyInvalid, k)
+		}
+	})
+
+	t.Run("rotate", func(t *testing.T) {
+		newAPIKey, newKey, err := keys.Rotate(ctx, key.ID, time.Hour)
+		is := assert.New(t)
+		is.Nil(err)
+		is.NotEqual(key.ID, newKey.ID)
+		is.Equal(key.Scopes, newKey.Scopes)
+
+		_, err = keys.Verify(ctx, apiKey)
+		is.Nil(err, "the old key is valid during the overlap")
+		_, err = keys.Verify(ctx, newAPIKey)
+		is.Nil(err)
+
+		now = now.Add(time.Hour)
+		_, err = keys.Verify(ctx, apiKey)
+		is.ErrorIs(err, auth.ErrAPIKeyInvalid)
+		_, err = keys.Verify(ctx, newAPIKey)
+		is.Nil(err)
+
+		is.Nil(keys.Revoke(ctx, newKey.ID))
+		_, err = keys.Verify(ctx, newAPIKey)
+		is.ErrorIs(err, auth.ErrAPIKeyInvalid)
+	})
+}
+
+func TestAPIKeyHandler(t *testing.T) {
+	ctx := context.Background()
+	keys := auth.NewAPIKeys(auth.NewMemoryAPIKeyStore(), "sk")
+	apiKey, _, err := keys.Issue(ctx, "billing-service", []string{"orders:read"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := auth.APIKeyContext.MustValue(r.Context())
+		fmt.Fprint(w, key.Subject)
+	})
+
+	tests := map[string]struct {
+		mw     chain.Middleware
+		apiKey string
+		code   int
+	}{
+		"success":       {auth.RequireScopes("orders:read"), apiKey, http.StatusOK},
+		"missing scope": {auth.RequireScopes("orders:write"), apiKey, http.StatusForbidden},
+		"invalid":       {auth.RequireScopes("orders:read"), apiKey + "x", http.StatusUnauthorized},
+		"no key":        {auth.RequireScopes("orders:read"), "", http.StatusUnauthorized},
+	}
+
+	for name, ts := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/orders", nil)
+			if ts.apiKey != "" {
+				r.Header.Set(auth.APIKeyHeader, ts.apiKey)
+			}
+
+			auth.APIKeyHandler(ts.mw(h), keys).ServeHTTP(w, r)
+
+			is := assert.New(t)
+			is.Equal(ts.code, w.Code)
+			if ts.code == http.StatusOK {
+				is.Equal("billing-service", w.Body.String())
+			}
+		})
+	}
+}
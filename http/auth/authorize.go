@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/alextanhongpin/core/http/chain"
+	"github.com/alextanhongpin/core/http/response"
+	"github.com/alextanhongpin/errors/causes"
+	"github.com/alextanhongpin/errors/codes"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnauthorized = causes.New(codes.Unauthorized, "auth/unauthorized", "Authentication is required")
+	ErrForbidden    = causes.New(codes.Forbidden, "auth/forbidden", "You do not have permission to perform this action")
+)
+
+// Policy reports whether the claims are allowed to make the request.
+type Policy func(r *http.Request, claims jwt.Claims) bool
+
+// Authorize allows the request if all the policies allow the claims set by
+// the BearerVerifierHandler.
+// Requests without claims are rejected with 401 and ErrUnauthorized, and
+// denied requests with 403 and ErrForbidden.
+func Authorize(policies ...Policy) chain.Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := claimsValue(r.Context())
+			if !ok {
+				response.Error(w, ErrUnauthorized)
+
+				return
+			}
+
+			for _, allow := range policies {
+				if !allow(r, claims) {
+					response.Error(w, ErrForbidden)
+
+					return
+				}
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireScopes requires the claims to have all the scopes, e.g.
+// RequireScopes("orders:write").
+func RequireScopes(scopes ...string) chain.Middleware {
+	return Authorize(HasScopes(scopes...))
+}
+
+// RequireRole requires the claims to have any of the roles.
+func RequireRole(roles ...string) chain.Middleware {
+	return Authorize(HasRole(roles...))
+}
+
+// HasScopes allows the claims with all the scopes. The claims must
+// implement GetScopes, e.g. AccessClaims.
+func HasScopes(scopes ...string) Policy {
+	return func(r *http.Request, claims jwt.Claims) bool {
+		c, ok := claims.(interface{ GetScopes() []string })
+		if !ok {
+			return false
+		}
+
+		granted := c.GetScopes()
+		for _, scope := range scopes {
+			if !slices.Contains(granted, scope) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// HasRole allows the claims with any of the roles. The claims must implement
+// GetRoles, e.g. AccessClaims.
+func HasRole(roles ...string) Policy {
+	return func(r *http.Request, claims jwt.Claims) bool {
+		c, ok := claims.(interface{ GetRoles() []string })
+		if !ok {
+			return false
+		}
+
+		return slices.ContainsFunc(c.GetRoles(), func(role string) bool {
+			return slices.Contains(roles, role)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package auth_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/core/http/auth"
+	"github.com/alextanhongpin/core/http/chain"
+	"github.com/alextanhongpin/testdump/httpdump"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorize(t *testing.T) {
+	jwt := auth.NewJWT[auth.AccessClaims]([]byte("secret"))
+
+	sign := func(t *testing.T, scope string, roles ...string) string {
+		t.Helper()
+
+		token, err := jwt.Sign(auth.AccessClaims{
+			Claims:   auth.Claims{Subject: "john.appleseed@mail.com"},
+			Scope:    scope,
+			Roles:    roles,
+			TenantID: "acme",
+		}, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return token
+	}
+
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := auth.ClaimsFromContext[auth.AccessClaims](r.Context())
+		fmt.Fprintf(w, "hello %s from %s", claims.Subject, claims.TenantID)
+	})
+
+	tests := map[string]struct {
+		mw    chain.Middleware
+		token string
+	}{
+		"scopes":         {auth.RequireScopes("orders:read", "orders:write"), sign(t, "orders:read orders:write")},
+		"missing scope":  {auth.RequireScopes("orders:read", "orders:write"), sign(t, "orders:read")},
+		"role":           {auth.RequireRole("admin", "staff"), sign(t, "", "staff")},
+		"missing role":   {auth.RequireRole("admin"), sign(t, "", "staff")},
+		"unauthorized":   {auth.RequireRole("admin"), ""},
+		"custom policy":  {auth.Authorize(tenant("acme")), sign(t, "")},
+		"denied policy":  {auth.Authorize(tenant("other")), sign(t, "")},
+		"invalid claims": {auth.RequireScopes("orders:read"), "invalid"},
+	}
+
+	for name, ts := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/orders", nil)
+			if ts.token != "" {
+				r.Header.Set("Authorization", "Bearer "+ts.token)
+			}
+
+			h := auth.BearerVerifierHandler(ts.mw(h), jwt)
+			httpdump.Handler(t, h, httpdump.IgnoreRequestHeaders("Authorization")).ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestRequireBearerHandlerCustomClaims(t *testing.T) {
+	jwt := auth.NewJWT[auth.AccessClaims]([]byte("secret"))
+	token, err := jwt.Sign(auth.AccessClaims{
+		Claims: auth.Claims{Subject: "john"},
+	}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := auth.BearerVerifierHandler(auth.RequireBearerHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ok := auth.ClaimsContext.Value(r.Context())
+		is := assert.New(t)
+		is.False(ok, "custom claims are not Claims")
+
+		claims, ok := auth.ClaimsFromContext[auth.AccessClaims](r.Context())
+		is.True(ok)
+		is.Equal("john", claims.Subject)
+	})), jwt)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	h.ServeHTTP(w, r)
+
+	is := assert.New(t)
+	is.Equal(http.StatusOK, w.Code)
+}
+
+func tenant(id string) auth.Policy {
+	return func(r *http.Request, claims jwt.Claims) bool {
+		c, ok := claims.(*auth.AccessClaims)
+		return ok && c.TenantID == id
+	}
+}
@@ -0,0 +1,106 @@
+package authredis_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/core/http/auth"
+	"github.com/alextanhongpin/core/http/auth/authredis"
+	"github.com/alextanhongpin/core/storage/redis/redistest"
+	"github.com/stretchr/testify/assert"
+)
+
+var ctx = context.Background()
+
+func TestMain(m *testing.M) {
+	stop := redistest.Init()
+	code := m.Run()
+	stop()
+	os.Exit(code)
+}
+
+func TestDenylist(t *testing.T) {
+	denylist := authredis.NewDenylist(redistest.Client(t))
+	jwt := auth.NewJWT[auth.Claims]([]byte("secret"))
+	jwt.Denylist = denylist
+
+	token, err := jwt.Sign(auth.Claims{Subject: "john"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	is := assert.New(t)
+	claims, err := jwt.Verify(token)
+	is.Nil(err)
+
+	is.Nil(jwt.Revoke(ctx, claims))
+	_, err = jwt.Verify(token)
+	is.ErrorIs(err, auth.ErrTokenRevoked)
+
+	t.Run("subject", func(t *testing.T) {
+		token, err := jwt.Sign(auth.Claims{Subject: "jane"}, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		is := assert.New(t)
+		_, err = jwt.Verify(token)
+		is.Nil(err)
+
+		is.Nil(denylist.DenySubject(ctx, "jane", time.Hour))
+		_, err = jwt.Verify(token)
+		is.ErrorIs(err, auth.ErrTokenRevoked)
+	})
+
+	t.Run("subject before issued", func(t *testing.T) {
+		denylist := authredis.NewDenylist(redistest.Client(t))
+		denylist.Now = func() time.Time {
+			return time.Now().Add(-time.Minute)
+		}
+		jwt := auth.NewJWT[auth.Claims]([]byte("secret"))
+		jwt.Denylist = denylist
+
+		is := assert.New(t)
+		is.Nil(denylist.DenySubject(ctx, "jack", time.Hour))
+
+		token, err := jwt.Sign(auth.Claims{Subject: "jack"}, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = jwt.Verify(token)
+		is.Nil(err, "tokens issued after the revocation are valid")
+	})
+}
+
+func TestRefreshStore(t *testing.T) {
+	r := auth.NewRefresher(authredis.NewRefreshStore(redistest.Client(t)))
+
+	token, err := r.Issue(ctx, "john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := r.Issue(ctx, "john")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	is := assert.New(t)
+	next, rt, err := r.Rotate(ctx, token)
+	is.Nil(err)
+	is.Equal("john", rt.Subject)
+
+	_, _, err = r.Rotate(ctx, token)
+	is.ErrorIs(err, auth.ErrRefreshTokenReused)
+
+	_, _, err = r.Rotate(ctx, next)
+	is.ErrorIs(err, auth.ErrRefreshTokenInvalid, "family is revoked on reuse")
+
+	other, _, err = r.Rotate(ctx, other)
+	is.Nil(err)
+
+	is.Nil(r.RevokeSubject(ctx, "john"))
+	_, _, err = r.Rotate(ctx, other)
+	is.ErrorIs(err, auth.ErrRefreshTokenInvalid)
+}
@@ -0,0 +1,78 @@
+// Package authredis implements the auth stores with redis.
+package authredis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/alextanhongpin/core/http/auth"
+	"github.com/redis/go-redis/v9"
+)
+
+// Denylist is the auth.Denylist backed by redis. The entries expire with the
+// tokens.
+type Denylist struct {
+	Now func() time.Time
+
+	client *redis.Client
+	prefix string
+}
+
+var _ auth.Denylist = (*Denylist)(nil)
+
+func NewDenylist(client *redis.Client) *Denylist {
+	return &Denylist{
+		Now:    time.Now,
+		client: client,
+		prefix: "auth:denylist:",
+	}
+}
+
+func (d *Denylist) Deny(ctx context.Context, jti string, ttl time.Duration) error {
+	return d.client.Set(ctx, d.jtiKey(jti), 1, ttl).Err()
+}
+
+// DenySubject stores the time of revocation, in seconds like the iat.
+func (d *Denylist) DenySubject(ctx context.Context, subject string, ttl time.Duration) error {
+	return d.client.Set(ctx, d.subjectKey(subject), d.Now().Unix(), ttl).Err()
+}
+
+func (d *Denylist) Denied(ctx context.Context, claims *auth.Claims) (bool, error) {
+	vals, err := d.client.MGet(ctx, d.jtiKey(claims.ID), d.subjectKey(claims.Subject)).Result()
+	if err != nil {
+		return false, err
+	}
+	if vals[0] != nil {
+		return true, nil
+	}
+	if vals[1] == nil {
+		return false, nil
+	}
+
+	s, ok := vals[1].(string)
+	if !ok {
+		return false, errors.New("authredis: invalid denylist value")
+	}
+
+	at, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return false, err
+	}
+
+	// Tokens without iat are always revoked.
+	if claims.IssuedAt == nil {
+		return true, nil
+	}
+
+	return claims.IssuedAt.Unix() <= at, nil
+}
+
+func (d *Denylist) jtiKey(jti string) string {
+	return d.prefix + "jti:" + jti
+}
+
+func (d *Denylist) subjectKey(subject string) string {
+	return d.prefix + "sub:" + subject
+}
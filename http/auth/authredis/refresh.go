@@ -0,0 +1,114 @@
+package authredis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/alextanhongpin/core/http/auth"
+	"github.com/redis/go-redis/v9"
+)
+
+// RefreshStore is the auth.RefreshStore backed by redis.
+type RefreshStore struct {
+	client *redis.Client
+	prefix string
+}
+
+var _ auth.RefreshStore = (*RefreshStore)(nil)
+
+func NewRefreshStore(client *redis.Client) *RefreshStore {
+	return &RefreshStore{
+		client: client,
+		prefix: "auth:refresh:",
+	}
+}
+
+func (s *RefreshStore) Save(ctx context.Context, token *auth.RefreshToken) error {
+	keys := []string{s.tokenKey(token.Hash), s.familyKey(token.Family), s.subjectKey(token.Subject)}
+	n, err := save.Run(ctx, s.client, keys, token.Family, token.Subject, token.ExpiresAt.UnixMilli()).Int()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return auth.ErrRefreshTokenInvalid
+	}
+
+	return nil
+}
+
+func (s *RefreshStore) Consume(ctx context.Context, hash string) (*auth.RefreshToken, error) {
+	vals, err := s.client.HMGet(ctx, s.tokenKey(hash), "family", "subject", "expiresAt").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	family, _ := vals[0].(string)
+	subject, _ := vals[1].(string)
+	exp, _ := vals[2].(string)
+	if family == "" {
+		return nil, auth.ErrRefreshTokenInvalid
+	}
+
+	ms, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &auth.RefreshToken{
+		Hash:      hash,
+		Family:    family,
+		Subject:   subject,
+		ExpiresAt: time.UnixMilli(ms),
+	}
+
+	status, err := consume.Run(ctx, s.client, []string{s.tokenKey(hash), s.familyKey(family)}).Text()
+	if err != nil {
+		return nil, err
+	}
+
+	switch status {
+	case "ok":
+		return token, nil
+	case "reused":
+		return token, auth.ErrRefreshTokenReused
+	case "invalid":
+		return nil, auth.ErrRefreshTokenInvalid
+	default:
+		return nil, errors.New("authredis: unknown status " + status)
+	}
+}
+
+func (s *RefreshStore) RevokeFamily(ctx context.Context, family string) error {
+	return revoke.Run(ctx, s.client, []string{s.familyKey(family)}).Err()
+}
+
+func (s *RefreshStore) RevokeSubject(ctx context.Context, subject string) error {
+	families, err := s.client.SMembers(ctx, s.subjectKey(subject)).Result()
+	if err != nil {
+		return err
+	}
+	if len(families) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(families))
+	for i, f := range families {
+		keys[i] = s.familyKey(f)
+	}
+
+	return revoke.Run(ctx, s.client, keys).Err()
+}
+
+func (s *RefreshStore) tokenKey(hash string) string {
+	return s.prefix + "token:" + hash
+}
+
+func (s *RefreshStore) familyKey(family string) string {
+	return s.prefix + "family:" + family
+}
+
+func (s *RefreshStore) subjectKey(subject string) string {
+	return s.prefix + "subject:" + subject
+}
@@ -0,0 +1,66 @@
+package authredis
+
+import "github.com/redis/go-redis/v9"
+
+var save = redis.NewScript(`
+	-- KEYS[1]: The refresh token key
+	-- KEYS[2]: The family key
+	-- KEYS[3]: The subject key, with the set of families
+	-- ARGV[1]: The family
+	-- ARGV[2]: The subject
+	-- ARGV[3]: The expiry in unix milliseconds
+	local token = KEYS[1]
+	local family = KEYS[2]
+	local subject = KEYS[3]
+	local exp = tonumber(ARGV[3])
+
+	if redis.call('HGET', family, 'revoked') == '1' then
+		return 0
+	end
+
+	redis.call('HSET', token, 'family', ARGV[1], 'subject', ARGV[2], 'expiresAt', ARGV[3], 'used', '0')
+	redis.call('PEXPIREAT', token, exp)
+
+	-- The family and subject lives as long as the latest token.
+	redis.call('HSETNX', family, 'revoked', '0')
+	redis.call('PEXPIREAT', family, exp)
+	redis.call('SADD', subject, ARGV[1])
+	redis.call('PEXPIREAT', subject, exp)
+
+	return 1
+`)
+
+var consume = redis.NewScript(`
+	-- KEYS[1]: The refresh token key
+	-- KEYS[2]: The family key
+	local token = KEYS[1]
+	local family = KEYS[2]
+
+	if redis.call('EXISTS', token) == 0 then
+		return 'invalid'
+	end
+
+	if redis.call('HGET', family, 'revoked') ~= '0' then
+		return 'invalid'
+	end
+
+	if redis.call('HGET', token, 'used') == '1' then
+		return 'reused'
+	end
+
+	redis.call('HSET', token, 'used', '1')
+
+	return 'ok'
+`)
+
+// revoke marks the existing families as revoked, without extending them.
+var revoke = redis.NewScript(`
+	-- KEYS: The family keys
+	for _, family in ipairs(KEYS) do
+		if redis.call('EXISTS', family) == 1 then
+			redis.call('HSET', family, 'revoked', '1')
+		end
+	end
+
+	return 0
+`)
@@ -1,15 +1,29 @@
 package auth
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
+
+	"github.com/golang-jwt/jwt/v5"
 )
 
+type verifier[T any] interface {
+	VerifyContext(ctx context.Context, bearerToken string) (*T, error)
+}
+
 func BearerHandler(h http.Handler, secret []byte) http.Handler {
-	jwt := NewJWT(secret)
+	return BearerVerifierHandler(h, NewJWT[Claims](secret))
+}
+
+// BearerVerifierHandler is similar to BearerHandler, but verifies the token
+// with the verifier, e.g. JWT, Keyring or JWKSVerifier.
+// The claims can be obtained with ClaimsFromContext, or ClaimsContext for
+// Claims.
+func BearerVerifierHandler[T jwt.Claims](h http.Handler, v verifier[T]) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if token, ok := BearerAuth(r); ok {
-			claims, err := jwt.Verify(token)
+			claims, err := v.VerifyContext(r.Context(), token)
 			if err != nil {
 				w.WriteHeader(http.StatusUnauthorized)
 
@@ -20,7 +34,11 @@ func BearerHandler(h http.Handler, secret []byte) http.Handler {
 				return
 			}
 
-			ctx := ClaimsContext.WithValue(r.Context(), claims)
+			ctx := r.Context()
+			if c, ok := any(claims).(*Claims); ok {
+				ctx = ClaimsContext.WithValue(ctx, c)
+			}
+			ctx = claimsContext.WithValue(ctx, any(claims).(jwt.Claims))
 			r = r.WithContext(ctx)
 		}
 
@@ -30,7 +48,7 @@ func BearerHandler(h http.Handler, secret []byte) http.Handler {
 
 func RequireBearerHandler(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if _, ok := ClaimsContext.Value(r.Context()); ok {
+		if _, ok := claimsValue(r.Context()); ok {
 			h.ServeHTTP(w, r)
 
 			return
@@ -1,13 +1,38 @@
 package auth
 
 import (
+	"context"
 	"log/slog"
 
 	"github.com/alextanhongpin/core/http/contextkey"
+	"github.com/golang-jwt/jwt/v5"
 )
 
 var (
 	ClaimsContext contextkey.Key[*Claims] = "claims"
 
+	APIKeyContext contextkey.Key[*APIKey] = "api_key"
+
 	LoggerContext contextkey.Key[*slog.Logger] = "logger"
+
+	claimsContext contextkey.Key[jwt.Claims] = "claims"
 )
+
+// ClaimsFromContext returns the claims T set by the BearerVerifierHandler.
+func ClaimsFromContext[T any](ctx context.Context) (*T, bool) {
+	c, _ := claimsValue(ctx)
+	t, ok := any(c).(*T)
+	return t, ok
+}
+
+func claimsValue(ctx context.Context) (jwt.Claims, bool) {
+	if c, ok := claimsContext.Value(ctx); ok {
+		return c, true
+	}
+
+	if c, ok := ClaimsContext.Value(ctx); ok {
+		return c, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,374 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/alextanhongpin/core/http/response"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/singleflight"
+)
+
+// JWK is the JSON Web Key of the public key, as defined in RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+
+	// RSA.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC and OKP.
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []*JWK `json:"keys"`
+}
+
+var curves = map[string]struct {
+	elliptic elliptic.Curve
+	ecdh     ecdh.Curve
+}{
+	"P-256": {elliptic.P256(), ecdh.P256()},
+	"P-384": {elliptic.P384(), ecdh.P384()},
+	"P-521": {elliptic.P521(), ecdh.P521()},
+}
+
+// JWK returns the public key as JWK. HMAC keys are not supported.
+func (k *Key) JWK() (*JWK, error) {
+	enc := base64.RawURLEncoding
+	jwk := &JWK{
+		Kid: k.ID,
+		Use: "sig",
+		Alg: k.Method.Alg(),
+	}
+
+	switch pub := k.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = enc.EncodeToString(pub.N.Bytes())
+		jwk.E = enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = enc.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = enc.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = enc.EncodeToString(pub)
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrKeyUnsupported, k.Public)
+	}
+
+	return jwk, nil
+}
+
+// Key returns the key for verification.
+func (j *JWK) Key() (*Key, error) {
+	pub, err := j.publicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := NewPublicKey(j.Kid, pub)
+	if err != nil {
+		return nil, err
+	}
+
+	if j.Alg == "" || j.Alg == key.Method.Alg() {
+		return key, nil
+	}
+
+	// RSA keys can be used with the other RSA algorithms, e.g. PS256.
+	if _, ok := key.Public.(*rsa.PublicKey); ok {
+		switch m := jwt.GetSigningMethod(j.Alg); m.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			key.Method = m
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: alg %s does not match kty %s", ErrKeyUnsupported, j.Alg, j.Kty)
+}
+
+func (j *JWK) publicKey() (crypto.PublicKey, error) {
+	enc := base64.RawURLEncoding
+	decode := func(name, s string) ([]byte, error) {
+		b, err := enc.DecodeString(s)
+		if err != nil || len(b) == 0 {
+			return nil, fmt.Errorf("%w: invalid %s", ErrKeyUnsupported, name)
+		}
+
+		return b, nil
+	}
+
+	switch j.Kty {
+	case "RSA":
+		n, err := decode("n", j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode("e", j.E)
+		if err != nil {
+			return nil, err
+		}
+		if len(e) > 4 {
+			return nil, fmt.Errorf("%w: invalid e", ErrKeyUnsupported)
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		c, ok := curves[j.Crv]
+		if !ok {
+			return nil, fmt.Errorf("%w: crv %s", ErrKeyUnsupported, j.Crv)
+		}
+		x, err := decode("x", j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode("y", j.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		// Checks that the point is on the curve.
+		if _, err := c.ecdh.NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrKeyUnsupported, err)
+		}
+
+		return &ecdsa.PublicKey{
+			Curve: c.elliptic,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	case "OKP":
+		x, err := decode("x", j.X)
+		if err != nil {
+			return nil, err
+		}
+		if j.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: crv %s", ErrKeyUnsupported, j.Crv)
+		}
+
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("%w: kty %s", ErrKeyUnsupported, j.Kty)
+	}
+}
+
+// Keyring returns the keyring of the signature keys. Keys of other uses, or
+// unsupported types, are skipped.
+func (j *JWKS) Keyring() *Keyring {
+	kr := NewKeyring()
+	for _, jwk := range j.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.Key()
+		if err != nil {
+			continue
+		}
+
+		kr.keys = append(kr.keys, key)
+	}
+
+	return kr
+}
+
+// JWKSHandler publishes the public keys of the keyring.
+func JWKSHandler(kr *Keyring) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+
+		if err := response.JSON(w, kr.JWKS()); err != nil {
+			response.Error(w, err)
+		}
+	})
+}
+
+// JWKSVerifier verifies the tokens with the keys from the JWKS document.
+// The keys are cached for the TTL. Tokens with unknown kid reloads the keys,
+// at most once every RefreshInterval, to pick up the rotated keys.
+// When the load fails, the stale keys are still used, and the load is retried
+// after the RefreshInterval.
+type JWKSVerifier struct {
+	Load            func(ctx context.Context) (*JWKS, error)
+	TTL             time.Duration
+	RefreshInterval time.Duration
+	Now             func() time.Time
+
+	group       singleflight.Group
+	mu          sync.Mutex
+	keys        *Keyring
+	attempts    int
+	attemptedAt time.Time
+	expiresAt   time.Time
+}
+
+func NewJWKSVerifier(load func(ctx context.Context) (*JWKS, error)) *JWKSVerifier {
+	return &JWKSVerifier{
+		Load:            load,
+		TTL:             15 * time.Minute,
+		RefreshInterval: time.Minute,
+		Now:             time.Now,
+	}
+}
+
+func (v *JWKSVerifier) Verify(bearerToken string) (*Claims, error) {
+	return v.VerifyContext(context.Background(), bearerToken)
+}
+
+func (v *JWKSVerifier) VerifyContext(ctx context.Context, bearerToken string) (*Claims, error) {
+	return verifyToken[Claims](ctx, bearerToken, v.verifyingKey, v.Now)
+}
+
+// signingKey returns ErrKeyNotFound, since the JWKS only has the public keys.
+func (v *JWKSVerifier) signingKey() (*Key, error) {
+	return nil, fmt.Errorf("%w: jwks can only verify", ErrKeyNotFound)
+}
+
+func (v *JWKSVerifier) verifyingKey(ctx context.Context, kid string) (*Key, error) {
+	v.mu.Lock()
+	now := v.Now()
+	keys := v.keys
+	attempts := v.attempts
+	fresh := keys != nil && now.Before(v.expiresAt)
+	throttled := now.Sub(v.attemptedAt) < v.RefreshInterval
+	v.mu.Unlock()
+
+	if key, ok := lookupKey(keys, kid); ok && (fresh || throttled) {
+		return key, nil
+	}
+	if throttled {
+		return nil, fmt.Errorf("%w: %q", ErrKeyNotFound, kid)
+	}
+
+	// The load is shared by the concurrent requests, and is not cancelled
+	// when the first request is.
+	res, err, _ := v.group.Do("", func() (any, error) {
+		return v.load(context.WithoutCancel(ctx), attempts)
+	})
+	if err != nil {
+		// Keep using the stale keys until the load succeeds.
+		if key, ok := lookupKey(keys, kid); ok {
+			return key, nil
+		}
+
+		return nil, err
+	}
+
+	key, ok := lookupKey(res.(*Keyring), kid)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrKeyNotFound, kid)
+	}
+
+	return key, nil
+}
+
+func lookupKey(keys *Keyring, kid string) (*Key, bool) {
+	if keys == nil {
+		return nil, false
+	}
+
+	return keys.Key(kid)
+}
+
+// load loads the keys. The keys are kept when the load fails, and the next
+// load is attempted after the RefreshInterval.
+// The load is skipped if the keys are loaded by another request after the
+// attempts are observed.
+func (v *JWKSVerifier) load(ctx context.Context, attempts int) (*Keyring, error) {
+	v.mu.Lock()
+	if v.attempts != attempts {
+		defer v.mu.Unlock()
+
+		return v.keys, nil
+	}
+	v.mu.Unlock()
+
+	jwks, err := v.Load(ctx)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := v.Now()
+	v.attempts++
+	v.attemptedAt = now
+	if err != nil {
+		return nil, err
+	}
+
+	v.keys = jwks.Keyring()
+	v.expiresAt = now.Add(v.TTL)
+
+	return v.keys, nil
+}
+
+// JWKSFromFile loads the JWKS document from the file.
+func JWKSFromFile(path string) func(ctx context.Context) (*JWKS, error) {
+	return func(ctx context.Context) (*JWKS, error) {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var jwks JWKS
+		if err := json.Unmarshal(b, &jwks); err != nil {
+			return nil, err
+		}
+
+		return &jwks, nil
+	}
+}
+
+// JWKSFromURL loads the JWKS document from the endpoint.
+func JWKSFromURL(client *http.Client, url string) func(ctx context.Context) (*JWKS, error) {
+	return func(ctx context.Context) (*JWKS, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("auth: failed to load jwks: %s", resp.Status)
+		}
+
+		var jwks JWKS
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&jwks); err != nil {
+			return nil, err
+		}
+
+		return &jwks, nil
+	}
+}
@@ -0,0 +1,239 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/core/http/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWK(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	edPub, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	for _, pub := range []any{&rsaKey.PublicKey, &ecKey.PublicKey, edPub} {
+		key, err := auth.NewPublicKey("kid", pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		jwk, err := key.JWK()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := jwk.Key()
+		is := assert.New(t)
+		is.Nil(err)
+		is.Equal(key, got)
+	}
+
+	is := assert.New(t)
+	_, err := auth.NewHMACKey("kid", []byte("secret")).JWK()
+	is.ErrorIs(err, auth.ErrKeyUnsupported)
+
+	_, err = (&auth.JWK{Kty: "OKP", Crv: "Ed25519", X: "AA", Alg: "EdDSA"}).Key()
+	is.ErrorIs(err, auth.ErrKeyUnsupported)
+}
+
+func TestJWKSVerifier(t *testing.T) {
+	_, oldKey, _ := ed25519.GenerateKey(rand.Reader)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	oldSigner, _ := auth.NewKey("old", oldKey)
+	newSigner, _ := auth.NewKey("new", ecKey)
+	kr := auth.NewKeyring(oldSigner)
+
+	var loads int
+	jwks := auth.JWKSHandler(kr)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loads++
+		jwks.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+
+	now := time.Now()
+	v := auth.NewJWKSVerifier(auth.JWKSFromURL(ts.Client(), ts.URL))
+	v.Now = func() time.Time {
+		return now
+	}
+
+	sign := func(t *testing.T) string {
+		t.Helper()
+
+		token, err := kr.Sign(auth.Claims{Subject: "john"}, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return token
+	}
+
+	is := assert.New(t)
+	oldToken := sign(t)
+	claims, err := v.Verify(oldToken)
+	is.Nil(err)
+	is.Equal("john", claims.Subject)
+
+	_, err = v.Verify(oldToken)
+	is.Nil(err)
+	is.Equal(1, loads, "keys are cached")
+
+	kr.Rotate(newSigner)
+	newToken := sign(t)
+	_, err = v.Verify(newToken)
+	is.ErrorIs(err, auth.ErrKeyNotFound, "refreshes at most once every interval")
+	is.Equal(1, loads)
+
+	now = now.Add(v.RefreshInterval)
+	_, err = v.Verify(newToken)
+	is.Nil(err)
+	_, err = v.Verify(oldToken)
+	is.Nil(err)
+	is.Equal(2, loads)
+
+	t.Run("handler", func(t *testing.T) {
+		h := auth.BearerVerifierHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, _ := auth.ClaimsContext.Value(r.Context())
+			w.Write([]byte(claims.Subject))
+		}), v)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Authorization", "Bearer "+newToken)
+		h.ServeHTTP(w, r)
+
+		is := assert.New(t)
+		is.Equal(http.StatusOK, w.Code)
+		is.Equal("john", w.Body.String())
+	})
+
+	t.Run("file", func(t *testing.T) {
+		b, err := json.Marshal(kr.JWKS())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		path := filepath.Join(t.TempDir(), "jwks.json")
+		if err := os.WriteFile(path, b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		v := auth.NewJWKSVerifier(auth.JWKSFromFile(path))
+		claims, err := v.Verify(newToken)
+		is := assert.New(t)
+		is.Nil(err)
+		is.Equal("john", claims.Subject)
+	})
+}
+
+func TestJWKSVerifier_Load(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := auth.NewKey("kid", key)
+	kr := auth.NewKeyring(signer)
+
+	token, err := kr.Sign(auth.Claims{Subject: "john"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errLoad := errors.New("load failed")
+	newVerifier := func(fail *atomic.Bool, loads *atomic.Int64) (*auth.JWKSVerifier, *time.Time) {
+		now := time.Now()
+		v := auth.NewJWKSVerifier(func(ctx context.Context) (*auth.JWKS, error) {
+			loads.Add(1)
+			time.Sleep(10 * time.Millisecond)
+			if fail.Load() {
+				return nil, errLoad
+			}
+
+			return kr.JWKS(), nil
+		})
+		v.Now = func() time.Time {
+			return now
+		}
+
+		return v, &now
+	}
+
+	t.Run("stale", func(t *testing.T) {
+		var fail atomic.Bool
+		var loads atomic.Int64
+		v, now := newVerifier(&fail, &loads)
+
+		is := assert.New(t)
+		_, err := v.Verify(token)
+		is.Nil(err)
+
+		fail.Store(true)
+		*now = now.Add(v.TTL)
+		_, err = v.Verify(token)
+		is.Nil(err, "the stale keys are used when the load fails")
+		_, err = v.Verify(token)
+		is.Nil(err)
+		is.Equal(int64(2), loads.Load(), "the load is retried after the refresh interval")
+
+		fail.Store(false)
+		*now = now.Add(v.RefreshInterval)
+		_, err = v.Verify(token)
+		is.Nil(err)
+		is.Equal(int64(3), loads.Load())
+	})
+
+	t.Run("no keys", func(t *testing.T) {
+		var fail atomic.Bool
+		var loads atomic.Int64
+		fail.Store(true)
+		v, now := newVerifier(&fail, &loads)
+
+		is := assert.New(t)
+		_, err := v.Verify(token)
+		is.ErrorIs(err, errLoad)
+		_, err = v.Verify(token)
+		is.ErrorIs(err, auth.ErrKeyNotFound)
+		is.Equal(int64(1), loads.Load(), "the load is retried after the refresh interval")
+
+		fail.Store(false)
+		*now = now.Add(v.RefreshInterval)
+		_, err = v.Verify(token)
+		is.Nil(err)
+		is.Equal(int64(2), loads.Load())
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		var fail atomic.Bool
+		var loads atomic.Int64
+		v, _ := newVerifier(&fail, &loads)
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				_, err := v.Verify(token)
+				assert.Nil(t, err)
+			}()
+		}
+		wg.Wait()
+
+		is := assert.New(t)
+		is.Equal(int64(1), loads.Load(), "the concurrent loads are shared")
+	})
+}
@@ -1,11 +1,15 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 var (
@@ -15,35 +19,188 @@ var (
 
 type Claims = jwt.RegisteredClaims
 
-type JWT struct {
-	Secret []byte
+// AccessClaims is the claims with the scopes, roles and tenant.
+// Custom claims can embed it, or implement the GetScopes and GetRoles methods
+// for RequireScopes and RequireRole.
+type AccessClaims struct {
+	Claims
+	Scope    string   `json:"scope,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+	TenantID string   `json:"tid,omitempty"`
 }
 
-func NewJWT(secret []byte) *JWT {
-	return &JWT{
+// GetScopes returns the space-delimited scopes.
+func (c AccessClaims) GetScopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+func (c AccessClaims) GetRoles() []string {
+	return c.Roles
+}
+
+type keys interface {
+	signingKey() (*Key, error)
+	verifyingKey(ctx context.Context, kid string) (*Key, error)
+}
+
+// JWT signs and verifies the tokens with the claims T. T is either Claims, or
+// a struct embedding Claims, e.g.
+//
+//	type UserClaims struct {
+//		auth.Claims
+//		Email string `json:"email"`
+//	}
+//
+// The tokens are signed with the HS256 Secret, unless the Keys, e.g. Keyring
+// or JWKSVerifier is set.
+// When the Denylist is set, the revoked tokens are rejected.
+type JWT[T jwt.Claims] struct {
+	Secret   []byte
+	Keys     keys
+	Denylist Denylist
+	Now      func() time.Time
+}
+
+func NewJWT[T jwt.Claims](secret []byte) *JWT[T] {
+	return &JWT[T]{
 		Secret: secret,
+		Now:    time.Now,
+	}
+}
+
+func NewJWTWithKeys[T jwt.Claims](keys keys) *JWT[T] {
+	return &JWT[T]{
+		Keys: keys,
+		Now:  time.Now,
+	}
+}
+
+func (j *JWT[T]) Sign(claims T, ttl time.Duration) (string, error) {
+	key, err := j.keys().signingKey()
+	if err != nil {
+		return "", err
+	}
+
+	return signToken(claims, ttl, key, j.Now())
+}
+
+func (j *JWT[T]) Verify(bearerToken string) (*T, error) {
+	return j.VerifyContext(context.Background(), bearerToken)
+}
+
+func (j *JWT[T]) VerifyContext(ctx context.Context, bearerToken string) (*T, error) {
+	claims, err := verifyToken[T](ctx, bearerToken, j.keys().verifyingKey, j.Now)
+	if err != nil {
+		return nil, err
+	}
+	if j.Denylist == nil {
+		return claims, nil
+	}
+
+	rc, err := registered(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	denied, err := j.Denylist.Denied(ctx, rc)
+	if err != nil {
+		return nil, err
+	}
+	if denied {
+		return nil, fmt.Errorf("%w: %w", ErrTokenInvalid, ErrTokenRevoked)
+	}
+
+	return claims, nil
+}
+
+// Revoke revokes the token by the jti until it expires.
+func (j *JWT[T]) Revoke(ctx context.Context, claims *T) error {
+	if j.Denylist == nil {
+		return fmt.Errorf("%w: denylist is not set", ErrClaimsInvalid)
+	}
+
+	rc, err := registered(claims)
+	if err != nil {
+		return err
+	}
+	if rc.ID == "" || rc.ExpiresAt == nil {
+		return fmt.Errorf("%w: jti and exp are required", ErrClaimsInvalid)
+	}
+
+	ttl := rc.ExpiresAt.Sub(j.Now())
+	if ttl <= 0 {
+		return nil
+	}
+
+	return j.Denylist.Deny(ctx, rc.ID, ttl)
+}
+
+func (j *JWT[T]) keys() keys {
+	if j.Keys != nil {
+		return j.Keys
 	}
+
+	return NewKeyring(NewHMACKey("", j.Secret))
 }
 
-func (j *JWT) Sign(claims Claims, ttl time.Duration) (string, error) {
-	if claims.Subject == "" {
+// signToken signs the claims with the key. The key id is set in the kid
+// header, if any.
+// The jti and iat are set if empty, for revocation.
+func signToken[T jwt.Claims](claims T, ttl time.Duration, key *Key, now time.Time) (string, error) {
+	sub, err := claims.GetSubject()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrClaimsInvalid, err)
+	}
+	if sub == "" {
 		return "", fmt.Errorf("%w: subject is required", ErrClaimsInvalid)
 	}
+	if key.Private == nil {
+		return "", fmt.Errorf("%w: %q cannot sign", ErrKeyNotFound, key.ID)
+	}
+
+	rc, err := registered(&claims)
+	if err != nil {
+		return "", err
+	}
 
-	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(ttl))
+	rc.ExpiresAt = jwt.NewNumericDate(now.Add(ttl))
+	if rc.IssuedAt == nil {
+		rc.IssuedAt = jwt.NewNumericDate(now)
+	}
+	if rc.ID == "" {
+		rc.ID = uuid.NewString()
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.Secret)
+	return token.SignedString(key.Private)
 }
 
-func (j *JWT) Verify(bearerToken string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(bearerToken, &Claims{}, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+// verifyToken verifies the token with the key by the kid header.
+// The token must be signed with the same algorithm as the key, to prevent
+// algorithm confusion, e.g. RS256 public keys used as HS256 secrets.
+func verifyToken[T jwt.Claims](ctx context.Context, bearerToken string, lookup func(ctx context.Context, kid string) (*Key, error), now func() time.Time) (*T, error) {
+	claims, ok := any(new(T)).(jwt.Claims)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T is not a struct", ErrClaimsInvalid, *new(T))
+	}
+
+	token, err := jwt.ParseWithClaims(bearerToken, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		key, err := lookup(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != key.Method.Alg() {
 			return nil, fmt.Errorf("%w: unexpected signing method %s", ErrTokenInvalid, token.Header["alg"])
 		}
 
-		return j.Secret, nil
-	})
+		return key.Public, nil
+	}, jwt.WithTimeFunc(now))
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrTokenInvalid, err)
 	}
@@ -51,10 +208,39 @@ func (j *JWT) Verify(bearerToken string) (*Claims, error) {
 		return nil, ErrClaimsInvalid
 	}
 
-	claims, ok := token.Claims.(*Claims)
+	res, ok := any(token.Claims).(*T)
 	if !ok {
 		return nil, fmt.Errorf("%w: failed to cast claims", ErrTokenInvalid)
 	}
 
-	return claims, nil
+	return res, nil
+}
+
+// registered returns the Claims of the claims, which may be embedded.
+func registered(claims any) (*Claims, error) {
+	if c, ok := claims.(*Claims); ok {
+		return c, nil
+	}
+
+	v := reflect.ValueOf(claims).Elem()
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %s is not a struct", ErrClaimsInvalid, v.Type())
+	}
+
+	// The embedded Claims may be named RegisteredClaims or Claims, so it is
+	// found through the promoted field.
+	f, ok := v.Type().FieldByName("ExpiresAt")
+	if !ok || len(f.Index) < 2 {
+		return nil, fmt.Errorf("%w: %s does not embed Claims", ErrClaimsInvalid, v.Type())
+	}
+
+	fv, err := v.FieldByIndexErr(f.Index[:len(f.Index)-1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrClaimsInvalid, err)
+	}
+	if fv.Type() != reflect.TypeFor[Claims]() {
+		return nil, fmt.Errorf("%w: %s does not embed Claims", ErrClaimsInvalid, v.Type())
+	}
+
+	return fv.Addr().Interface().(*Claims), nil
 }
@@ -36,7 +36,7 @@ func TestBearerAuth(t *testing.T) {
 
 func TestSignAndVerifyJWT(t *testing.T) {
 	t.Run("valid", func(t *testing.T) {
-		jwt := auth.NewJWT([]byte("secret"))
+		jwt := auth.NewJWT[auth.Claims]([]byte("secret"))
 		token, err := jwt.Sign(auth.Claims{
 			Subject: "john.appleseed@mail.com",
 		}, 1*time.Hour)
@@ -51,7 +51,7 @@ func TestSignAndVerifyJWT(t *testing.T) {
 	})
 
 	t.Run("expired", func(t *testing.T) {
-		jwt := auth.NewJWT([]byte("secret"))
+		jwt := auth.NewJWT[auth.Claims]([]byte("secret"))
 		token, err := jwt.Sign(auth.Claims{
 			Subject: "john.appleseed@mail.com",
 		}, -1*time.Hour)
@@ -63,4 +63,23 @@ func TestSignAndVerifyJWT(t *testing.T) {
 		is := assert.New(t)
 		is.ErrorIs(err, auth.ErrTokenInvalid)
 	})
+
+	t.Run("now", func(t *testing.T) {
+		now := time.Now()
+		jwt := auth.NewJWT[auth.Claims]([]byte("secret"))
+		jwt.Now = func() time.Time {
+			return now
+		}
+		token, err := jwt.Sign(auth.Claims{
+			Subject: "john.appleseed@mail.com",
+		}, 1*time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		now = now.Add(2 * time.Hour)
+		_, err = jwt.Verify(token)
+		is := assert.New(t)
+		is.ErrorIs(err, auth.ErrTokenInvalid)
+	})
 }
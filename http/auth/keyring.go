@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrKeyNotFound    = errors.New("auth: key not found")
+	ErrKeyUnsupported = errors.New("auth: unsupported key")
+)
+
+// Key is the key used to sign and verify the tokens. Keys without the private
+// key can only verify.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private any
+	Public  any
+}
+
+// NewKey creates the signing key from the RSA, ECDSA or Ed25519 private key.
+// The algorithm is RS256, ES256 (ES384 and ES512 for the P-384 and P-521
+// curves), or EdDSA respectively.
+func NewKey(id string, private crypto.Signer) (*Key, error) {
+	key, err := NewPublicKey(id, private.Public())
+	if err != nil {
+		return nil, err
+	}
+	key.Private = private
+
+	return key, nil
+}
+
+// NewPublicKey creates the key that can only verify the tokens.
+func NewPublicKey(id string, public crypto.PublicKey) (*Key, error) {
+	method, err := signingMethod(public)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Key{
+		ID:     id,
+		Method: method,
+		Public: public,
+	}, nil
+}
+
+// NewHMACKey creates the HS256 key from the shared secret.
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{
+		ID:      id,
+		Method:  jwt.SigningMethodHS256,
+		Private: secret,
+		Public:  secret,
+	}
+}
+
+// Keyring signs the tokens with the current key, and verifies them with any
+// of the keys by the kid header.
+//
+// To rotate the keys, call Rotate with the new key. The previous keys are
+// still used for verification, until they are removed after the tokens
+// signed by them expire.
+type Keyring struct {
+	mu   sync.RWMutex
+	keys []*Key
+}
+
+// NewKeyring creates the keyring. The first key is the current key.
+func NewKeyring(keys ...*Key) *Keyring {
+	return &Keyring{
+		keys: slices.Clone(keys),
+	}
+}
+
+// Rotate sets the key as the current key for signing.
+func (k *Keyring) Rotate(key *Key) {
+	k.mu.Lock()
+	k.keys = slices.Insert(slices.DeleteFunc(k.keys, func(old *Key) bool {
+		return old.ID == key.ID
+	}), 0, key)
+	k.mu.Unlock()
+}
+
+// Remove removes the key. Tokens signed by the key are no longer valid.
+func (k *Keyring) Remove(id string) {
+	k.mu.Lock()
+	k.keys = slices.DeleteFunc(k.keys, func(key *Key) bool {
+		return key.ID == id
+	})
+	k.mu.Unlock()
+}
+
+// Key returns the key by id.
+func (k *Keyring) Key(id string) (*Key, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range k.keys {
+		if key.ID == id {
+			return key, true
+		}
+	}
+
+	return nil, false
+}
+
+// Keys returns the keys, starting with the current key.
+func (k *Keyring) Keys() []*Key {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return slices.Clone(k.keys)
+}
+
+func (k *Keyring) Sign(claims Claims, ttl time.Duration) (string, error) {
+	key, err := k.signingKey()
+	if err != nil {
+		return "", err
+	}
+
+	return signToken(claims, ttl, key, time.Now())
+}
+
+func (k *Keyring) Verify(bearerToken string) (*Claims, error) {
+	return k.VerifyContext(context.Background(), bearerToken)
+}
+
+func (k *Keyring) VerifyContext(ctx context.Context, bearerToken string) (*Claims, error) {
+	return verifyToken[Claims](ctx, bearerToken, k.verifyingKey, time.Now)
+}
+
+func (k *Keyring) signingKey() (*Key, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if len(k.keys) == 0 {
+		return nil, ErrKeyNotFound
+	}
+
+	return k.keys[0], nil
+}
+
+func (k *Keyring) verifyingKey(ctx context.Context, kid string) (*Key, error) {
+	key, ok := k.Key(kid)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrKeyNotFound, kid)
+	}
+
+	return key, nil
+}
+
+// JWKS returns the public keys. HMAC keys are excluded.
+func (k *Keyring) JWKS() *JWKS {
+	jwks := &JWKS{Keys: []*JWK{}}
+	for _, key := range k.Keys() {
+		jwk, err := key.JWK()
+		if err != nil {
+			continue
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
+
+func signingMethod(public crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		}
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+
+	return nil, fmt.Errorf("%w: %T", ErrKeyUnsupported, public)
+}
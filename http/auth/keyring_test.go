@@ -0,0 +1,115 @@
+package auth_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/core/http/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyring(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		signer crypto.Signer
+		alg    string
+	}{
+		"rsa":     {rsaKey, "RS256"},
+		"ecdsa":   {ecKey, "ES256"},
+		"ed25519": {edKey, "EdDSA"},
+	}
+
+	for name, ts := range tests {
+		t.Run(name, func(t *testing.T) {
+			key, err := auth.NewKey(name, ts.signer)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			kr := auth.NewKeyring(key)
+			token, err := kr.Sign(auth.Claims{Subject: "john"}, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			is := assert.New(t)
+			is.Equal(ts.alg, key.Method.Alg())
+
+			claims, err := kr.Verify(token)
+			is.Nil(err)
+			is.Equal("john", claims.Subject)
+
+			// Verifies with the public key only.
+			pub, err := auth.NewPublicKey(name, ts.signer.Public())
+			is.Nil(err)
+			claims, err = auth.NewKeyring(pub).Verify(token)
+			is.Nil(err)
+			is.Equal("john", claims.Subject)
+
+			_, err = auth.NewKeyring(pub).Sign(auth.Claims{Subject: "john"}, time.Hour)
+			is.ErrorIs(err, auth.ErrKeyNotFound)
+		})
+	}
+
+	t.Run("rotate", func(t *testing.T) {
+		oldKey, _ := auth.NewKey("old", ecKey)
+		newKey, _ := auth.NewKey("new", edKey)
+
+		kr := auth.NewKeyring(oldKey)
+		oldToken, err := kr.Sign(auth.Claims{Subject: "john"}, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		kr.Rotate(newKey)
+		newToken, err := kr.Sign(auth.Claims{Subject: "john"}, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		is := assert.New(t)
+		is.Equal([]*auth.Key{newKey, oldKey}, kr.Keys())
+
+		_, err = kr.Verify(oldToken)
+		is.Nil(err, "old keys are accepted during rotation")
+		_, err = kr.Verify(newToken)
+		is.Nil(err)
+
+		kr.Remove("old")
+		_, err = kr.Verify(oldToken)
+		is.ErrorIs(err, auth.ErrTokenInvalid)
+		is.ErrorIs(err, auth.ErrKeyNotFound)
+	})
+
+	t.Run("algorithm confusion", func(t *testing.T) {
+		key, _ := auth.NewKey("key", edKey)
+
+		// Signs with the public key as the HMAC secret.
+		forged, err := auth.NewKeyring(auth.NewHMACKey("key", key.Public.(ed25519.PublicKey))).Sign(auth.Claims{Subject: "john"}, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = auth.NewKeyring(key).Verify(forged)
+		is := assert.New(t)
+		is.ErrorIs(err, auth.ErrTokenInvalid)
+	})
+}
@@ -0,0 +1,278 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("auth: invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("auth: refresh token reused")
+)
+
+// RefreshToken is the stored refresh token. Every login starts a new family,
+// and the refresh tokens rotated from it belongs to the same family.
+type RefreshToken struct {
+	Hash      string
+	Family    string
+	Subject   string
+	ExpiresAt time.Time
+}
+
+// RefreshStore stores the refresh tokens by the hash.
+type RefreshStore interface {
+	// Save stores the refresh token until it expires. Returns
+	// ErrRefreshTokenInvalid if the family is revoked.
+	Save(ctx context.Context, token *RefreshToken) error
+
+	// Consume marks the refresh token as used. Used tokens are kept until they
+	// expire, and consuming them again returns the token with
+	// ErrRefreshTokenReused.
+	// Returns ErrRefreshTokenInvalid if the token does not exist, expired, or
+	// the family is revoked.
+	Consume(ctx context.Context, hash string) (*RefreshToken, error)
+
+	// RevokeFamily revokes all the refresh tokens in the family. The revoked
+	// family is kept until the tokens expire, so that it can not be saved again.
+	RevokeFamily(ctx context.Context, family string) error
+
+	// RevokeSubject revokes all the refresh tokens of the subject.
+	RevokeSubject(ctx context.Context, subject string) error
+}
+
+// Refresher issues and rotates the opaque refresh tokens.
+//
+// The refresh token can only be used once. Reusing a rotated refresh token,
+// which is an indication that it was stolen, revokes the whole family.
+type Refresher struct {
+	Store RefreshStore
+	TTL   time.Duration
+	Now   func() time.Time
+
+	// Denylist and AccessTTL are used by RevokeSubject to revoke the access
+	// tokens too. AccessTTL must not be less than the ttl of the access tokens.
+	Denylist  Denylist
+	AccessTTL time.Duration
+}
+
+func NewRefresher(store RefreshStore) *Refresher {
+	return &Refresher{
+		Store:     store,
+		TTL:       30 * 24 * time.Hour,
+		Now:       time.Now,
+		AccessTTL: time.Hour,
+	}
+}
+
+// Issue issues the refresh token of the new family for the subject, e.g. on
+// login.
+func (r *Refresher) Issue(ctx context.Context, subject string) (string, error) {
+	if subject == "" {
+		return "", fmt.Errorf("%w: subject is required", ErrClaimsInvalid)
+	}
+
+	return r.issue(ctx, uuid.NewString(), subject)
+}
+
+// Rotate exchanges the refresh token for the new refresh token in the same
+// family. The returned RefreshToken is used to issue the access token for
+// the subject.
+func (r *Refresher) Rotate(ctx context.Context, token string) (string, *RefreshToken, error) {
+	rt, err := r.Store.Consume(ctx, HashRefreshToken(token))
+	if errors.Is(err, ErrRefreshTokenReused) {
+		if err := r.Store.RevokeFamily(ctx, rt.Family); err != nil {
+			return "", nil, err
+		}
+
+		return "", nil, err
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	next, err := r.issue(ctx, rt.Family, rt.Subject)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return next, rt, nil
+}
+
+// Revoke revokes the family of the refresh token, e.g. on logout.
+func (r *Refresher) Revoke(ctx context.Context, token string) error {
+	rt, err := r.Store.Consume(ctx, HashRefreshToken(token))
+	if err != nil && !errors.Is(err, ErrRefreshTokenReused) {
+		return err
+	}
+
+	return r.Store.RevokeFamily(ctx, rt.Family)
+}
+
+// RevokeSubject logouts the subject everywhere. All the refresh tokens are
+// revoked, as well as the access tokens if the Denylist is set.
+func (r *Refresher) RevokeSubject(ctx context.Context, subject string) error {
+	if err := r.Store.RevokeSubject(ctx, subject); err != nil {
+		return err
+	}
+
+	if r.Denylist == nil {
+		return nil
+	}
+
+	return r.Denylist.DenySubject(ctx, subject, r.AccessTTL)
+}
+
+func (r *Refresher) issue(ctx context.Context, family, subject string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+	if err := r.Store.Save(ctx, &RefreshToken{
+		Hash:      HashRefreshToken(token),
+		Family:    family,
+		Subject:   subject,
+		ExpiresAt: r.Now().Add(r.TTL),
+	}); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// HashRefreshToken returns the hash of the refresh token, so that the stored
+// tokens can not be used if leaked.
+func HashRefreshToken(token string) string {
+	return hashSecret(token)
+}
+
+type refreshToken struct {
+	*RefreshToken
+	used bool
+}
+
+type refreshFamily struct {
+	subject string
+	revoked bool
+}
+
+// MemoryRefreshStore is the in-memory RefreshStore, for single instance or
+// tests.
+type MemoryRefreshStore struct {
+	Now func() time.Time
+
+	mu        sync.Mutex
+	tokens    map[string]*refreshToken
+	families  map[string]*refreshFamily
+	nextSweep time.Time
+}
+
+var _ RefreshStore = (*MemoryRefreshStore)(nil)
+
+func NewMemoryRefreshStore() *MemoryRefreshStore {
+	return &MemoryRefreshStore{
+		Now:      time.Now,
+		tokens:   make(map[string]*refreshToken),
+		families: make(map[string]*refreshFamily),
+	}
+}
+
+func (s *MemoryRefreshStore) Save(ctx context.Context, token *RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep()
+
+	f, ok := s.families[token.Family]
+	if ok && f.revoked {
+		return ErrRefreshTokenInvalid
+	}
+	if !ok {
+		s.families[token.Family] = &refreshFamily{subject: token.Subject}
+	}
+	s.tokens[token.Hash] = &refreshToken{RefreshToken: token}
+
+	return nil
+}
+
+func (s *MemoryRefreshStore) Consume(ctx context.Context, hash string) (*RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tokens[hash]
+	if !ok || !s.Now().Before(t.ExpiresAt) {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if f, ok := s.families[t.Family]; !ok || f.revoked {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if t.used {
+		return t.RefreshToken, ErrRefreshTokenReused
+	}
+	t.used = true
+
+	return t.RefreshToken, nil
+}
+
+func (s *MemoryRefreshStore) RevokeFamily(ctx context.Context, family string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f, ok := s.families[family]; ok {
+		f.revoked = true
+	}
+
+	return nil
+}
+
+func (s *MemoryRefreshStore) RevokeSubject(ctx context.Context, subject string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, f := range s.families {
+		if f.subject == subject {
+			f.revoked = true
+		}
+	}
+
+	return nil
+}
+
+// sweep evicts the expired tokens at most once per sweepInterval, since the
+// expired tokens are already rejected by Consume.
+func (s *MemoryRefreshStore) sweep() {
+	now := s.Now()
+	if now.Before(s.nextSweep) {
+		return
+	}
+
+	s.evict(now)
+	s.nextSweep = now.Add(sweepInterval)
+}
+
+// evict removes the expired tokens, and the families without tokens.
+func (s *MemoryRefreshStore) evict(now time.Time) {
+	alive := make(map[string]bool)
+	for hash, t := range s.tokens {
+		if !now.Before(t.ExpiresAt) {
+			delete(s.tokens, hash)
+			continue
+		}
+
+		alive[t.Family] = true
+	}
+
+	for family := range s.families {
+		if !alive[family] {
+			delete(s.families, family)
+		}
+	}
+}
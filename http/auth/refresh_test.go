@@ -0,0 +1,121 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/core/http/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefresher(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("rotate", func(t *testing.T) {
+		r := auth.NewRefresher(auth.NewMemoryRefreshStore())
+		token, err := r.Issue(ctx, "john")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		next, rt, err := r.Rotate(ctx, token)
+		is := assert.New(t)
+		is.Nil(err)
+		is.NotEqual(token, next)
+		is.Equal("john", rt.Subject)
+
+		_, rt2, err := r.Rotate(ctx, next)
+		is.Nil(err)
+		is.Equal(rt.Family, rt2.Family)
+	})
+
+	t.Run("reuse", func(t *testing.T) {
+		r := auth.NewRefresher(auth.NewMemoryRefreshStore())
+		token, err := r.Issue(ctx, "john")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		next, _, err := r.Rotate(ctx, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, _, err = r.Rotate(ctx, token)
+		is := assert.New(t)
+		is.ErrorIs(err, auth.ErrRefreshTokenReused)
+
+		_, _, err = r.Rotate(ctx, next)
+		is.ErrorIs(err, auth.ErrRefreshTokenInvalid, "family is revoked on reuse")
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		now := time.Now()
+		store := auth.NewMemoryRefreshStore()
+		store.Now = func() time.Time {
+			return now
+		}
+
+		r := auth.NewRefresher(store)
+		r.Now = store.Now
+		r.TTL = time.Minute
+		token, err := r.Issue(ctx, "john")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		now = now.Add(time.Minute)
+		_, _, err = r.Rotate(ctx, token)
+		is := assert.New(t)
+		is.ErrorIs(err, auth.ErrRefreshTokenInvalid)
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		r := auth.NewRefresher(auth.NewMemoryRefreshStore())
+		token, err := r.Issue(ctx, "john")
+		if err != nil {
+			t.Fatal(err)
+		}
+		other, err := r.Issue(ctx, "john")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		is := assert.New(t)
+		is.Nil(r.Revoke(ctx, token))
+		_, _, err = r.Rotate(ctx, token)
+		is.ErrorIs(err, auth.ErrRefreshTokenInvalid)
+
+		_, _, err = r.Rotate(ctx, other)
+		is.Nil(err, "other sessions are not revoked")
+	})
+
+	t.Run("revoke subject", func(t *testing.T) {
+		denylist := auth.NewMemoryDenylist()
+		jwt := auth.NewJWT[auth.Claims]([]byte("secret"))
+		jwt.Denylist = denylist
+
+		r := auth.NewRefresher(auth.NewMemoryRefreshStore())
+		r.Denylist = denylist
+
+		johnToken, _ := r.Issue(ctx, "john")
+		janeToken, _ := r.Issue(ctx, "jane")
+		accessToken, err := jwt.Sign(auth.Claims{Subject: "john"}, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		is := assert.New(t)
+		is.Nil(r.RevokeSubject(ctx, "john"))
+
+		_, _, err = r.Rotate(ctx, johnToken)
+		is.ErrorIs(err, auth.ErrRefreshTokenInvalid)
+
+		_, err = jwt.Verify(accessToken)
+		is.ErrorIs(err, auth.ErrTokenRevoked)
+
+		_, _, err = r.Rotate(ctx, janeToken)
+		is.Nil(err)
+	})
+}
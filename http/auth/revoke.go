@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrTokenRevoked = errors.New("auth: token revoked")
+
+// Denylist revokes the access tokens before they expire.
+type Denylist interface {
+	// Deny revokes the token by the jti for the ttl, which should be the
+	// remaining lifetime of the token.
+	Deny(ctx context.Context, jti string, ttl time.Duration) error
+
+	// DenySubject revokes all the tokens of the subject issued until now, e.g.
+	// to logout everywhere. The ttl should be the lifetime of the tokens.
+	DenySubject(ctx context.Context, subject string, ttl time.Duration) error
+
+	// Denied reports whether the token is revoked.
+	Denied(ctx context.Context, claims *Claims) (bool, error)
+}
+
+// sweepInterval is how often the in-memory stores evict the expired entries.
+const sweepInterval = time.Minute
+
+type denial struct {
+	at        time.Time
+	expiresAt time.Time
+}
+
+// MemoryDenylist is the in-memory Denylist, for single instance or tests.
+type MemoryDenylist struct {
+	Now func() time.Time
+
+	mu        sync.Mutex
+	jtis      map[string]denial
+	subjects  map[string]denial
+	nextSweep time.Time
+}
+
+var _ Denylist = (*MemoryDenylist)(nil)
+
+func NewMemoryDenylist() *MemoryDenylist {
+	return &MemoryDenylist{
+		Now:      time.Now,
+		jtis:     make(map[string]denial),
+		subjects: make(map[string]denial),
+	}
+}
+
+func (d *MemoryDenylist) Deny(ctx context.Context, jti string, ttl time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.Now()
+	d.sweep(now)
+	d.jtis[jti] = denial{at: now, expiresAt: now.Add(ttl)}
+
+	return nil
+}
+
+func (d *MemoryDenylist) DenySubject(ctx context.Context, subject string, ttl time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.Now()
+	d.sweep(now)
+	d.subjects[subject] = denial{at: now, expiresAt: now.Add(ttl)}
+
+	return nil
+}
+
+func (d *MemoryDenylist) Denied(ctx context.Context, claims *Claims) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.Now()
+	if v, ok := d.jtis[claims.ID]; ok && now.Before(v.expiresAt) {
+		return true, nil
+	}
+
+	if v, ok := d.subjects[claims.Subject]; ok && now.Before(v.expiresAt) {
+		return issuedBefore(claims, v.at), nil
+	}
+
+	return false, nil
+}
+
+// sweep evicts the expired entries at most once per sweepInterval, since
+// the expired entries are already ignored by Denied.
+func (d *MemoryDenylist) sweep(now time.Time) {
+	if now.Before(d.nextSweep) {
+		return
+	}
+
+	d.evict(now)
+	d.nextSweep = now.Add(sweepInterval)
+}
+
+func (d *MemoryDenylist) evict(now time.Time) {
+	for k, v := range d.jtis {
+		if !now.Before(v.expiresAt) {
+			delete(d.jtis, k)
+		}
+	}
+
+	for k, v := range d.subjects {
+		if !now.Before(v.expiresAt) {
+			delete(d.subjects, k)
+		}
+	}
+}
+
+// issuedBefore reports whether the token is issued at or before the time.
+// The iat is in seconds, so the tokens issued within the same second are also
+// revoked. Tokens without iat are always revoked.
+func issuedBefore(claims *Claims, at time.Time) bool {
+	if claims.IssuedAt == nil {
+		return true
+	}
+
+	return claims.IssuedAt.Unix() <= at.Unix()
+}
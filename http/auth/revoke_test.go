@@ -0,0 +1,76 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/core/http/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDenylist(t *testing.T) {
+	ctx := context.Background()
+
+	now := time.Now()
+	denylist := auth.NewMemoryDenylist()
+	denylist.Now = func() time.Time {
+		return now
+	}
+
+	jwt := auth.NewJWT[auth.Claims]([]byte("secret"))
+	jwt.Denylist = denylist
+
+	token, err := jwt.Sign(auth.Claims{Subject: "john"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := jwt.Sign(auth.Claims{Subject: "john"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	is := assert.New(t)
+	claims, err := jwt.Verify(token)
+	is.Nil(err)
+	is.NotEmpty(claims.ID)
+	is.NotNil(claims.IssuedAt)
+
+	is.Nil(jwt.Revoke(ctx, claims))
+	_, err = jwt.Verify(token)
+	is.ErrorIs(err, auth.ErrTokenInvalid)
+	is.ErrorIs(err, auth.ErrTokenRevoked)
+
+	_, err = jwt.Verify(other)
+	is.Nil(err, "only the jti is revoked")
+
+	t.Run("without denylist", func(t *testing.T) {
+		jwt := auth.NewJWT[auth.Claims]([]byte("secret"))
+
+		is := assert.New(t)
+		is.ErrorIs(jwt.Revoke(ctx, claims), auth.ErrClaimsInvalid)
+	})
+
+	t.Run("handler", func(t *testing.T) {
+		h := auth.BearerVerifierHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), jwt)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		h.ServeHTTP(w, r)
+
+		is := assert.New(t)
+		is.Equal(http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		now = now.Add(time.Hour)
+
+		revoked, err := denylist.Denied(ctx, claims)
+		is := assert.New(t)
+		is.Nil(err)
+		is.False(revoked, "the denial expires with the token")
+	})
+}
@@ -1,27 +1,73 @@
 module github.com/alextanhongpin/core/http
 
-go 1.23.0
+go 1.23.1
 
 require (
+	github.com/alextanhongpin/core/dsync/cache v0.0.0-00010101000000-000000000000
+	github.com/alextanhongpin/core/storage/redis v0.0.0-20241028033631-6d88609c62b1
 	github.com/alextanhongpin/errors v0.0.0-20240821095552-2b58a77f37b0
 	github.com/alextanhongpin/testdump/httpdump v0.0.0-20240815130218-4368114cac78
 	github.com/golang-jwt/jwt/v5 v5.2.1
 	github.com/google/go-cmp v0.6.0
-	github.com/google/uuid v1.3.0
-	github.com/stretchr/testify v1.9.0
-	golang.org/x/sys v0.21.0
+	github.com/google/uuid v1.6.0
+	github.com/redis/go-redis/v9 v9.7.0
+	github.com/stretchr/testify v1.10.0
+	golang.org/x/sync v0.9.0
+	golang.org/x/sys v0.27.0
 )
 
 require (
+	dario.cat/mergo v1.0.1 // indirect
+	github.com/Azure/go-ansiterm v0.0.0-20230124172434-306776ec8161 // indirect
+	github.com/Microsoft/go-winio v0.6.2 // indirect
+	github.com/Nvveen/Gotty v0.0.0-20120604004816-cd527374f1e5 // indirect
+	github.com/alextanhongpin/core/sync/rate v0.0.0-20241129045434-84469bdbd179 // indirect
+	github.com/cenkalti/backoff/v4 v4.3.0 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/containerd/continuity v0.4.3 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/docker/cli v27.1.2+incompatible // indirect
+	github.com/docker/docker v27.1.2+incompatible // indirect
+	github.com/docker/go-connections v0.5.0 // indirect
+	github.com/docker/go-units v0.5.0 // indirect
+	github.com/go-viper/mapstructure/v2 v2.1.0 // indirect
+	github.com/gogo/protobuf v1.3.2 // indirect
+	github.com/google/shlex v0.0.0-20191202100458-e7afc7fbc510 // indirect
+	github.com/moby/docker-image-spec v1.3.1 // indirect
+	github.com/moby/term v0.5.0 // indirect
+	github.com/opencontainers/go-digest v1.0.0 // indirect
+	github.com/opencontainers/image-spec v1.1.0 // indirect
+	github.com/opencontainers/runc v1.1.13 // indirect
+	github.com/ory/dockertest/v3 v3.11.0 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/sirupsen/logrus v1.9.3 // indirect
+	github.com/xeipuuv/gojsonpointer v0.0.0-20190905194746-02993c407bfb // indirect
+	github.com/xeipuuv/gojsonreference v0.0.0-20180127040603-bd5ef7bd5415 // indirect
+	github.com/xeipuuv/gojsonschema v1.2.0 // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
+)
+
+require (
+	github.com/alextanhongpin/core v0.0.0-00010101000000-000000000000
+	github.com/alextanhongpin/core/sync/circuitbreaker v0.0.0-00010101000000-000000000000
+	github.com/alextanhongpin/core/sync/retry v0.0.0-00010101000000-000000000000
 	github.com/alextanhongpin/testdump/pkg/diff v0.0.0-20240617032328-5cdd37fc0156 // indirect
 	github.com/alextanhongpin/testdump/pkg/file v0.0.0-20240814172502-38533f751ca6 // indirect
 	github.com/alextanhongpin/testdump/pkg/reviver v0.0.0-20240617032328-5cdd37fc0156 // indirect
 	github.com/alextanhongpin/testdump/pkg/snapshot v0.0.0-20240814172502-38533f751ca6 // indirect
-	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
 	github.com/golang/protobuf v1.5.3 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
 	golang.org/x/tools v0.22.0 // indirect
 	google.golang.org/grpc v1.56.2 // indirect
-	google.golang.org/protobuf v1.31.0 // indirect
+	google.golang.org/protobuf v1.35.2 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/alextanhongpin/core => ../
+
+replace github.com/alextanhongpin/core/sync/retry => ../sync/retry
+
+replace github.com/alextanhongpin/core/sync/circuitbreaker => ../sync/circuitbreaker
+
+replace github.com/alextanhongpin/core/dsync/cache => ../dsync/cache
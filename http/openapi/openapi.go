@@ -0,0 +1,284 @@
+// Package openapi generates the OpenAPI 3.1 document from the typed handlers
+// registered through route.Group.
+//
+// The constraints of the fields are read from the validate tag, which is the
+// same tag enforced by validator.Struct, e.g.
+//
+//	type CreateUserRequest struct {
+//		Email string `json:"email" validate:"email,max=100"`
+//		Age   int    `json:"age" validate:"optional,min=13"`
+//	}
+//
+//	func (r *CreateUserRequest) Validate() error {
+//		return validator.Struct(r)
+//	}
+//
+// Fields with the tag are required, unless they are optional. Since the
+// document and the validation share the same rules, they do not drift apart.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/alextanhongpin/core/http/response"
+	"github.com/alextanhongpin/core/http/route"
+)
+
+const Version = "3.1.0"
+
+// tagName is the struct tag of the field constraints, which is the same as
+// validator.TagName.
+const tagName = "validate"
+
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// PathItem maps the lowercase method to the operation.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// New creates the document from the routes. Only the routes with method
+// and route.Handler are included.
+func New(info Info, routes ...route.Route) *Document {
+	g := newGenerator()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+	}
+
+	for _, r := range routes {
+		h, ok := r.Handler.(*route.Handler)
+		if !ok {
+			continue
+		}
+
+		method, path, ok := strings.Cut(r.Pattern, " ")
+		if !ok {
+			continue
+		}
+		path = strings.TrimSpace(path)
+
+		// Replace the wildcards, e.g. {path...}.
+		path = strings.ReplaceAll(path, "...}", "}")
+		path = strings.ReplaceAll(path, "{$}", "")
+
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+		(*item)[strings.ToLower(method)] = g.operation(method, path, h)
+	}
+
+	doc.Components.Schemas = g.schemas
+
+	return doc
+}
+
+// Handler serves the document as JSON.
+func Handler(doc *Document) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := response.JSON(w, doc); err != nil {
+			response.Error(w, err)
+		}
+	})
+}
+
+func (g *generator) operation(method, path string, h *route.Handler) *Operation {
+	op := &Operation{
+		OperationID: operationID(method, path),
+		Summary:     h.Summary,
+		Responses: map[string]*Response{
+			"400": {
+				Description: http.StatusText(http.StatusBadRequest),
+				Content:     jsonContent(ref(errorSchema)),
+			},
+			"default": {
+				Description: "Error",
+				Content:     jsonContent(ref(errorSchema)),
+			},
+		},
+	}
+
+	req := indirect(h.Request)
+	if req.Kind() == reflect.Struct {
+		op.Parameters = g.parameters(req)
+
+		if hasBody(method) {
+			if body := g.body(req); body != nil {
+				op.RequestBody = &RequestBody{
+					Required: true,
+					Content:  jsonContent(body),
+				}
+			}
+		}
+	}
+
+	status := strconv.Itoa(h.Status)
+	if h.Status == http.StatusNoContent {
+		op.Responses[status] = &Response{
+			Description: http.StatusText(h.Status),
+		}
+
+		return op
+	}
+
+	op.Responses[status] = &Response{
+		Description: http.StatusText(h.Status),
+		Content: jsonContent(&Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"data":     g.schema(h.Response),
+				"pageInfo": ref(pageInfoSchema),
+			},
+		}),
+	}
+
+	return op
+}
+
+// parameters returns the path and query parameters of the request.
+func (g *generator) parameters(t reflect.Type) []*Parameter {
+	var params []*Parameter
+	for _, f := range fields(t) {
+		if name := f.Tag.Get("path"); name != "" {
+			params = append(params, &Parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   g.field(f),
+			})
+
+			continue
+		}
+
+		if name := f.Tag.Get("query"); name != "" {
+			params = append(params, &Parameter{
+				Name:     name,
+				In:       "query",
+				Required: required(f),
+				Schema:   g.field(f),
+			})
+		}
+	}
+
+	return params
+}
+
+// body returns the schema of the request body, excluding the path and query
+// parameters. Returns nil if there are no fields.
+func (g *generator) body(t reflect.Type) *Schema {
+	var hasBody, hasParams bool
+	for _, f := range fields(t) {
+		if _, ok := jsonName(f); !ok {
+			continue
+		}
+
+		if f.Tag.Get("path") != "" || f.Tag.Get("query") != "" {
+			hasParams = true
+		} else {
+			hasBody = true
+		}
+	}
+	if !hasBody {
+		return nil
+	}
+	if !hasParams {
+		return g.schema(t)
+	}
+
+	// The parameters are not tagged with `json:"-"`, so the schema is inlined
+	// without them.
+	s := g.properties(t)
+	for _, f := range fields(t) {
+		if f.Tag.Get("path") == "" && f.Tag.Get("query") == "" {
+			continue
+		}
+
+		name, _ := jsonName(f)
+		delete(s.Properties, name)
+		s.Required = slices.DeleteFunc(s.Required, func(r string) bool {
+			return r == name
+		})
+	}
+
+	return s
+}
+
+func hasBody(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete, http.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
+
+func jsonContent(s *Schema) map[string]*MediaType {
+	return map[string]*MediaType{
+		"application/json": {Schema: s},
+	}
+}
+
+// operationID returns the id from the method and path, e.g.
+// GET /users/{id} becomes getUsersId.
+func operationID(method, path string) string {
+	var sb strings.Builder
+	sb.WriteString(strings.ToLower(method))
+
+	words := strings.FieldsFunc(path, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+	})
+	for _, w := range words {
+		sb.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+
+	return sb.String()
+}
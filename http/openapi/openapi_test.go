@@ -0,0 +1,112 @@
+package openapi_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/core/http/openapi"
+	"github.com/alextanhongpin/core/http/route"
+	"github.com/alextanhongpin/testdump/httpdump"
+	"github.com/stretchr/testify/assert"
+)
+
+type User struct {
+	ID        int64     `json:"id"`
+	Email     string    `json:"email"`
+	Friends   []*User   `json:"friends,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type findUserRequest struct {
+	ID int64 `json:"-" path:"id"`
+}
+
+type listUsersRequest struct {
+	Limit int    `json:"-" query:"limit" validate:"optional,min=1,max=100"`
+	After string `json:"-" query:"after"`
+}
+
+type createUserRequest struct {
+	Email  string   `json:"email" validate:"email,max=100"`
+	Age    int      `json:"age" validate:"optional,min=13"`
+	Status string   `json:"status" validate:"optional,oneof=active inactive"`
+	Tags   []string `json:"tags" validate:"optional,max=5"`
+	secret string
+}
+
+func TestOpenAPI(t *testing.T) {
+	mux := http.NewServeMux()
+	api := route.NewGroup(mux, "/api")
+	users := api.Group("/users")
+
+	find := route.Handle(func(ctx context.Context, req findUserRequest) (*User, error) {
+		return nil, nil
+	})
+	find.Summary = "Find user by id"
+	users.Handle("GET /{id}", find)
+	users.Handle("GET /", route.Handle(func(ctx context.Context, req listUsersRequest) ([]User, error) {
+		return nil, nil
+	}))
+	users.Handle("POST /", route.Handle(func(ctx context.Context, req createUserRequest) (*User, error) {
+		return nil, nil
+	}, http.StatusCreated))
+	users.Handle("DELETE /{id}", route.Handle(func(ctx context.Context, req findUserRequest) (any, error) {
+		return nil, nil
+	}, http.StatusNoContent))
+	api.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {})
+
+	doc := openapi.New(openapi.Info{Title: "Users", Version: "1.0.0"}, api.Routes()...)
+
+	t.Run("paths", func(t *testing.T) {
+		is := assert.New(t)
+		is.Len(doc.Paths, 2, "untyped handlers are skipped")
+
+		get := (*doc.Paths["/api/users/{id}"])["get"]
+		is.Equal("getApiUsersId", get.OperationID)
+		is.Equal("Find user by id", get.Summary)
+		is.Equal("path", get.Parameters[0].In)
+		is.True(get.Parameters[0].Required)
+		is.Nil(get.RequestBody)
+
+		list := (*doc.Paths["/api/users/"])["get"]
+		is.Equal("limit", list.Parameters[0].Name)
+		is.False(list.Parameters[0].Required)
+		is.Equal(1.0, *list.Parameters[0].Schema.Minimum)
+		is.Equal(100.0, *list.Parameters[0].Schema.Maximum)
+
+		post := (*doc.Paths["/api/users/"])["post"]
+		is.NotNil(post.RequestBody)
+		is.Contains(post.Responses, "201")
+		is.Contains(post.Responses, "400")
+
+		del := (*doc.Paths["/api/users/{id}"])["delete"]
+		is.Nil(del.Responses["204"].Content)
+	})
+
+	t.Run("schemas", func(t *testing.T) {
+		is := assert.New(t)
+		schemas := doc.Components.Schemas
+		is.Contains(schemas, "Error")
+		is.Contains(schemas, "JSONError")
+		is.Contains(schemas, "PageInfo")
+		is.Equal("#/components/schemas/User", schemas["User"].Properties["friends"].Items.Ref)
+		is.Equal("date-time", schemas["User"].Properties["createdAt"].Format)
+
+		req := schemas["createUserRequest"]
+		is.Equal([]string{"email"}, req.Required)
+		is.Equal("email", req.Properties["email"].Format)
+		is.Equal(100, *req.Properties["email"].MaxLength)
+		is.Equal([]any{"active", "inactive"}, req.Properties["status"].Enum)
+		is.Equal(5, *req.Properties["tags"].MaxItems)
+		is.NotContains(req.Properties, "secret")
+	})
+
+	t.Run("handler", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/openapi.json", nil)
+		httpdump.Handler(t, openapi.Handler(doc)).ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,365 @@
+package openapi
+
+import (
+	"encoding"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alextanhongpin/core/http/response"
+)
+
+// The names of the components for the response envelopes.
+const (
+	errorSchema     = "Error"
+	jsonErrorSchema = "JSONError"
+	pageInfoSchema  = "PageInfo"
+)
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Const                any                `json:"const,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	invalidNameRe     = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+)
+
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newGenerator() *generator {
+	g := &generator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+
+	// Register the envelopes of the response.
+	g.names[reflect.TypeFor[response.JSONError]()] = jsonErrorSchema
+	g.names[reflect.TypeFor[response.PageInfo]()] = pageInfoSchema
+	g.schema(reflect.TypeFor[response.JSONError]())
+	g.schema(reflect.TypeFor[response.PageInfo]())
+	g.schemas[errorSchema] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"error": ref(jsonErrorSchema),
+		},
+		Required: []string{"error"},
+	}
+
+	return g
+}
+
+// Schema returns the JSON schema of the type. Named structs are added to the
+// components, and referenced by $ref.
+func (g *generator) schema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	t = indirect(t)
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Implements(textMarshalerType), reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: ptr(0.0)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		return g.object(t)
+	default:
+		// Interfaces accepts any values.
+		return &Schema{}
+	}
+}
+
+// object returns the reference to the component for named structs, and the
+// inline schema for anonymous structs.
+func (g *generator) object(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return g.properties(t)
+	}
+
+	name, ok := g.names[t]
+	if ok {
+		if _, ok := g.schemas[name]; ok {
+			return ref(name)
+		}
+	} else {
+		name = g.name(t)
+		g.names[t] = name
+	}
+
+	// Register before building the properties, for recursive types.
+	s := &Schema{}
+	g.schemas[name] = s
+	*s = *g.properties(t)
+
+	return ref(name)
+}
+
+func (g *generator) properties(t reflect.Type) *Schema {
+	s := &Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+	for _, f := range fields(t) {
+		name, ok := jsonName(f)
+		if !ok {
+			continue
+		}
+
+		s.Properties[name] = g.field(f)
+		if required(f) {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	return s
+}
+
+// field returns the schema of the field, with the constraints from the
+// validate tag.
+func (g *generator) field(f reflect.StructField) *Schema {
+	s := g.schema(f.Type)
+	if expr := f.Tag.Get(tagName); expr != "" && s.Ref == "" {
+		applyRules(s, expr)
+	}
+
+	return s
+}
+
+// name returns the unique component name of the type.
+func (g *generator) name(t reflect.Type) string {
+	name := invalidNameRe.ReplaceAllString(t.Name(), "_")
+	if _, ok := g.schemas[name]; !ok {
+		return name
+	}
+
+	pkg := t.PkgPath()
+	pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+	base := invalidNameRe.ReplaceAllString(pkg, "_") + "." + name
+	name = base
+	for i := 2; ; i++ {
+		if _, ok := g.schemas[name]; !ok {
+			return name
+		}
+		name = base + strconv.Itoa(i)
+	}
+}
+
+// applyRules maps the rules, e.g. `validate:"email,max=100"` to the schema
+// constraints. Unknown rules are ignored.
+func applyRules(s *Schema, expr string) {
+	for _, rule := range strings.Split(expr, ",") {
+		k, v, _ := strings.Cut(rule, "=")
+
+		switch s.Type {
+		case "string":
+			applyStringRule(s, k, v)
+		case "integer", "number":
+			applyNumberRule(s, k, v)
+		case "array":
+			applySliceRule(s, k, v)
+		}
+	}
+}
+
+func applyStringRule(s *Schema, k, v string) {
+	switch k {
+	case "min":
+		s.MinLength = ptr(toInt(v))
+	case "max":
+		s.MaxLength = ptr(toInt(v))
+	case "len":
+		s.MinLength = ptr(toInt(v))
+		s.MaxLength = ptr(toInt(v))
+	case "oneof":
+		for _, f := range strings.Fields(v) {
+			s.Enum = append(s.Enum, f)
+		}
+	case "is":
+		s.Const = v
+	case "alpha":
+		s.Pattern = "^[a-zA-Z]+$"
+	case "numeric":
+		s.Pattern = "^[0-9]+$"
+	case "alphanumeric":
+		s.Pattern = "^[a-zA-Z0-9]+$"
+	case "email":
+		s.Format = "email"
+	case "url":
+		s.Format = "uri"
+	case "starts_with":
+		s.Pattern = "^" + regexp.QuoteMeta(v)
+	case "ends_with":
+		s.Pattern = regexp.QuoteMeta(v) + "$"
+	}
+}
+
+func applyNumberRule(s *Schema, k, v string) {
+	switch k {
+	case "min":
+		s.Minimum = ptr(toFloat64(v))
+	case "max":
+		s.Maximum = ptr(toFloat64(v))
+	case "is":
+		s.Const = toFloat64(v)
+	case "oneof":
+		for _, f := range strings.Fields(v) {
+			s.Enum = append(s.Enum, toFloat64(f))
+		}
+	case "between":
+		lo, hi, _ := strings.Cut(v, " ")
+		s.Minimum = ptr(toFloat64(lo))
+		s.Maximum = ptr(toFloat64(hi))
+	case "positive":
+		s.Minimum = nil
+		s.ExclusiveMinimum = ptr(0.0)
+	case "negative":
+		s.ExclusiveMaximum = ptr(0.0)
+	case "latitude":
+		s.Minimum = ptr(-90.0)
+		s.Maximum = ptr(90.0)
+	case "longitude":
+		s.Minimum = ptr(-180.0)
+		s.Maximum = ptr(180.0)
+	}
+}
+
+func applySliceRule(s *Schema, k, v string) {
+	switch k {
+	case "min":
+		s.MinItems = ptr(toInt(v))
+	case "max":
+		s.MaxItems = ptr(toInt(v))
+	case "len":
+		s.MinItems = ptr(toInt(v))
+		s.MaxItems = ptr(toInt(v))
+	}
+}
+
+// fields returns the exported fields, including the fields of the embedded
+// structs.
+func fields(t reflect.Type) []reflect.StructField {
+	var res []reflect.StructField
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.Anonymous && f.Tag.Get("json") == "" && indirect(f.Type).Kind() == reflect.Struct {
+			res = append(res, fields(indirect(f.Type))...)
+
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		res = append(res, f)
+	}
+
+	return res
+}
+
+// jsonName returns the name of the field in JSON.
+func jsonName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = f.Name
+	}
+
+	return name, true
+}
+
+// required reports whether the field is required. Fields with the validate tag
+// are required, unless they are optional.
+func required(f reflect.StructField) bool {
+	expr, ok := f.Tag.Lookup(tagName)
+	if !ok {
+		return false
+	}
+
+	for _, rule := range strings.Split(expr, ",") {
+		if rule == "optional" {
+			return false
+		}
+	}
+
+	return true
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func ptr[T any](t T) *T {
+	return &t
+}
+
+func toInt(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func toFloat64(s string) float64 {
+	n, _ := strconv.ParseFloat(s, 64)
+	return n
+}
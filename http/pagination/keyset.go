@@ -0,0 +1,223 @@
+package pagination
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/alextanhongpin/core/http/response"
+	"github.com/alextanhongpin/core/types/safe"
+)
+
+var ErrInvalidCursor = errors.New("pagination: invalid cursor")
+
+// Request is the keyset pagination request. Either First and After for
+// forward paging, or Last and Before for backward paging.
+type Request[K any] struct {
+	First  int
+	After  *K
+	Last   int
+	Before *K
+}
+
+// Backward reports whether the request pages backward.
+func (r *Request[K]) Backward() bool {
+	return r.Last > 0
+}
+
+// Limit returns the database limit, with an additional row to check if there
+// are more items.
+// When paging backward, the items should be queried in the reverse order.
+func (r *Request[K]) Limit() int {
+	if r.Backward() {
+		return r.Last + 1
+	}
+
+	return r.First + 1
+}
+
+// Cursor returns the key to query from, or nil for the first page.
+func (r *Request[K]) Cursor() *K {
+	if r.Backward() {
+		return r.Before
+	}
+
+	return r.After
+}
+
+// Keyset paginates the items of type T by the sort key K, e.g.
+//
+//	type UserKey struct {
+//		CreatedAt time.Time
+//		ID        int64
+//	}
+//
+// The cursors are opaque. They are base64 encoded, and signed with HMAC-SHA256
+// so that clients can not tamper with them.
+//
+// A zero MaxLimit does not cap the first and last parameters.
+type Keyset[T, K any] struct {
+	Secret       []byte
+	Key          func(T) K
+	DefaultLimit int
+	MaxLimit     int
+}
+
+func NewKeyset[T, K any](secret []byte, key func(T) K) *Keyset[T, K] {
+	return &Keyset[T, K]{
+		Secret:       secret,
+		Key:          key,
+		DefaultLimit: 20,
+		MaxLimit:     100,
+	}
+}
+
+// Encode encodes the key into a signed cursor.
+func (k *Keyset[T, K]) Encode(key K) (string, error) {
+	b, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(b) + "." + enc.EncodeToString(safe.Signature(k.Secret, b)), nil
+}
+
+// Decode verifies the signature of the cursor and decodes the key.
+func (k *Keyset[T, K]) Decode(cursor string) (K, error) {
+	var key K
+
+	data, sig, ok := bytes.Cut([]byte(cursor), []byte("."))
+	if !ok {
+		return key, ErrInvalidCursor
+	}
+
+	enc := base64.RawURLEncoding
+	b, err := enc.DecodeString(string(data))
+	if err != nil {
+		return key, ErrInvalidCursor
+	}
+	s, err := enc.DecodeString(string(sig))
+	if err != nil {
+		return key, ErrInvalidCursor
+	}
+	if !hmac.Equal(s, safe.Signature(k.Secret, b)) {
+		return key, ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(b, &key); err != nil {
+		return key, ErrInvalidCursor
+	}
+
+	return key, nil
+}
+
+// Parse parses the request from the query parameters first, after, last and
+// before. Invalid parameters are returned as response.ValidationErrors.
+func (k *Keyset[T, K]) Parse(q url.Values) (*Request[K], error) {
+	errs := make(map[string]string)
+
+	limit := func(name string) int {
+		s := q.Get(name)
+		if s == "" {
+			return 0
+		}
+
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			errs[name] = "must be a positive integer"
+			return 0
+		}
+
+		if k.MaxLimit > 0 {
+			n = min(n, k.MaxLimit)
+		}
+
+		return n
+	}
+	cursor := func(name string) *K {
+		s := q.Get(name)
+		if s == "" {
+			return nil
+		}
+
+		key, err := k.Decode(s)
+		if err != nil {
+			errs[name] = "invalid cursor"
+			return nil
+		}
+
+		return &key
+	}
+
+	req := &Request[K]{
+		First:  limit("first"),
+		After:  cursor("after"),
+		Last:   limit("last"),
+		Before: cursor("before"),
+	}
+	if err := response.NewValidationErrors(errs); err != nil {
+		return nil, err
+	}
+
+	forward := req.First > 0 || req.After != nil
+	backward := req.Last > 0 || req.Before != nil
+	switch {
+	case forward && backward:
+		return nil, response.NewValidationErrors(map[string]string{
+			"before": "cannot be used with first or after",
+		})
+	case backward && req.Last == 0:
+		req.Last = k.DefaultLimit
+	case !backward && req.First == 0:
+		req.First = k.DefaultLimit
+	}
+
+	return req, nil
+}
+
+// Paginate trims the additional item fetched by Request.Limit, and returns
+// the page info.
+// When paging backward, the items are expected in the reverse order, and are
+// reversed back to the sort order.
+func (k *Keyset[T, K]) Paginate(items []T, req *Request[K]) ([]T, *response.PageInfo, error) {
+	info := new(response.PageInfo)
+
+	if req.Backward() {
+		if len(items) > req.Last {
+			items = items[:req.Last]
+			info.HasPrevPage = true
+		}
+		items = slices.Clone(items)
+		slices.Reverse(items)
+		info.HasNextPage = req.Before != nil
+	} else {
+		if len(items) > req.First {
+			items = items[:req.First]
+			info.HasNextPage = true
+		}
+		info.HasPrevPage = req.After != nil
+	}
+
+	if len(items) == 0 {
+		return items, info, nil
+	}
+
+	var err error
+	info.StartCursor, err = k.Encode(k.Key(items[0]))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info.EndCursor, err = k.Encode(k.Key(items[len(items)-1]))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return items, info, nil
+}
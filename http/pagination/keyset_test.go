@@ -0,0 +1,172 @@
+package pagination_test
+
+import (
+	"cmp"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/core/http/pagination"
+	"github.com/alextanhongpin/core/http/response"
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	ID        int64
+	CreatedAt time.Time
+}
+
+type userKey struct {
+	CreatedAt time.Time `json:"createdAt"`
+	ID        int64     `json:"id"`
+}
+
+func TestKeyset(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	users := make([]user, 10)
+	for i := range users {
+		users[i] = user{ID: int64(i + 1), CreatedAt: now.Add(time.Duration(i/2) * time.Hour)}
+	}
+
+	key := func(u user) userKey {
+		return userKey{CreatedAt: u.CreatedAt, ID: u.ID}
+	}
+	compare := func(a, b userKey) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	}
+
+	// query simulates the keyset query on (created_at, id).
+	query := func(req *pagination.Request[userKey]) []user {
+		var res []user
+		if req.Backward() {
+			for _, u := range slices.Backward(users) {
+				if req.Before == nil || compare(key(u), *req.Before) < 0 {
+					res = append(res, u)
+				}
+			}
+		} else {
+			for _, u := range users {
+				if req.After == nil || compare(key(u), *req.After) > 0 {
+					res = append(res, u)
+				}
+			}
+		}
+
+		return res[:min(len(res), req.Limit())]
+	}
+	keyset := pagination.NewKeyset([]byte("secret"), key)
+
+	page := func(t *testing.T, q string) ([]int64, *response.PageInfo) {
+		t.Helper()
+
+		v, err := url.ParseQuery(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := keyset.Parse(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		items, info, err := keyset.Paginate(query(req), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids := make([]int64, len(items))
+		for i, u := range items {
+			ids[i] = u.ID
+		}
+
+		return ids, info
+	}
+
+	is := assert.New(t)
+	ids, info := page(t, "first=4")
+	is.Equal([]int64{1, 2, 3, 4}, ids)
+	is.False(info.HasPrevPage)
+	is.True(info.HasNextPage)
+
+	ids, info = page(t, "first=4&after="+info.EndCursor)
+	is.Equal([]int64{5, 6, 7, 8}, ids)
+	is.True(info.HasPrevPage)
+	is.True(info.HasNextPage)
+
+	next := info.EndCursor
+	ids, info = page(t, "last=2&before="+info.StartCursor)
+	is.Equal([]int64{3, 4}, ids)
+	is.True(info.HasPrevPage)
+	is.True(info.HasNextPage)
+
+	ids, info = page(t, "first=4&after="+next)
+	is.Equal([]int64{9, 10}, ids)
+	is.True(info.HasPrevPage)
+	is.False(info.HasNextPage)
+
+	ids, info = page(t, "last=3")
+	is.Equal([]int64{8, 9, 10}, ids)
+	is.True(info.HasPrevPage)
+	is.False(info.HasNextPage)
+}
+
+func TestKeysetCursor(t *testing.T) {
+	keyset := pagination.NewKeyset([]byte("secret"), func(n int) int { return n })
+
+	is := assert.New(t)
+	cursor, err := keyset.Encode(42)
+	is.Nil(err)
+	is.NotContains(cursor, "42")
+
+	n, err := keyset.Decode(cursor)
+	is.Nil(err)
+	is.Equal(42, n)
+
+	t.Run("tampered", func(t *testing.T) {
+		data, sig, _ := strings.Cut(cursor, ".")
+		forged, err := keyset.Encode(43)
+		is.Nil(err)
+		forgedData, _, _ := strings.Cut(forged, ".")
+
+		is := assert.New(t)
+		_, err = keyset.Decode(forgedData + "." + sig)
+		is.ErrorIs(err, pagination.ErrInvalidCursor)
+
+		_, err = keyset.Decode(data)
+		is.ErrorIs(err, pagination.ErrInvalidCursor)
+
+		other := pagination.NewKeyset([]byte("other"), func(n int) int { return n })
+		_, err = other.Decode(cursor)
+		is.ErrorIs(err, pagination.ErrInvalidCursor)
+	})
+
+	t.Run("parse", func(t *testing.T) {
+		is := assert.New(t)
+		req, err := keyset.Parse(url.Values{})
+		is.Nil(err)
+		is.Equal(20, req.First)
+		is.Equal(21, req.Limit())
+
+		req, err = keyset.Parse(url.Values{"first": {"1000"}})
+		is.Nil(err)
+		is.Equal(100, req.First)
+
+		uncapped := pagination.NewKeyset([]byte("secret"), func(n int) int { return n })
+		uncapped.MaxLimit = 0
+		req, err = uncapped.Parse(url.Values{"last": {"1000"}})
+		is.Nil(err)
+		is.Equal(1000, req.Last)
+
+		_, err = keyset.Parse(url.Values{"first": {"-1"}, "after": {"abc"}})
+		var ve response.ValidationErrors
+		is.True(errors.As(err, &ve))
+		is.Equal(response.ValidationErrors{
+			"first": "must be a positive integer",
+			"after": "invalid cursor",
+		}, ve)
+
+		_, err = keyset.Parse(url.Values{"first": {"1"}, "last": {"1"}})
+		is.True(errors.As(err, &ve))
+	})
+}
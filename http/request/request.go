@@ -30,6 +30,15 @@ type validatable interface {
 
 // DecodeJSON decodes the json to struct and performs validation.
 func DecodeJSON(r *http.Request, v validatable) error {
+	if err := ReadJSON(r, v); err != nil {
+		return err
+	}
+
+	return v.Valid()
+}
+
+// ReadJSON is similar to DecodeJSON, but without validation.
+func ReadJSON(r *http.Request, v any) error {
 	b, err := io.ReadAll(r.Body)
 	if err != nil {
 		return err
@@ -45,5 +54,5 @@ func DecodeJSON(r *http.Request, v validatable) error {
 		return &BodyError{Body: b, err: err}
 	}
 
-	return v.Valid()
+	return nil
 }
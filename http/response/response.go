@@ -64,3 +64,17 @@ func NewJSONError(err error) *Body {
 		},
 	}
 }
+
+// Page is similar to OK, but with the page info, e.g. from pagination.Keyset.
+func Page(w http.ResponseWriter, data any, pageInfo *PageInfo, codes ...int) error {
+	code := http.StatusOK
+	if len(codes) > 0 {
+		code = codes[0]
+	}
+
+	return JSON(w, &Body{
+		Code:     code,
+		Data:     data,
+		PageInfo: pageInfo,
+	}, code)
+}
@@ -0,0 +1,76 @@
+package route
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/alextanhongpin/core/http/chain"
+)
+
+// Group registers the routes under the prefix, with the middlewares applied
+// only to the routes in the group.
+//
+//	mux := http.NewServeMux()
+//	api := route.NewGroup(mux, "/api", logger)
+//	users := api.Group("/users", auth)
+//	users.Handle("GET /{id}", route.Handle(findUser)) // GET /api/users/{id}
+type Group struct {
+	mux         *http.ServeMux
+	prefix      string
+	middlewares []chain.Middleware
+	routes      *[]Route
+}
+
+// Route is the handler registered with the pattern, without the middlewares.
+type Route struct {
+	Pattern string
+	Handler http.Handler
+}
+
+func NewGroup(mux *http.ServeMux, prefix string, mws ...chain.Middleware) *Group {
+	return &Group{
+		mux:         mux,
+		prefix:      strings.TrimSuffix(prefix, "/"),
+		middlewares: mws,
+		routes:      new([]Route),
+	}
+}
+
+// Group returns a sub group. The middlewares of the parent group runs first.
+func (g *Group) Group(prefix string, mws ...chain.Middleware) *Group {
+	return &Group{
+		mux:         g.mux,
+		prefix:      g.prefix + strings.TrimSuffix(prefix, "/"),
+		middlewares: append(slices.Clip(g.middlewares), mws...),
+		routes:      g.routes,
+	}
+}
+
+// Handle registers the handler for the pattern, which may include the method,
+// e.g. GET /users/{id}.
+func (g *Group) Handle(pattern string, h http.Handler, mws ...chain.Middleware) {
+	pattern = g.Pattern(pattern)
+	g.mux.Handle(pattern, chain.Handler(h, append(slices.Clip(g.middlewares), mws...)...))
+	*g.routes = append(*g.routes, Route{Pattern: pattern, Handler: h})
+}
+
+func (g *Group) HandleFunc(pattern string, h http.HandlerFunc, mws ...chain.Middleware) {
+	g.Handle(pattern, h, mws...)
+}
+
+// Routes returns the routes registered by the group, the parent and the sub
+// groups, in the order they are registered.
+func (g *Group) Routes() []Route {
+	return slices.Clone(*g.routes)
+}
+
+// Pattern returns the pattern with the prefix of the group.
+func (g *Group) Pattern(pattern string) string {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		return g.prefix + pattern
+	}
+
+	return method + " " + g.prefix + strings.TrimLeft(path, " ")
+}
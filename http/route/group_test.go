@@ -0,0 +1,61 @@
+package route_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alextanhongpin/core/http/chain"
+	"github.com/alextanhongpin/core/http/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroup(t *testing.T) {
+	var logs []string
+	middleware := func(name string) chain.Middleware {
+		return func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				logs = append(logs, name)
+				h.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	mux := http.NewServeMux()
+	api := route.NewGroup(mux, "/api/", middleware("api"))
+	api.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Pattern)
+	})
+
+	users := api.Group("/users", middleware("users"))
+	users.HandleFunc("GET /{id}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Pattern)
+	}, middleware("route"))
+
+	serve := func(target string) (string, []string) {
+		logs = nil
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", target, nil)
+		mux.ServeHTTP(w, r)
+
+		return strings.TrimSpace(w.Body.String()), logs
+	}
+
+	is := assert.New(t)
+	body, logs := serve("/api/health")
+	is.Equal("GET /api/health", body)
+	is.Equal([]string{"api"}, logs)
+
+	body, logs = serve("/api/users/1")
+	is.Equal("GET /api/users/{id}", body)
+	is.Equal([]string{"api", "users", "route"}, logs)
+
+	body, logs = serve("/health")
+	is.Equal("404 page not found", body)
+	is.Empty(logs)
+
+	is.Equal("/api/users/{id}", users.Pattern("/{id}"))
+}
@@ -0,0 +1,213 @@
+// Package route adapts typed functions to http.Handler, and groups the routes
+// with shared prefix and middlewares.
+package route
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/alextanhongpin/core/http/request"
+	"github.com/alextanhongpin/core/http/response"
+	"github.com/alextanhongpin/errors/causes"
+	"github.com/alextanhongpin/errors/codes"
+)
+
+var ErrInvalidJSON = causes.New(codes.BadRequest, "api/invalid_json", "The request body is not a valid JSON")
+
+// Handler is the http.Handler created by Handle, with the types of the
+// request and response for introspection, e.g. OpenAPI.
+type Handler struct {
+	http.Handler
+	Request  reflect.Type
+	Response reflect.Type
+	Status   int
+	Summary  string // Optional. Describes the operation.
+}
+
+type validatable interface {
+	Valid() error
+}
+
+// Handle decodes the request into Req and responds with Res as JSON.
+//
+// The request is populated in the following order:
+//   - the JSON body, if any
+//   - the path values, e.g. `path:"id"` for /users/{id}
+//   - the query values, e.g. `query:"limit"`
+//
+// Then Valid() is called if Req or *Req implements it. Req may also be a
+// pointer, e.g. *CreateUserRequest. Errors are mapped with
+// response.NewJSONError. The status defaults to 200 OK.
+func Handle[Req, Res any](fn func(ctx context.Context, req Req) (Res, error), status ...int) *Handler {
+	code := http.StatusOK
+	if len(status) > 0 {
+		code = status[0]
+	}
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := Decode[Req](r)
+		if err != nil {
+			response.Error(w, err)
+
+			return
+		}
+
+		res, err := fn(r.Context(), req)
+		if err != nil {
+			response.Error(w, err)
+
+			return
+		}
+
+		if code == http.StatusNoContent {
+			response.NoContent(w)
+
+			return
+		}
+
+		if err := response.OK(w, res, code); err != nil {
+			response.Error(w, err)
+		}
+	})
+
+	return &Handler{
+		Handler:  h,
+		Request:  reflect.TypeFor[Req](),
+		Response: reflect.TypeFor[Res](),
+		Status:   code,
+	}
+}
+
+// Decode decodes the request body, path and query values into Req, and
+// validates it. See Handle.
+func Decode[Req any](r *http.Request) (Req, error) {
+	var req Req
+
+	// Pointer requests are allocated and decoded in place, so that the
+	// methods of both Req and *Req are found.
+	target := any(&req)
+	if t := reflect.TypeFor[Req](); t.Kind() == reflect.Pointer {
+		req = reflect.New(t.Elem()).Interface().(Req)
+		target = req
+	}
+
+	b, err := request.Read(r)
+	if err != nil {
+		return req, err
+	}
+	if len(bytes.TrimSpace(b)) > 0 {
+		if err := request.ReadJSON(r, target); err != nil {
+			return req, fmt.Errorf("%w: %w", ErrInvalidJSON, err)
+		}
+	}
+
+	if err := decodeParams(r, target); err != nil {
+		return req, err
+	}
+
+	if v, ok := target.(validatable); ok {
+		if err := v.Valid(); err != nil {
+			return req, err
+		}
+	}
+
+	return req, nil
+}
+
+// decodeParams sets the fields tagged with `path` and `query`.
+// Fields that fails to parse are reported as response.ValidationErrors.
+func decodeParams(r *http.Request, v any) error {
+	rv := reflect.ValueOf(v).Elem()
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	errs := make(map[string]string)
+	rt := rv.Type()
+	query := r.URL.Query()
+	for i := range rt.NumField() {
+		f := rt.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		var name string
+		var values []string
+		if name = f.Tag.Get("path"); name != "" {
+			if s := r.PathValue(name); s != "" {
+				values = []string{s}
+			}
+		} else if name = f.Tag.Get("query"); name != "" {
+			values = query[name]
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		if err := setValue(rv.Field(i), values); err != nil {
+			errs[name] = err.Error()
+		}
+	}
+
+	return response.NewValidationErrors(errs)
+}
+
+var errUnsupportedType = errors.New("unsupported type")
+
+func setValue(v reflect.Value, values []string) error {
+	if tu, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return tu.UnmarshalText([]byte(values[0]))
+	}
+
+	if v.Kind() == reflect.Slice {
+		s := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, val := range values {
+			if err := setValue(s.Index(i), []string{val}); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+
+		return nil
+	}
+
+	s := values[0]
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a positive integer")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		v.SetFloat(n)
+	default:
+		return errUnsupportedType
+	}
+
+	return nil
+}
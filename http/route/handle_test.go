@@ -0,0 +1,165 @@
+package route_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alextanhongpin/core/http/response"
+	"github.com/alextanhongpin/core/http/route"
+	"github.com/alextanhongpin/errors/causes"
+	"github.com/alextanhongpin/errors/codes"
+	"github.com/stretchr/testify/assert"
+)
+
+var ErrUserNotFound = causes.New(codes.NotFound, "user/not_found", "User not found")
+
+type updateUserRequest struct {
+	ID     int64    `json:"-" path:"id"`
+	Name   string   `json:"name"`
+	Notify bool     `json:"-" query:"notify"`
+	Tags   []string `json:"-" query:"tag"`
+}
+
+func (r *updateUserRequest) Valid() error {
+	return response.NewValidationErrors(map[string]string{
+		"name": map[bool]string{true: "required"}[r.Name == ""],
+	})
+}
+
+type updateUserResponse struct {
+	ID     int64    `json:"id"`
+	Name   string   `json:"name"`
+	Notify bool     `json:"notify"`
+	Tags   []string `json:"tags"`
+}
+
+func TestHandle(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("PUT /users/{id}", route.Handle(func(ctx context.Context, req updateUserRequest) (*updateUserResponse, error) {
+		if req.ID == 404 {
+			return nil, ErrUserNotFound
+		}
+
+		return &updateUserResponse{
+			ID:     req.ID,
+			Name:   req.Name,
+			Notify: req.Notify,
+			Tags:   req.Tags,
+		}, nil
+	}))
+
+	serve := func(target, body string) (int, map[string]any) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("PUT", target, strings.NewReader(body))
+		mux.ServeHTTP(w, r)
+
+		var res map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+
+		return w.Code, res
+	}
+
+	t.Run("success", func(t *testing.T) {
+		code, res := serve("/users/1?notify=true&tag=a&tag=b", `{"name": "john"}`)
+
+		is := assert.New(t)
+		is.Equal(http.StatusOK, code)
+		is.Equal(map[string]any{
+			"id":     1.0,
+			"name":   "john",
+			"notify": true,
+			"tags":   []any{"a", "b"},
+		}, res["data"])
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		code, res := serve("/users/1", `<html>`)
+
+		is := assert.New(t)
+		is.Equal(http.StatusBadRequest, code)
+		is.Equal("api/invalid_json", res["error"].(map[string]any)["code"])
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		code, res := serve("/users/abc?notify=maybe", `{"name": "john"}`)
+
+		is := assert.New(t)
+		is.Equal(http.StatusBadRequest, code)
+		is.Equal(map[string]any{
+			"id":     "must be an integer",
+			"notify": "must be a boolean",
+		}, res["error"].(map[string]any)["errors"])
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		code, res := serve("/users/1", `{}`)
+
+		is := assert.New(t)
+		is.Equal(http.StatusBadRequest, code)
+		is.Equal(map[string]any{
+			"name": "required",
+		}, res["error"].(map[string]any)["errors"])
+	})
+
+	t.Run("error", func(t *testing.T) {
+		code, res := serve("/users/404", `{"name": "john"}`)
+
+		is := assert.New(t)
+		is.Equal(http.StatusNotFound, code)
+		is.Equal("user/not_found", res["error"].(map[string]any)["code"])
+	})
+}
+
+func TestHandleStatus(t *testing.T) {
+	h := route.Handle(func(ctx context.Context, req struct{}) (any, error) {
+		return nil, nil
+	}, http.StatusNoContent)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("DELETE", "/", nil)
+	h.ServeHTTP(w, r)
+
+	is := assert.New(t)
+	is.Equal(http.StatusNoContent, w.Code)
+	is.Empty(w.Body.String())
+}
+
+func TestDecode(t *testing.T) {
+	r := httptest.NewRequest("GET", "/?notify=1", nil)
+	req, err := route.Decode[updateUserRequest](r)
+
+	is := assert.New(t)
+	var ve response.ValidationErrors
+	is.True(errors.As(err, &ve))
+	is.True(req.Notify)
+}
+
+func TestDecode_Pointer(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		r := httptest.NewRequest("PATCH", "/users/42?notify=true", strings.NewReader(`{"name": "john"}`))
+		r.SetPathValue("id", "42")
+		req, err := route.Decode[*updateUserRequest](r)
+
+		is := assert.New(t)
+		is.Nil(err)
+		is.Equal(&updateUserRequest{ID: 42, Name: "john", Notify: true}, req)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		r := httptest.NewRequest("PATCH", "/users/42", nil)
+		req, err := route.Decode[*updateUserRequest](r)
+
+		is := assert.New(t)
+		var ve response.ValidationErrors
+		is.True(errors.As(err, &ve), "validates the pointer request")
+		is.Equal(response.ValidationErrors{"name": "required"}, ve)
+		is.NotNil(req)
+	})
+}
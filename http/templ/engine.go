@@ -0,0 +1,111 @@
+package templ
+
+import (
+	"io"
+	"io/fs"
+	"path/filepath"
+	"slices"
+)
+
+// tmpl is implemented by both *text/template.Template and
+// *html/template.Template.
+type tmpl[T any] interface {
+	Clone() (T, error)
+	Lookup(name string) T
+	ParseFS(fsys fs.FS, patterns ...string) (T, error)
+	Execute(wr io.Writer, data any) error
+	ExecuteTemplate(wr io.Writer, name string, data any) error
+}
+
+// engine parses the templates from the FS with either text/template or
+// html/template.
+type engine[T tmpl[T]] struct {
+	basePath  string
+	fs        fs.FS
+	hotReload bool
+	new       func() T
+}
+
+func (e *engine[T]) parse(files ...string) (T, error) {
+	f := joinPaths(e.basePath, files...)
+
+	tpl, err := e.new().ParseFS(e.fs, f...)
+	if err != nil {
+		return tpl, err
+	}
+
+	// ParseFS returns the first file, which is the "" in the template.New("").
+	// We want to lookup the first file we passed in instead.
+	return tpl.Lookup(filepath.Base(f[0])), nil
+}
+
+// extend returns the func that parses the files into the clone of the base
+// template.
+func (e *engine[T]) extend(base func() (T, error), files ...string) func() (T, error) {
+	return func() (T, error) {
+		tpl, err := base()
+		if err != nil {
+			return tpl, err
+		}
+
+		top, err := tpl.Clone()
+		if err != nil {
+			return top, err
+		}
+
+		return top.ParseFS(e.fs, joinPaths(e.basePath, files...)...)
+	}
+}
+
+// compile calls the fn immediately and caches the template, unless hot reload
+// is enabled.
+func (e *engine[T]) compile(fn func() (T, error)) (func() (T, error), error) {
+	if e.hotReload {
+		return fn, nil
+	}
+
+	tpl, err := fn()
+	if err != nil {
+		return nil, err
+	}
+
+	return func() (T, error) {
+		return tpl, nil
+	}, nil
+}
+
+// extension is the compiled template that can be extended.
+type extension[T tmpl[T]] struct {
+	fn     func() (T, error)
+	engine *engine[T]
+}
+
+func (e *extension[T]) Execute(wr io.Writer, data any) error {
+	tpl, err := e.fn()
+	if err != nil {
+		return err
+	}
+
+	return tpl.Execute(wr, data)
+}
+
+func (e *extension[T]) ExecuteTemplate(wr io.Writer, name string, data any) error {
+	tpl, err := e.fn()
+	if err != nil {
+		return err
+	}
+
+	return tpl.ExecuteTemplate(wr, name, data)
+}
+
+func joinPaths(basePath string, files ...string) []string {
+	if basePath == "" {
+		return files
+	}
+
+	f := slices.Clone(files)
+	for i := range f {
+		f[i] = filepath.Join(basePath, f[i])
+	}
+	return f
+}
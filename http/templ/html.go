@@ -0,0 +1,117 @@
+package templ
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+)
+
+// HTMLTemplate is similar to Template, but uses html/template to escape the
+// data based on the context, e.g. HTML, attributes, JavaScript and URLs.
+type HTMLTemplate struct {
+	BasePath string
+	// e.g. os.DirFS(".") or from embed.FS
+	FS        fs.FS
+	Funcs     template.FuncMap
+	HotReload bool
+}
+
+// Compile parses the files, and panics if the files cannot be parsed, so that
+// it can be used to initialize the variables. With hot reload, the files are
+// parsed on every execution instead, and the errors are returned by Execute.
+func (t *HTMLTemplate) Compile(files ...string) *HTMLExtension {
+	e := t.engine()
+	fn, err := e.compile(func() (*template.Template, error) {
+		return e.parse(files...)
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return &HTMLExtension{
+		extension: extension[*template.Template]{
+			fn:     fn,
+			engine: e,
+		},
+	}
+}
+
+func (t *HTMLTemplate) engine() *engine[*template.Template] {
+	return &engine[*template.Template]{
+		basePath:  t.BasePath,
+		fs:        t.FS,
+		hotReload: t.HotReload,
+		new: func() *template.Template {
+			return template.New("").Funcs(t.Funcs)
+		},
+	}
+}
+
+type HTMLExtension struct {
+	extension[*template.Template]
+}
+
+// Extend parses the files into the clone of the template.
+// Unlike text/template, html/template cannot be cloned after it is executed,
+// so the files are parsed immediately, unless hot reload is enabled.
+func (e *HTMLExtension) Extend(files ...string) (*HTMLExtension, error) {
+	fn, err := e.engine.compile(e.engine.extend(e.fn, files...))
+	if err != nil {
+		return nil, err
+	}
+
+	return &HTMLExtension{
+		extension: extension[*template.Template]{
+			fn:     fn,
+			engine: e.engine,
+		},
+	}, nil
+}
+
+func (e *HTMLExtension) Template() (*template.Template, error) {
+	return e.fn()
+}
+
+// Handler renders the template with the data from the request. See Render.
+func (e *HTMLExtension) Handler(name string, status int, data func(r *http.Request) any) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v any
+		if data != nil {
+			v = data(r)
+		}
+
+		if err := Render(w, e, status, name, v); err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	})
+}
+
+type executor interface {
+	Execute(wr io.Writer, data any) error
+	ExecuteTemplate(wr io.Writer, name string, data any) error
+}
+
+// Render executes the named template into a buffer, and writes it with the
+// status code only if it succeeds, so that a failed template never sends a
+// partial page. The root template is executed when the name is empty.
+// Nothing is written when an error is returned.
+func Render(w http.ResponseWriter, e executor, status int, name string, data any) error {
+	var b bytes.Buffer
+	var err error
+	if name == "" {
+		err = e.Execute(&b, data)
+	} else {
+		err = e.ExecuteTemplate(&b, name, data)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, err = b.WriteTo(w)
+
+	return err
+}
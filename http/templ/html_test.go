@@ -0,0 +1,127 @@
+package templ_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/alextanhongpin/core/http/templ"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTML(t *testing.T) {
+	tpl := &templ.HTMLTemplate{
+		FS: newFS(),
+	}
+	base := tpl.Compile("base.html", "partials/*.html")
+	home, err := base.Extend("home.html")
+	is := assert.New(t)
+	is.Nil(err)
+	about, err := base.Extend("about.html")
+	is.Nil(err)
+
+	var b bytes.Buffer
+	is.Nil(home.Execute(&b, map[string]any{
+		"Msg": "<script>alert(1)</script>",
+	}))
+	is.Equal("hello, &lt;script&gt;alert(1)&lt;/script&gt;", b.String())
+
+	b.Reset()
+	is.Nil(about.Execute(&b, map[string]any{
+		"Msg": "world",
+	}))
+	is.Equal("header: world", b.String())
+
+	b.Reset()
+	is.Nil(base.ExecuteTemplate(&b, "footer", nil))
+	is.Equal("footer", b.String())
+}
+
+func TestHTMLExtendError(t *testing.T) {
+	tpl := &templ.HTMLTemplate{
+		FS: newFS(),
+	}
+	base := tpl.Compile("base.html", "home.html")
+
+	is := assert.New(t)
+	_, err := base.Extend("missing.html")
+	is.NotNil(err)
+
+	// html/template cannot be cloned after it is executed.
+	is.Nil(base.Execute(new(bytes.Buffer), map[string]any{}))
+	_, err = base.Extend("about.html")
+	is.NotNil(err)
+}
+
+func TestHTMLHotReload(t *testing.T) {
+	fs := newFS()
+	tpl := &templ.HTMLTemplate{
+		FS:        fs,
+		HotReload: true,
+	}
+	home, err := tpl.Compile("base.html").Extend("home.html")
+	is := assert.New(t)
+	is.Nil(err)
+
+	var b bytes.Buffer
+	is.Nil(home.Execute(&b, map[string]any{"Msg": "world"}))
+	is.Equal("hello, world", b.String())
+
+	fs["home.html"] = &fstest.MapFile{
+		Data: []byte(`{{ define "content" }}hi, {{.Msg}}{{ end }}`),
+	}
+
+	b.Reset()
+	is.Nil(home.Execute(&b, map[string]any{"Msg": "world"}))
+	is.Equal("hi, world", b.String())
+}
+
+func TestHTMLHandler(t *testing.T) {
+	fs := newFS()
+	fs["error.html"] = &fstest.MapFile{
+		Data: []byte(`{{ define "content" }}partial {{ template "missing" . }}{{ end }}`),
+	}
+	tpl := &templ.HTMLTemplate{
+		FS: fs,
+	}
+	base := tpl.Compile("base.html")
+
+	t.Run("success", func(t *testing.T) {
+		home, err := base.Extend("home.html")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		h := home.Handler("", http.StatusCreated, func(r *http.Request) any {
+			return map[string]any{"Msg": r.URL.Query().Get("msg")}
+		})
+
+		wr := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/?msg=<b>world</b>", nil)
+		h.ServeHTTP(wr, r)
+
+		is := assert.New(t)
+		is.Equal(http.StatusCreated, wr.Code)
+		is.Equal("text/html; charset=utf-8", wr.Header().Get("Content-Type"))
+		is.Equal("hello, &lt;b&gt;world&lt;/b&gt;", wr.Body.String())
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		page, err := base.Extend("error.html")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		h := page.Handler("", http.StatusOK, nil)
+
+		wr := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		h.ServeHTTP(wr, r)
+
+		is := assert.New(t)
+		is.Equal(http.StatusInternalServerError, wr.Code)
+		is.NotContains(wr.Body.String(), "partial")
+	})
+}
@@ -1,10 +1,7 @@
 package templ
 
 import (
-	"io"
 	"io/fs"
-	"path/filepath"
-	"slices"
 	"sync"
 	"text/template"
 )
@@ -19,8 +16,10 @@ type Template struct {
 
 func (t *Template) Compile(files ...string) *Extension {
 	return &Extension{
-		fn: t.ParseFunc(files...),
-		t:  t,
+		extension: extension[*template.Template]{
+			fn:     withError(t.ParseFunc(files...)),
+			engine: t.engine(),
+		},
 	}
 }
 
@@ -39,58 +38,46 @@ func (t *Template) ParseFunc(files ...string) func() *template.Template {
 }
 
 func (t *Template) Parse(files ...string) *template.Template {
-	f := t.paths(files...)
-
-	tpl := template.Must(template.New("").Funcs(t.Funcs).ParseFS(t.FS, f...))
-	// ParseFS returns the first file, which is the "" in the template.New("").
-	// We want to lookup the first file we passed in instead.
-	return tpl.Lookup(filepath.Base(f[0]))
+	return template.Must(t.engine().parse(files...))
 }
 
-func (t *Template) paths(files ...string) []string {
-	if t.BasePath == "" {
-		return files
-	}
-
-	f := slices.Clone(files)
-	for i := range f {
-		f[i] = filepath.Join(t.BasePath, f[i])
+func (t *Template) engine() *engine[*template.Template] {
+	return &engine[*template.Template]{
+		basePath:  t.BasePath,
+		fs:        t.FS,
+		hotReload: t.HotReload,
+		new: func() *template.Template {
+			return template.New("").Funcs(t.Funcs)
+		},
 	}
-	return f
 }
 
 type Extension struct {
-	fn func() *template.Template
-	t  *Template
+	extension[*template.Template]
 }
 
+// Extend parses the files into the clone of the template. Unless hot reload
+// is enabled, the files are parsed once on the first execution.
 func (e *Extension) Extend(files ...string) *Extension {
-	return &Extension{
-		fn: e.templateFunc(files...),
-		t:  e.t,
+	fn := e.engine.extend(e.fn, files...)
+	if !e.engine.hotReload {
+		fn = sync.OnceValues(fn)
 	}
-}
 
-func (e *Extension) Execute(wr io.Writer, data any) error {
-	return e.fn().Execute(wr, data)
-}
-
-func (e *Extension) ExecuteTemplate(wr io.Writer, name string, data any) error {
-	return e.fn().ExecuteTemplate(wr, name, data)
+	return &Extension{
+		extension: extension[*template.Template]{
+			fn:     fn,
+			engine: e.engine,
+		},
+	}
 }
 
 func (e *Extension) Template() *template.Template {
-	return e.fn()
+	return template.Must(e.fn())
 }
 
-func (e *Extension) templateFunc(files ...string) func() *template.Template {
-	fn := func() *template.Template {
-		top := template.Must(e.fn().Clone())
-		return template.Must(top.ParseFS(e.t.FS, e.t.paths(files...)...))
+func withError[T any](fn func() T) func() (T, error) {
+	return func() (T, error) {
+		return fn(), nil
 	}
-	if e.t.HotReload {
-		return fn
-	}
-
-	return sync.OnceValue(fn)
 }
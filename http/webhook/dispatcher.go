@@ -0,0 +1,437 @@
+package webhook
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/alextanhongpin/core/http/response"
+	"github.com/alextanhongpin/core/sync/circuitbreaker"
+	"github.com/alextanhongpin/core/sync/retry"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDeliveryFailed   = errors.New("webhook: delivery failed")
+	ErrDispatcherClosed = errors.New("webhook: dispatcher closed")
+	ErrEndpointNotFound = errors.New("webhook: endpoint not found")
+	ErrEventNotFound    = errors.New("webhook: event not found")
+	ErrQueueFull        = errors.New("webhook: queue full")
+	ErrEndpointInvalid  = errors.New("webhook: endpoint id and url are required")
+)
+
+// Endpoint is the subscriber of the webhooks. The webhooks are signed with
+// all the secrets, so that the secrets can be rotated without downtime.
+type Endpoint struct {
+	ID      string
+	URL     string
+	Secrets [][]byte
+}
+
+// Event is the webhook to deliver to the endpoint. The ID is sent as the
+// X-Webhook-Id, and stays the same for the retries, so that the receiver can
+// deduplicate them.
+type Event struct {
+	ID         string
+	EndpointID string
+	Body       []byte
+	CreatedAt  time.Time
+	Attempts   int
+	Error      string
+}
+
+// Attempt is the delivery attempt of the event.
+type Attempt struct {
+	EventID    string
+	EndpointID string
+	Attempt    int
+	StatusCode int
+	Error      string
+	Duration   time.Duration
+	At         time.Time
+}
+
+// DeliveryStore records the delivery attempts, and stores the events that
+// failed after the maximum attempts.
+type DeliveryStore interface {
+	RecordAttempt(ctx context.Context, a *Attempt) error
+	Attempts(ctx context.Context, eventID string) ([]*Attempt, error)
+
+	// DeadLetter stores the failed event.
+	DeadLetter(ctx context.Context, e *Event) error
+	DeadLetters(ctx context.Context) ([]*Event, error)
+
+	// RemoveDeadLetter removes and returns the failed event, for redelivery.
+	// Returns ErrEventNotFound if the event does not exist.
+	RemoveDeadLetter(ctx context.Context, eventID string) (*Event, error)
+}
+
+type queue struct {
+	endpoint *Endpoint
+	events   chan *Event
+	breaker  *circuitbreaker.Breaker
+	retry    *retry.Retry
+}
+
+// Dispatcher delivers the events to the endpoints.
+//
+// The events are queued per endpoint, so that slow endpoints do not delay
+// the others. Failed deliveries are retried with the backoff, up to
+// MaxAttempts, and then moved to the dead-letter store. Each endpoint has its
+// own retry and circuit breaker, so that the failing endpoints do not throttle
+// or break the others.
+type Dispatcher struct {
+	Client      *http.Client
+	Store       DeliveryStore
+	NewRetry    func() *retry.Retry
+	MaxAttempts int
+	QueueSize   int
+	NewBreaker  func() *circuitbreaker.Breaker
+	Now         func() time.Time
+
+	mu     sync.Mutex
+	queues map[string]*queue
+	closed bool
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewDispatcher(store DeliveryStore) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Dispatcher{
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		Store:       store,
+		NewRetry:    newRetry,
+		MaxAttempts: 5,
+		QueueSize:   100,
+		NewBreaker:  circuitbreaker.New,
+		Now:         time.Now,
+		queues:      make(map[string]*queue),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Register adds the endpoint, and starts delivering the events to it.
+// Registering the existing endpoint updates the url and secrets.
+func (d *Dispatcher) Register(e *Endpoint) error {
+	if e.ID == "" || e.URL == "" {
+		return ErrEndpointInvalid
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return ErrDispatcherClosed
+	}
+
+	if q, ok := d.queues[e.ID]; ok {
+		q.endpoint = e
+		return nil
+	}
+
+	q := &queue{
+		endpoint: e,
+		events:   make(chan *Event, d.QueueSize),
+		breaker:  d.NewBreaker(),
+		retry:    d.NewRetry(),
+	}
+	d.queues[e.ID] = q
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+
+		for e := range q.events {
+			d.deliver(d.ctx, q, e)
+		}
+	}()
+
+	return nil
+}
+
+// Unregister removes the endpoint. The queued events are still delivered.
+func (d *Dispatcher) Unregister(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if q, ok := d.queues[id]; ok {
+		close(q.events)
+		delete(d.queues, id)
+	}
+}
+
+// Dispatch queues the body for delivery to the endpoint.
+// Returns ErrQueueFull if the endpoint has too many pending events.
+// The returned event is a copy, since the queued event is updated during the
+// delivery.
+func (d *Dispatcher) Dispatch(endpointID string, body []byte) (*Event, error) {
+	e := &Event{
+		ID:         uuid.NewString(),
+		EndpointID: endpointID,
+		Body:       body,
+		CreatedAt:  d.Now(),
+	}
+	c := *e
+	if err := d.enqueue(e); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// Redeliver queues the dead-lettered event for delivery again, with the
+// attempts reset.
+func (d *Dispatcher) Redeliver(ctx context.Context, eventID string) (*Event, error) {
+	e, err := d.Store.RemoveDeadLetter(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	e.Attempts = 0
+	e.Error = ""
+	c := *e
+	if err := d.enqueue(e); err != nil {
+		// Keep the event for the next redelivery.
+		return nil, errors.Join(err, d.Store.DeadLetter(ctx, e))
+	}
+
+	return &c, nil
+}
+
+// Shutdown stops accepting events, and waits for the queued events to be
+// delivered. If the context is done first, the pending events are moved to
+// the dead-letter store.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		for id, q := range d.queues {
+			close(q.events)
+			delete(d.queues, id)
+		}
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) enqueue(e *Event) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return ErrDispatcherClosed
+	}
+
+	q, ok := d.queues[e.EndpointID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrEndpointNotFound, e.EndpointID)
+	}
+
+	select {
+	case q.events <- e:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (d *Dispatcher) endpoint(q *queue) *Endpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return q.endpoint
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, q *queue, e *Event) {
+	var err error
+	for _, rerr := range q.retry.Try(ctx, d.MaxAttempts) {
+		if rerr != nil {
+			err = cmp.Or(err, rerr)
+			break
+		}
+
+		e.Attempts++
+		err = d.send(ctx, q, e)
+		if err == nil {
+			return
+		}
+	}
+
+	e.Error = err.Error()
+
+	// The context may be cancelled on shutdown.
+	_ = d.Store.DeadLetter(context.WithoutCancel(ctx), e)
+}
+
+// send records the attempt, including the attempts rejected by the circuit
+// breaker.
+func (d *Dispatcher) send(ctx context.Context, q *queue, e *Event) (err error) {
+	start := d.Now()
+	a := &Attempt{
+		EventID:    e.ID,
+		EndpointID: e.EndpointID,
+		Attempt:    e.Attempts,
+		At:         start,
+	}
+	defer func() {
+		a.Duration = d.Now().Sub(start)
+		if err != nil {
+			a.Error = err.Error()
+		}
+		d.record(ctx, a)
+	}()
+
+	return q.breaker.Do(func() error {
+		return d.post(ctx, d.endpoint(q), e, a)
+	})
+}
+
+func (d *Dispatcher) post(ctx context.Context, endpoint *Endpoint, e *Event, a *Attempt) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(e.Body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// The timestamp is renewed for every attempt, so that the retries are
+	// within the tolerance of the receiver.
+	p := &Payload{ID: e.ID, At: a.At, Body: e.Body}
+	p.SignRequest(req, endpoint.Secrets...)
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Drain the body to reuse the connection.
+	defer io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	a.StatusCode = resp.StatusCode
+	if replayed(resp) {
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%w: %s", ErrDeliveryFailed, resp.Status)
+	}
+
+	return nil
+}
+
+// replayed reports whether the receiver responds with the ErrReplayed from the
+// Verifier, i.e. the event is already received. Other 409 Conflict, e.g. from
+// the business logic, are failures.
+func replayed(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusConflict {
+		return false
+	}
+
+	var body response.Body
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&body); err != nil {
+		return false
+	}
+
+	return body.Error != nil && body.Error.Code == response.NewJSONError(ErrReplayed).Error.Code
+}
+
+func newRetry() *retry.Retry {
+	return retry.New(retry.NewExponentialBackOff(time.Second, time.Minute))
+}
+
+func (d *Dispatcher) record(ctx context.Context, a *Attempt) {
+	_ = d.Store.RecordAttempt(context.WithoutCancel(ctx), a)
+}
+
+// MemoryDeliveryStore is the in-memory DeliveryStore, for tests.
+type MemoryDeliveryStore struct {
+	mu       sync.Mutex
+	attempts map[string][]*Attempt
+	dead     map[string]*Event
+}
+
+var _ DeliveryStore = (*MemoryDeliveryStore)(nil)
+
+func NewMemoryDeliveryStore() *MemoryDeliveryStore {
+	return &MemoryDeliveryStore{
+		attempts: make(map[string][]*Attempt),
+		dead:     make(map[string]*Event),
+	}
+}
+
+func (s *MemoryDeliveryStore) RecordAttempt(ctx context.Context, a *Attempt) error {
+	s.mu.Lock()
+	s.attempts[a.EventID] = append(s.attempts[a.EventID], a)
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *MemoryDeliveryStore) Attempts(ctx context.Context, eventID string) ([]*Attempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.attempts[eventID]), nil
+}
+
+func (s *MemoryDeliveryStore) DeadLetter(ctx context.Context, e *Event) error {
+	s.mu.Lock()
+	s.dead[e.ID] = e
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *MemoryDeliveryStore) DeadLetters(ctx context.Context) ([]*Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := make([]*Event, 0, len(s.dead))
+	for _, e := range s.dead {
+		events = append(events, e)
+	}
+	slices.SortFunc(events, func(a, b *Event) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return events, nil
+}
+
+func (s *MemoryDeliveryStore) RemoveDeadLetter(ctx context.Context, eventID string) (*Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.dead[eventID]
+	if !ok {
+		return nil, ErrEventNotFound
+	}
+	delete(s.dead, eventID)
+
+	return e, nil
+}
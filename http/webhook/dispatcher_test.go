@@ -0,0 +1,218 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/core/http/response"
+	"github.com/alextanhongpin/core/http/webhook"
+	"github.com/alextanhongpin/core/sync/circuitbreaker"
+	"github.com/alextanhongpin/core/sync/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDispatcher(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		body      = []byte(`{"message":"hello"}`)
+		oldSecret = []byte("oldsecret1234567")
+		newSecret = []byte("newsecret1234567")
+	)
+
+	// newServer fails the first n requests.
+	newServer := func(t *testing.T, n int) (*httptest.Server, *atomic.Int64) {
+		var calls atomic.Int64
+		v := webhook.NewVerifier(newSecret)
+
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) <= int64(n) {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		})
+		ts := httptest.NewServer(v.Handler(h))
+		t.Cleanup(ts.Close)
+
+		return ts, &calls
+	}
+
+	newDispatcher := func(t *testing.T, url string) (*webhook.Dispatcher, *webhook.MemoryDeliveryStore) {
+		store := webhook.NewMemoryDeliveryStore()
+		d := webhook.NewDispatcher(store)
+		d.NewRetry = func() *retry.Retry {
+			return retry.New(retry.NewConstantBackOff(time.Millisecond))
+		}
+		d.MaxAttempts = 3
+		if err := d.Register(&webhook.Endpoint{
+			ID:      "orders",
+			URL:     url,
+			Secrets: [][]byte{oldSecret, newSecret},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		return d, store
+	}
+
+	t.Run("retry", func(t *testing.T) {
+		ts, calls := newServer(t, 2)
+		d, store := newDispatcher(t, ts.URL)
+
+		e, err := d.Dispatch("orders", body)
+		is := assert.New(t)
+		is.Nil(err)
+		is.Equal(0, e.Attempts, "the returned event is a copy")
+		is.Nil(d.Shutdown(ctx))
+		is.Equal(0, e.Attempts)
+
+		attempts, err := store.Attempts(ctx, e.ID)
+		is.Nil(err)
+		is.Len(attempts, 3)
+		is.Equal(http.StatusInternalServerError, attempts[0].StatusCode)
+		is.Equal(http.StatusOK, attempts[2].StatusCode)
+		is.Equal(int64(3), calls.Load())
+
+		dead, err := store.DeadLetters(ctx)
+		is.Nil(err)
+		is.Empty(dead)
+	})
+
+	t.Run("replayed", func(t *testing.T) {
+		v := webhook.NewVerifier(newSecret)
+		v.Store = webhook.NewMemoryStore()
+		ts := httptest.NewServer(v.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})))
+		t.Cleanup(ts.Close)
+
+		d, store := newDispatcher(t, ts.URL)
+		e, err := d.Dispatch("orders", body)
+		is := assert.New(t)
+		is.Nil(err)
+		is.Nil(d.Shutdown(ctx))
+
+		attempts, err := store.Attempts(ctx, e.ID)
+		is.Nil(err)
+		is.Len(attempts, 3)
+		for _, a := range attempts {
+			is.Equal(http.StatusInternalServerError, a.StatusCode, "the failed webhooks are not replays")
+		}
+
+		dead, err := store.DeadLetters(ctx)
+		is.Nil(err)
+		is.Len(dead, 1)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		tests := map[string]struct {
+			err      error
+			attempts int
+		}{
+			"replayed": {webhook.ErrReplayed, 1},
+			"business": {errors.New("conflict"), 3},
+		}
+
+		for name, tc := range tests {
+			t.Run(name, func(t *testing.T) {
+				ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if errors.Is(tc.err, webhook.ErrReplayed) {
+						response.Error(w, tc.err)
+						return
+					}
+
+					http.Error(w, tc.err.Error(), http.StatusConflict)
+				}))
+				t.Cleanup(ts.Close)
+
+				d, store := newDispatcher(t, ts.URL)
+				e, err := d.Dispatch("orders", body)
+				is := assert.New(t)
+				is.Nil(err)
+				is.Nil(d.Shutdown(ctx))
+
+				attempts, err := store.Attempts(ctx, e.ID)
+				is.Nil(err)
+				is.Len(attempts, tc.attempts)
+				is.Equal(http.StatusConflict, attempts[0].StatusCode)
+			})
+		}
+	})
+
+	t.Run("dead letter", func(t *testing.T) {
+		ts, calls := newServer(t, 3)
+		d, store := newDispatcher(t, ts.URL)
+
+		e, err := d.Dispatch("orders", body)
+		is := assert.New(t)
+		is.Nil(err)
+
+		is.Eventually(func() bool {
+			dead, _ := store.DeadLetters(ctx)
+			return len(dead) == 1
+		}, time.Second, time.Millisecond)
+
+		_, err = d.Redeliver(ctx, e.ID)
+		is.Nil(err)
+		is.Nil(d.Shutdown(ctx))
+
+		attempts, err := store.Attempts(ctx, e.ID)
+		is.Nil(err)
+		is.Len(attempts, 4)
+		is.Equal(http.StatusOK, attempts[3].StatusCode)
+		is.Equal(int64(4), calls.Load())
+
+		dead, err := store.DeadLetters(ctx)
+		is.Nil(err)
+		is.Empty(dead)
+
+		_, err = d.Redeliver(ctx, e.ID)
+		is.ErrorIs(err, webhook.ErrEventNotFound)
+	})
+
+	t.Run("circuit breaker", func(t *testing.T) {
+		ts, calls := newServer(t, 100)
+		d, store := newDispatcher(t, ts.URL)
+		d.Unregister("orders")
+		d.NewBreaker = func() *circuitbreaker.Breaker {
+			cb := circuitbreaker.New()
+			cb.FailureThreshold = 1
+			return cb
+		}
+		is := assert.New(t)
+		is.Nil(d.Register(&webhook.Endpoint{ID: "orders", URL: ts.URL, Secrets: [][]byte{newSecret}}))
+
+		e, err := d.Dispatch("orders", body)
+		is.Nil(err)
+		is.Nil(d.Shutdown(ctx))
+
+		attempts, err := store.Attempts(ctx, e.ID)
+		is.Nil(err)
+		is.Len(attempts, 3)
+		is.Equal(int64(1), calls.Load())
+		is.Equal(circuitbreaker.ErrBrokenCircuit.Error(), attempts[2].Error)
+
+		dead, err := store.DeadLetters(ctx)
+		is.Nil(err)
+		is.Len(dead, 1)
+		is.Equal(3, dead[0].Attempts)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		d := webhook.NewDispatcher(webhook.NewMemoryDeliveryStore())
+
+		is := assert.New(t)
+		is.ErrorIs(d.Register(&webhook.Endpoint{ID: "orders"}), webhook.ErrEndpointInvalid)
+
+		_, err := d.Dispatch("orders", body)
+		is.ErrorIs(err, webhook.ErrEndpointNotFound)
+
+		is.Nil(d.Shutdown(ctx))
+		_, err = d.Dispatch("orders", body)
+		is.ErrorIs(err, webhook.ErrDispatcherClosed)
+	})
+}
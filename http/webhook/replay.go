@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store deduplicates the webhook ids.
+type Store interface {
+	// Seen records the id for the ttl, and reports whether the id has already
+	// been recorded.
+	Seen(ctx context.Context, id string, ttl time.Duration) (bool, error)
+
+	// Forget removes the id, so that the webhook can be received again.
+	Forget(ctx context.Context, id string) error
+}
+
+// MemoryStore is the in-memory Store, for single instance or tests.
+type MemoryStore struct {
+	Now func() time.Time
+
+	mu  sync.Mutex
+	ids map[string]time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		Now: time.Now,
+		ids: make(map[string]time.Time),
+	}
+}
+
+func (s *MemoryStore) Seen(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Now()
+	for k, exp := range s.ids {
+		if !now.Before(exp) {
+			delete(s.ids, k)
+		}
+	}
+
+	if _, ok := s.ids[id]; ok {
+		return true, nil
+	}
+	s.ids[id] = now.Add(ttl)
+
+	return false, nil
+}
+
+func (s *MemoryStore) Forget(ctx context.Context, id string) error {
+	s.mu.Lock()
+	delete(s.ids, id)
+	s.mu.Unlock()
+
+	return nil
+}
+
+type cache interface {
+	LoadOrStore(ctx context.Context, key string, value []byte, ttl time.Duration) (old []byte, loaded bool, err error)
+	LoadAndDelete(ctx context.Context, key string) (value []byte, loaded bool, err error)
+}
+
+// CacheStore is the Store backed by the cache, e.g. the redis cache.Cache
+// from dsync/cache.
+type CacheStore struct {
+	cache  cache
+	prefix string
+}
+
+var _ Store = (*CacheStore)(nil)
+
+func NewCacheStore(cache cache) *CacheStore {
+	return &CacheStore{
+		cache:  cache,
+		prefix: "webhook:",
+	}
+}
+
+func (s *CacheStore) Seen(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	_, loaded, err := s.cache.LoadOrStore(ctx, s.prefix+id, []byte("1"), ttl)
+	return loaded, err
+}
+
+func (s *CacheStore) Forget(ctx context.Context, id string) error {
+	_, _, err := s.cache.LoadAndDelete(ctx, s.prefix+id)
+	return err
+}
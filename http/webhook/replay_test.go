@@ -0,0 +1,197 @@
+package webhook_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	dcache "github.com/alextanhongpin/core/dsync/cache"
+	"github.com/alextanhongpin/core/http/webhook"
+	"github.com/alextanhongpin/core/storage/redis/redistest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifier(t *testing.T) {
+	var (
+		body   = []byte(`{"message":"hello"}`)
+		secret = []byte("supersecret12345")
+		now    = time.Now()
+	)
+
+	newRequest := func(p *webhook.Payload) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		p.SignRequest(r, secret)
+
+		return r
+	}
+
+	tests := map[string]struct {
+		store webhook.Store
+	}{
+		"memory": {webhook.NewMemoryStore()},
+		"cache":  {webhook.NewCacheStore(new(cache))},
+	}
+
+	for name, ts := range tests {
+		t.Run(name, func(t *testing.T) {
+			v := webhook.NewVerifier(secret)
+			v.Store = ts.store
+			v.Now = func() time.Time {
+				return now
+			}
+
+			p := webhook.NewPayload(body)
+			p.At = now
+
+			is := assert.New(t)
+			_, err := v.Verify(newRequest(p))
+			is.Nil(err)
+
+			_, err = v.Verify(newRequest(p))
+			is.ErrorIs(err, webhook.ErrReplayed)
+
+			p = webhook.NewPayload(body)
+			p.At = now.Add(-v.Tolerance - time.Second)
+			_, err = v.Verify(newRequest(p))
+			is.ErrorIs(err, webhook.ErrTimestampExpired)
+
+			p.At = now.Add(v.Tolerance + time.Second)
+			_, err = v.Verify(newRequest(p))
+			is.ErrorIs(err, webhook.ErrTimestampExpired)
+
+			p = webhook.NewPayload(body)
+			p.ID = ""
+			_, err = v.Verify(newRequest(p))
+			is.ErrorIs(err, webhook.ErrMissingID)
+
+			r := newRequest(webhook.NewPayload(body))
+			r.Header.Set("X-Webhook-Timestamp", "yesterday")
+			_, err = v.Verify(r)
+			is.ErrorIs(err, webhook.ErrInvalidTimestamp)
+
+			p = webhook.NewPayload(body)
+			p.At = now.Add(-v.Tolerance - time.Second)
+			r = newRequest(p)
+			r.Header.Set("X-Webhook-Signature", "invalid")
+			_, err = v.Verify(r)
+			is.ErrorIs(err, webhook.ErrInvalidSignature, "the signature is verified before the timestamp")
+		})
+	}
+
+	t.Run("handler", func(t *testing.T) {
+		v := webhook.NewVerifier(secret)
+		v.Store = webhook.NewMemoryStore()
+		h := v.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		p := webhook.NewPayload(body)
+		codes := make([]int, 2)
+		for i := range codes {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, newRequest(p))
+			codes[i] = w.Code
+		}
+
+		is := assert.New(t)
+		is.Equal([]int{http.StatusOK, http.StatusConflict}, codes)
+	})
+
+	t.Run("handler failed", func(t *testing.T) {
+		v := webhook.NewVerifier(secret)
+		v.Store = webhook.NewCacheStore(new(cache))
+
+		fail := true
+		h := v.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if fail {
+				fail = false
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+
+		p := webhook.NewPayload(body)
+		codes := make([]int, 3)
+		for i := range codes {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, newRequest(p))
+			codes[i] = w.Code
+		}
+
+		is := assert.New(t)
+		is.Equal([]int{http.StatusInternalServerError, http.StatusOK, http.StatusConflict}, codes, "the id is forgotten when the handler fails")
+	})
+
+	t.Run("no tolerance", func(t *testing.T) {
+		v := webhook.NewVerifier(secret)
+		v.Tolerance = 0
+		v.Store = webhook.NewMemoryStore()
+
+		p := webhook.NewPayload(body)
+		is := assert.New(t)
+		_, err := v.Verify(newRequest(p))
+		is.Nil(err)
+
+		_, err = v.Verify(newRequest(p))
+		is.ErrorIs(err, webhook.ErrReplayed)
+	})
+}
+
+func TestCacheStore_Redis(t *testing.T) {
+	store := webhook.NewCacheStore(dcache.New(redistest.New(t).Client()))
+	ctx := context.Background()
+
+	is := assert.New(t)
+	seen, err := store.Seen(ctx, "id", time.Minute)
+	is.Nil(err)
+	is.False(seen)
+
+	seen, err = store.Seen(ctx, "id", time.Minute)
+	is.Nil(err)
+	is.True(seen)
+
+	is.Nil(store.Forget(ctx, "id"))
+	seen, err = store.Seen(ctx, "id", time.Minute)
+	is.Nil(err)
+	is.False(seen, "forgotten ids can be received again")
+
+	seen, err = store.Seen(ctx, "expired", time.Millisecond)
+	is.Nil(err)
+	is.False(seen)
+	time.Sleep(10 * time.Millisecond)
+
+	seen, err = store.Seen(ctx, "expired", time.Minute)
+	is.Nil(err)
+	is.False(seen, "the ids expire after the ttl")
+}
+
+type cache struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (c *cache) LoadOrStore(ctx context.Context, key string, value []byte, ttl time.Duration) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.data == nil {
+		c.data = make(map[string][]byte)
+	}
+	if old, ok := c.data[key]; ok {
+		return old, true, nil
+	}
+	c.data[key] = value
+
+	return value, false, nil
+}
+
+func (c *cache) LoadAndDelete(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	old, ok := c.data[key]
+	delete(c.data, key)
+
+	return old, ok, nil
+}
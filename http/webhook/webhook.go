@@ -1,6 +1,7 @@
 package webhook
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"crypto/subtle"
@@ -11,47 +12,126 @@ import (
 	"time"
 
 	"github.com/alextanhongpin/core/http/request"
+	"github.com/alextanhongpin/core/http/response"
+	"github.com/alextanhongpin/errors/causes"
+	"github.com/alextanhongpin/errors/codes"
 	"github.com/google/uuid"
 )
 
+var (
+	ErrInvalidTimestamp = causes.New(codes.BadRequest, "webhook/invalid_timestamp", "The webhook timestamp is invalid")
+	ErrMissingID        = causes.New(codes.BadRequest, "webhook/missing_id", "The webhook id is required")
+	ErrInvalidSignature = causes.New(codes.Unauthorized, "webhook/invalid_signature", "The webhook signature is invalid")
+	ErrTimestampExpired = causes.New(codes.Unauthorized, "webhook/timestamp_expired", "The webhook timestamp is outside of the tolerance")
+	ErrReplayed         = causes.New(codes.Conflict, "webhook/replayed", "The webhook has already been received")
+)
+
+// replayTTL is how long the ids are kept when there is no Tolerance.
+const replayTTL = 24 * time.Hour
+
 func Handler(h http.Handler, secrets ...[]byte) http.Handler {
+	return NewVerifier(secrets...).Handler(h)
+}
+
+// Verifier verifies the signature of the webhook, and protects against
+// replay attacks.
+// The webhooks with timestamp outside of the Tolerance are rejected, and
+// the ids are deduplicated with the Store, if set. Without the Tolerance, the
+// ids are kept for a day.
+type Verifier struct {
+	Secrets   [][]byte
+	Tolerance time.Duration
+	Store     Store
+	Now       func() time.Time
+}
+
+func NewVerifier(secrets ...[]byte) *Verifier {
+	return &Verifier{
+		Secrets:   secrets,
+		Tolerance: 5 * time.Minute,
+		Now:       time.Now,
+	}
+}
+
+// Handler rejects the invalid webhooks with the distinct error reasons.
+func (v *Verifier) Handler(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ok, err := verify(r, secrets...)
+		p, err := v.Verify(r)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			response.Error(w, err)
 
 			return
 		}
-		if !ok {
-			w.WriteHeader(http.StatusUnauthorized)
 
-			return
-		}
+		rw := response.NewResponseWriterRecorder(w)
+		h.ServeHTTP(rw, r)
 
-		h.ServeHTTP(w, r)
+		// Forget the id if the webhook is not processed, so that the retries
+		// from the sender are not rejected as replays.
+		if code := rw.StatusCode(); v.Store != nil && (code < 200 || code > 299) {
+			_ = v.Store.Forget(context.WithoutCancel(r.Context()), p.ID)
+		}
 	})
 }
 
-func verify(r *http.Request, secrets ...[]byte) (bool, error) {
+func (v *Verifier) Verify(r *http.Request) (*Payload, error) {
 	content, err := NewPayloadFromRequest(r)
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+
+	// Verify the signature first, so that the unsigned requests can not probe
+	// the tolerance.
+	if !v.verify(r, content) {
+		return nil, ErrInvalidSignature
 	}
 
+	if d := v.Now().Sub(content.At).Abs(); v.Tolerance > 0 && d > v.Tolerance {
+		return nil, ErrTimestampExpired
+	}
+
+	// Deduplicate only the verified webhooks, so that the ids can not be
+	// consumed by others.
+	if v.Store == nil {
+		return content, nil
+	}
+	if content.ID == "" {
+		return nil, ErrMissingID
+	}
+
+	// The webhooks outside of the tolerance are already rejected, so the
+	// ids only needs to be kept for the tolerance in both directions.
+	ttl := 2 * v.Tolerance
+	if ttl <= 0 {
+		ttl = replayTTL
+	}
+
+	seen, err := v.Store.Seen(r.Context(), content.ID, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if seen {
+		return nil, ErrReplayed
+	}
+
+	return content, nil
+}
+
+func (v *Verifier) verify(r *http.Request, content *Payload) bool {
 	signatures := r.Header.Values("X-Webhook-Signature")
 	for _, signature := range signatures {
 		signedContent, err := base64.URLEncoding.DecodeString(signature)
 		if err != nil {
-			return false, err
+			return false
 		}
-		for _, secret := range secrets {
+		for _, secret := range v.Secrets {
 			if content.Verify(signedContent, secret) {
-				return true, nil
+				return true
 			}
 		}
 	}
 
-	return false, nil
+	return false
 }
 
 type Payload struct {
@@ -81,7 +161,7 @@ func NewPayloadFromRequest(r *http.Request) (*Payload, error) {
 
 	nsec, err := strconv.ParseInt(ts, 10, 64)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTimestamp, err)
 	}
 
 	return &Payload{
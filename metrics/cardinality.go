@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Other is the label value used once the limit of the label is reached.
+const Other = "other"
+
+// Cardinality guards the labels of the HTTP metrics.
+var Cardinality = NewCardinalityLimiter(100)
+
+// CardinalityLimiter limits the number of unique values per label, to avoid
+// creating unbounded series. Values seen after the limit is reached are
+// replaced with Other.
+type CardinalityLimiter struct {
+	Limit   int
+	mu      sync.RWMutex
+	values  map[string]map[string]struct{}
+	dropped map[string]int64
+}
+
+func NewCardinalityLimiter(limit int) *CardinalityLimiter {
+	return &CardinalityLimiter{
+		Limit:   limit,
+		values:  make(map[string]map[string]struct{}),
+		dropped: make(map[string]int64),
+	}
+}
+
+// Value returns the value if it is already seen, or if the limit of the label
+// is not reached. Otherwise, Other is returned.
+func (c *CardinalityLimiter) Value(label, value string) string {
+	c.mu.RLock()
+	_, ok := c.values[label][value]
+	c.mu.RUnlock()
+	if ok {
+		return value
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	vs, ok := c.values[label]
+	if !ok {
+		vs = make(map[string]struct{})
+		c.values[label] = vs
+	}
+	if _, ok := vs[value]; ok {
+		return value
+	}
+	if len(vs) >= c.Limit {
+		c.drop(label)
+
+		return Other
+	}
+	vs[value] = struct{}{}
+
+	return value
+}
+
+// Dropped returns the number of values replaced with Other for the label.
+func (c *CardinalityLimiter) Dropped(label string) int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.dropped[label]
+}
+
+func (c *CardinalityLimiter) drop(label string) {
+	c.dropped[label]++
+	CardinalityDropped.WithLabelValues(label).Inc()
+}
+
+// Pattern returns the route pattern of the request, e.g. GET /users/{id}.
+// Requests that does not match any pattern, e.g. 404, are reported as Other,
+// since the URL path is unbounded.
+func (c *CardinalityLimiter) Pattern(r *http.Request) string {
+	return c.pattern("pattern", r.Pattern)
+}
+
+// Path is similar to Pattern, but without the method, e.g. /users/{id}.
+func (c *CardinalityLimiter) Path(r *http.Request) string {
+	return c.pattern("path", tail(strings.Fields(r.Pattern)))
+}
+
+func (c *CardinalityLimiter) pattern(label, pattern string) string {
+	if pattern == "" {
+		c.mu.Lock()
+		c.drop(label)
+		c.mu.Unlock()
+
+		return Other
+	}
+
+	return c.Value(label, pattern)
+}
@@ -0,0 +1,69 @@
+package metrics_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alextanhongpin/core/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCardinalityLimiter(t *testing.T) {
+	c := metrics.NewCardinalityLimiter(2)
+
+	is := assert.New(t)
+	is.Equal("GET", c.Value("method", "GET"))
+	is.Equal("POST", c.Value("method", "POST"))
+	is.Equal(metrics.Other, c.Value("method", "FOO"))
+	is.Equal(metrics.Other, c.Value("method", "BAR"))
+
+	// Values seen before the limit are kept.
+	is.Equal("GET", c.Value("method", "GET"))
+	is.Equal(int64(2), c.Dropped("method"))
+
+	// The limit is per label.
+	is.Equal("FOO", c.Value("other_label", "FOO"))
+	is.Equal(int64(0), c.Dropped("other_label"))
+}
+
+func TestCardinalityLimiter_Pattern(t *testing.T) {
+	c := metrics.NewCardinalityLimiter(10)
+
+	var pattern, path string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		pattern = c.Pattern(r)
+		path = c.Path(r)
+	})
+
+	is := assert.New(t)
+	for i := range 3 {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d", i), nil))
+		is.Equal("GET /users/{id}", pattern)
+		is.Equal("/users/{id}", path)
+	}
+
+	// Unmatched requests are collapsed.
+	r := httptest.NewRequest(http.MethodGet, "/unknown", nil)
+	is.Equal(metrics.Other, c.Pattern(r))
+	is.Equal(metrics.Other, c.Path(r))
+	is.Equal(int64(1), c.Dropped("pattern"))
+	is.Equal(int64(1), c.Dropped("path"))
+}
+
+func TestCardinalityDropped(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(metrics.CardinalityDropped)
+
+	c := metrics.NewCardinalityLimiter(1)
+	c.Value("dropped_label", "a")
+	c.Value("dropped_label", "b")
+	c.Value("dropped_label", "c")
+
+	is := assert.New(t)
+	is.Equal(2.0, testutil.ToFloat64(metrics.CardinalityDropped.WithLabelValues("dropped_label")))
+}
@@ -1,20 +1,41 @@
 package metrics
 
 import (
+	"cmp"
 	"context"
+	"embed"
+	"encoding/json"
 	"errors"
 	"expvar"
 	"fmt"
 	"log/slog"
+	"math"
+	"mime"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/alextanhongpin/core/dsync/probs"
-	"github.com/alextanhongpin/core/http/httputil"
+	"github.com/alextanhongpin/core/dsync/probs/inmem"
+	"github.com/alextanhongpin/core/http/response"
+	"github.com/alextanhongpin/core/http/templ"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
 	redis "github.com/redis/go-redis/v9"
+	"golang.org/x/sync/errgroup"
 )
 
+//go:embed templates/*.html
+var templates embed.FS
+
+var dashboard = (&templ.HTMLTemplate{
+	FS:       templates,
+	BasePath: "templates",
+}).Compile("dashboard.html")
+
 var (
 	StatusTotal   = expvar.NewMap("status_total")
 	RequestsTotal = expvar.NewMap("requests_total")
@@ -26,10 +47,10 @@ var (
 // mux.Handle("GET /debug/vars", expvar.Handler())
 func CounterHandler(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		wr := httputil.NewResponseWriterRecorder(w)
+		wr := response.NewResponseWriterRecorder(w)
 		h.ServeHTTP(wr, r)
 
-		path := fmt.Sprintf("%s - %d", r.Pattern, wr.StatusCode())
+		path := fmt.Sprintf("%s - %d", Cardinality.Pattern(r), wr.StatusCode())
 		RequestsTotal.Add("ALL", 1)
 		RequestsTotal.Add(path, 1)
 		StatusTotal.Add(fmt.Sprint(wr.StatusCode()), 1)
@@ -39,10 +60,10 @@ func CounterHandler(h http.Handler) http.Handler {
 func TrackerHandler(h http.Handler, tracker *Tracker, userFn func(r *http.Request) string, logger *slog.Logger) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		wr := httputil.NewResponseWriterRecorder(w)
+		wr := response.NewResponseWriterRecorder(w)
 		h.ServeHTTP(wr, r)
 
-		path := fmt.Sprintf("%s - %d", r.Pattern, wr.StatusCode())
+		path := fmt.Sprintf("%s - %d", Cardinality.Pattern(r), wr.StatusCode())
 		user := userFn(r)
 		took := time.Since(start)
 		err := tracker.Record(r.Context(), path, user, took)
@@ -52,118 +73,472 @@ func TrackerHandler(h http.Handler, tracker *Tracker, userFn func(r *http.Reques
 	})
 }
 
+// TrackerStatsHandler serves the tracker stats. The format is negotiated
+// through the Accept header:
+//   - application/json
+//   - application/openmetrics-text, text/plain;version=0.0.4 (Prometheus)
+//   - text/html (dashboard)
+//   - text/plain (default)
+//
+// The following query parameters are supported:
+//   - at: the date (2006-01-02) or time (RFC3339) of the bucket
+//   - from, to: the range of the buckets, inclusive
+//   - range: the trailing duration, e.g. 24h
+//   - path: only include paths containing the value
+//   - limit: the max number of paths, up to 100, defaults to the Tracker.TopK
+//
+// The range is bounded by the Tracker.MaxBuckets.
 func TrackerStatsHandler(tracker *Tracker) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		now := time.Now()
-		if at := r.URL.Query().Get("at"); at != "" {
-			t, err := time.Parse(time.DateOnly, at)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
+		q, err := parseStatsQuery(r, tracker.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 
-				return
-			}
-			now = t
+			return
 		}
 
-		var sb strings.Builder
 		ctx := r.Context()
-		stats, err := tracker.Stats(ctx, now)
+		stats, err := tracker.statsRange(ctx, q.From, q.To, q.match(), cmp.Or(q.Limit, tracker.TopK))
+		if errors.Is(err, ErrRangeTooLarge) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 
 			return
 		}
-		for _, stat := range stats {
-			_, err = sb.WriteString(fmt.Sprintf("at: %s\n%s\n\n", now, stat.String()))
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
 
-				return
+		switch negotiate(r.Header.Get("Accept")) {
+		case mediaJSON:
+			err = response.OK(w, &statsResponse{
+				From:  q.From,
+				To:    q.To,
+				Stats: stats,
+			})
+		case mediaMetrics:
+			err = writeOpenMetrics(w, r, tracker.Name, stats)
+		case mediaHTML:
+			err = templ.Render(w, dashboard, http.StatusOK, "", &statsResponse{
+				Name:  tracker.Name,
+				From:  q.From,
+				To:    q.To,
+				Stats: stats,
+			})
+		default:
+			var sb strings.Builder
+			for _, stat := range stats {
+				sb.WriteString(fmt.Sprintf("at: %s\n%s\n\n", q.From, stat.String()))
 			}
+			_, err = fmt.Fprint(w, sb.String())
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
+	})
+}
+
+// The media types of the stats. The plain text summary is the default.
+const (
+	mediaJSON    = "application/json"
+	mediaHTML    = "text/html"
+	mediaMetrics = expfmt.OpenMetricsType
+	mediaText    = "text/plain"
+)
 
-		fmt.Fprint(w, sb.String())
+// negotiate returns the media type of the stats for the Accept header, by the
+// order of the quality values. The prometheus text format, i.e. text/plain
+// with version 0.0.4, is served as metrics. The exact metrics format is then
+// negotiated by expfmt, see writeOpenMetrics.
+func negotiate(accept string) string {
+	type mediaRange struct {
+		typ    string
+		params map[string]string
+		q      float64
+	}
+
+	var ranges []mediaRange
+	for _, s := range strings.Split(accept, ",") {
+		typ, params, err := mime.ParseMediaType(s)
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q <= 0 {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{typ: typ, params: params, q: q})
+	}
+	slices.SortStableFunc(ranges, func(a, b mediaRange) int {
+		return cmp.Compare(b.q, a.q)
 	})
+
+	for _, r := range ranges {
+		switch {
+		case r.typ == mediaJSON, r.typ == mediaHTML, r.typ == mediaMetrics:
+			return r.typ
+		case r.typ == mediaText && r.params["version"] == expfmt.TextVersion:
+			return mediaMetrics
+		}
+	}
+
+	return mediaText
+}
+
+type statsResponse struct {
+	Name  string    `json:"-"`
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+	Stats []Stats   `json:"stats"`
+}
+
+// maxLimit is the max number of paths per query.
+const maxLimit = 100
+
+type statsQuery struct {
+	From  time.Time
+	To    time.Time
+	Path  string
+	Limit int
+}
+
+func parseStatsQuery(r *http.Request, now time.Time) (*statsQuery, error) {
+	v := r.URL.Query()
+	q := &statsQuery{
+		From: now,
+		To:   now,
+		Path: v.Get("path"),
+	}
+
+	if s := v.Get("at"); s != "" {
+		t, err := parseTime(s)
+		if err != nil {
+			return nil, err
+		}
+		q.From, q.To = t, t
+	}
+
+	if s := v.Get("range"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, err
+		}
+		q.From = now.Add(-d)
+	}
+
+	if s := v.Get("from"); s != "" {
+		t, err := parseTime(s)
+		if err != nil {
+			return nil, err
+		}
+		q.From = t
+	}
+
+	if s := v.Get("to"); s != "" {
+		t, err := parseTime(s)
+		if err != nil {
+			return nil, err
+		}
+		q.To = t
+	}
+
+	if q.From.After(q.To) {
+		return nil, errors.New("metrics: from must be before to")
+	}
+
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		if n < 1 || n > maxLimit {
+			return nil, fmt.Errorf("metrics: limit must be between 1 and %d", maxLimit)
+		}
+		q.Limit = n
+	}
+
+	return q, nil
+}
+
+// match returns the path filter, which is applied before the limit, so that
+// the paths outside of the top paths can be queried.
+func (q *statsQuery) match() func(path string) bool {
+	if q.Path == "" {
+		return nil
+	}
+
+	return func(path string) bool {
+		return strings.Contains(path, q.Path)
+	}
+}
+
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, s)
+}
+
+// writeOpenMetrics writes the stats in the Prometheus or OpenMetrics text
+// format, depending on the Accept header.
+func writeOpenMetrics(w http.ResponseWriter, r *http.Request, name string, stats []Stats) error {
+	labels := prometheus.Labels{"tracker": name}
+	total := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:        "tracker_requests",
+		Help:        "The number of requests within the range.",
+		ConstLabels: labels,
+	}, []string{"path"})
+	unique := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:        "tracker_unique_users",
+		Help:        "The number of unique users within the range.",
+		ConstLabels: labels,
+	}, []string{"path"})
+	latency := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:        "tracker_latency_seconds",
+		Help:        "The latency percentiles within the range.",
+		ConstLabels: labels,
+	}, []string{"path", "quantile"})
+
+	for _, s := range stats {
+		total.WithLabelValues(s.Path).Set(float64(s.Total))
+		unique.WithLabelValues(s.Path).Set(float64(s.Unique))
+		for _, p := range s.Percentiles {
+			latency.WithLabelValues(s.Path, strconv.FormatFloat(p.P, 'f', -1, 64)).Set(p.Value)
+		}
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(total, unique, latency)
+	mfs, err := reg.Gather()
+	if err != nil {
+		return err
+	}
+
+	format := expfmt.NegotiateIncludingOpenMetrics(r.Header)
+	w.Header().Set("Content-Type", string(format))
+
+	enc := expfmt.NewEncoder(w, format)
+	for _, mf := range mfs {
+		if err := enc.Encode(mf); err != nil {
+			return err
+		}
+	}
+	if closer, ok := enc.(expfmt.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// Granularity is the size of the time buckets used by the Tracker.
+// The buckets are in UTC, so that the times in different locations, or across
+// the daylight saving transitions, map to the same buckets.
+type Granularity int
+
+const (
+	Daily Granularity = iota
+	Hourly
+)
+
+func (g Granularity) format(t time.Time) string {
+	t = t.UTC()
+	if g == Hourly {
+		return t.Format("2006-01-02T15")
+	}
+
+	return t.Format(time.DateOnly)
+}
+
+// truncate returns the start of the bucket of the time in UTC.
+func (g Granularity) truncate(t time.Time) time.Time {
+	t = t.UTC()
+	if g == Hourly {
+		return t.Truncate(time.Hour)
+	}
+
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// count returns the number of buckets between from and to, inclusive.
+func (g Granularity) count(from, to time.Time) int {
+	size := 24 * time.Hour
+	if g == Hourly {
+		size = time.Hour
+	}
+
+	return int(g.truncate(to).Sub(g.truncate(from))/size) + 1
 }
 
+// buckets returns the bucket keys between from and to, inclusive.
+func (g Granularity) buckets(from, to time.Time) []string {
+	var res []string
+	for t := g.truncate(from); !t.After(to); t = g.next(t) {
+		res = append(res, g.format(t))
+	}
+
+	return res
+}
+
+func (g Granularity) next(t time.Time) time.Time {
+	if g == Hourly {
+		return t.Add(time.Hour)
+	}
+
+	return t.AddDate(0, 0, 1)
+}
+
+// ErrRangeTooLarge is returned when the range spans more than the
+// Tracker.MaxBuckets.
+var ErrRangeTooLarge = errors.New("metrics: range exceeds the max buckets")
+
 type Tracker struct {
-	Name string
-	Now  func() time.Time
-	cms  *probs.CountMinSketch // Track frequency of API calls.
-	hll  *probs.HyperLogLog    // Track unique page views by user.
-	td   *probs.TDigest        // Track API latency.
-	topK *probs.TopK           // Track top-10 requests.
+	Name        string
+	Now         func() time.Time
+	Granularity Granularity
+	Percentiles []float64 // e.g. 0.5, 0.9, 0.99, 0.999
+	TopK        int
+	MaxBuckets  int                       // The max buckets per range query.
+	cms         probs.CountMinSketchStore // Track frequency of API calls.
+	hll         probs.HyperLogLogStore    // Track unique page views by user.
+	td          probs.TDigestStore        // Track API latency.
+	topK        probs.TopKStore           // Track top-10 requests.
+}
+
+// Backend holds the probabilistic data structures used by the Tracker.
+type Backend struct {
+	CountMinSketch probs.CountMinSketchStore
+	HyperLogLog    probs.HyperLogLogStore
+	TDigest        probs.TDigestStore
+	TopK           probs.TopKStore
+}
+
+// RedisBackend returns the redis-backed data structures.
+func RedisBackend(client *redis.Client) Backend {
+	return Backend{
+		CountMinSketch: probs.NewCountMinSketch(client),
+		HyperLogLog:    probs.NewHyperLogLog(client),
+		TDigest:        probs.NewTDigest(client),
+		TopK:           probs.NewTopK(client),
+	}
+}
+
+// InMemoryBackend returns the in-memory data structures, which does not
+// require Redis Stack.
+func InMemoryBackend() Backend {
+	return Backend{
+		CountMinSketch: inmem.NewCountMinSketch(),
+		HyperLogLog:    inmem.NewHyperLogLog(),
+		TDigest:        inmem.NewTDigest(),
+		TopK:           inmem.NewTopK(),
+	}
 }
 
 func NewTracker(name string, client *redis.Client) *Tracker {
+	return NewTrackerWithBackend(name, RedisBackend(client))
+}
+
+func NewTrackerWithBackend(name string, b Backend) *Tracker {
 	return &Tracker{
-		Name: name,
-		Now:  time.Now,
-		cms:  probs.NewCountMinSketch(client),
-		hll:  probs.NewHyperLogLog(client),
-		td:   probs.NewTDigest(client),
-		topK: probs.NewTopK(client),
+		Name:        name,
+		Now:         time.Now,
+		Granularity: Daily,
+		Percentiles: []float64{0.5, 0.9, 0.95},
+		TopK:        10,
+		MaxBuckets:  168,
+		cms:         b.CountMinSketch,
+		hll:         b.HyperLogLog,
+		td:          b.TDigest,
+		topK:        b.TopK,
 	}
 }
 
 func (t *Tracker) Record(ctx context.Context, path, userID string, duration time.Duration) error {
-	day := t.Now().Format(time.DateOnly)
+	bucket := t.Granularity.format(t.Now())
 	key := t.Name
 
 	return errors.Join(
-		// We calculate the all-time rank.
-		t.rank(ctx, join(key, "top_k"), path),
-		t.countOccurences(ctx, join(key, "cms", day), path),
-		t.countUnique(ctx, join(key, "hll", day, path), userID),
-		t.recordLatency(ctx, join(key, "td", day, path), duration),
+		t.rank(ctx, join(key, "top_k", bucket), path),
+		t.countOccurences(ctx, join(key, "cms", bucket), path),
+		t.countUnique(ctx, join(key, "hll", bucket, path), userID),
+		t.recordLatency(ctx, join(key, "td", bucket, path), duration),
 	)
 }
 
+// Stats returns the stats of the bucket at the given time.
 func (t *Tracker) Stats(ctx context.Context, at time.Time) ([]Stats, error) {
+	return t.StatsRange(ctx, at, at)
+}
+
+// StatsRange returns the stats of the top paths between from and to,
+// inclusive. The buckets within the range are merged.
+// Returns ErrRangeTooLarge if the range spans more than MaxBuckets.
+func (t *Tracker) StatsRange(ctx context.Context, from, to time.Time) ([]Stats, error) {
+	return t.statsRange(ctx, from, to, nil, t.TopK)
+}
+
+// statsRange returns the stats of the top paths that matches, up to the limit.
+// All paths matches if match is nil.
+func (t *Tracker) statsRange(ctx context.Context, from, to time.Time, match func(path string) bool, limit int) ([]Stats, error) {
+	if n := t.Granularity.count(from, to); t.MaxBuckets > 0 && n > t.MaxBuckets {
+		return nil, fmt.Errorf("%w: %d > %d", ErrRangeTooLarge, n, t.MaxBuckets)
+	}
+
 	key := t.Name
-	day := at.Format(time.DateOnly)
-	paths, err := t.rankings(ctx, join(key, "top_k"))
+	buckets := t.Granularity.buckets(from, to)
+	paths, err := t.rankings(ctx, prefix(join(key, "top_k"), buckets), match, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	totals, err := t.totalOccurences(ctx, prefix(join(key, "cms"), buckets), paths)
 	if err != nil {
 		return nil, err
 	}
 
 	stats := make([]Stats, len(paths))
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
 	for i, path := range paths {
-		occurences, err := t.totalOccurences(ctx, join(key, "cms", day), path)
-		if err != nil {
-			return nil, err
-		}
+		g.Go(func() error {
+			unique, err := t.totalUnique(ctx, suffix(prefix(join(key, "hll"), buckets), path))
+			if err != nil {
+				return err
+			}
 
-		unique, err := t.totalUnique(ctx, join(key, "hll", day, path))
-		if err != nil {
-			return nil, err
-		}
+			vals, err := t.latency(ctx, suffix(prefix(join(key, "td"), buckets), path))
+			if err != nil {
+				return err
+			}
 
-		vals, err := t.latency(ctx, join(key, "td", day, path))
-		if err != nil {
-			return nil, err
-		}
+			stats[i] = newStats(path, t.Percentiles, vals)
+			stats[i].Total = totals[i]
+			stats[i].Unique = unique
 
-		stats[i] = Stats{
-			Path:   path,
-			P50:    vals[0],
-			P90:    vals[1],
-			P95:    vals[2],
-			Total:  occurences,
-			Unique: unique,
-		}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
 	}
 
 	return stats, nil
 }
 
-func (t *Tracker) recordLatency(ctx context.Context, path string, duration time.Duration) error {
-	_, err := t.td.Add(ctx, path, duration.Seconds())
+func (t *Tracker) recordLatency(ctx context.Context, key string, duration time.Duration) error {
+	_, err := t.td.Add(ctx, key, duration.Seconds())
 	return err
 }
 
-func (t *Tracker) latency(ctx context.Context, path string) ([]float64, error) {
-	return t.td.Quantile(ctx, path, 0.5, 0.9, 0.95)
+// latency returns the percentiles of the merged keys. The merged t-digest is
+// not kept.
+func (t *Tracker) latency(ctx context.Context, keys []string) ([]float64, error) {
+	return t.td.MergeQuantile(ctx, keys, t.Percentiles...)
 }
 
 func (t *Tracker) countOccurences(ctx context.Context, key, path string) error {
@@ -173,13 +548,44 @@ func (t *Tracker) countOccurences(ctx context.Context, key, path string) error {
 	return err
 }
 
-func (t *Tracker) totalOccurences(ctx context.Context, key, path string) (int64, error) {
-	counts, err := t.cms.Query(ctx, key, path)
+// totalOccurences returns the count of the paths, summed across the keys. The
+// paths are queried together, so there is one query per key.
+//
+// Summing replaces CMS.MERGE: the merged sketch adds the counters cell by
+// cell, so its estimate, the min of the summed cells, is never less than the
+// sum of the min of each key. Both never underestimate, so the sum is the
+// tighter estimate, and it does not require a temporary key.
+func (t *Tracker) totalOccurences(ctx context.Context, keys []string, paths []string) ([]int64, error) {
+	totals := make([]int64, len(paths))
+	if len(paths) == 0 {
+		return totals, nil
+	}
+
+	values := make([]any, len(paths))
+	for i, path := range paths {
+		values[i] = path
+	}
+
+	var mu sync.Mutex
+	err := eachKey(ctx, keys, func(ctx context.Context, key string) error {
+		counts, err := t.cms.Query(ctx, key, values...)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		for i, n := range counts {
+			totals[i] += n
+		}
+		mu.Unlock()
+
+		return nil
+	})
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	return counts[0], nil
+	return totals, nil
 }
 
 func (t *Tracker) countUnique(ctx context.Context, key, userID string) error {
@@ -187,8 +593,9 @@ func (t *Tracker) countUnique(ctx context.Context, key, userID string) error {
 	return err
 }
 
-func (t *Tracker) totalUnique(ctx context.Context, key string) (int64, error) {
-	return t.hll.Count(ctx, key)
+// totalUnique returns the cardinality of the union of the keys.
+func (t *Tracker) totalUnique(ctx context.Context, keys []string) (int64, error) {
+	return t.hll.Count(ctx, keys...)
 }
 
 func (t *Tracker) rank(ctx context.Context, key, path string) error {
@@ -196,29 +603,140 @@ func (t *Tracker) rank(ctx context.Context, key, path string) error {
 	return err
 }
 
-func (t *Tracker) rankings(ctx context.Context, key string) ([]string, error) {
-	return t.topK.List(ctx, key)
+// rankings returns the top paths that matches by summing the counts of each
+// key, since top-k cannot be merged.
+func (t *Tracker) rankings(ctx context.Context, keys []string, match func(path string) bool, limit int) ([]string, error) {
+	var mu sync.Mutex
+	counts := make(map[string]int64)
+	err := eachKey(ctx, keys, func(ctx context.Context, key string) error {
+		kvs, err := t.topK.ListWithCount(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		for path, n := range kvs {
+			counts[path] += n
+		}
+		mu.Unlock()
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(counts))
+	for path := range counts {
+		if match == nil || match(path) {
+			paths = append(paths, path)
+		}
+	}
+	slices.SortFunc(paths, func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), cmp.Compare(a, b))
+	})
+
+	return paths[:min(limit, len(paths))], nil
+}
+
+// concurrency is the max concurrent queries per range query.
+const concurrency = 8
+
+// eachKey calls fn for each key concurrently, since the keys of the buckets
+// are independent. The keys that does not exist are skipped.
+func eachKey(ctx context.Context, keys []string, fn func(ctx context.Context, key string) error) error {
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for _, key := range keys {
+		g.Go(func() error {
+			err := fn(ctx, key)
+			if probs.KeyDoesNotExistError(err) {
+				return nil
+			}
+
+			return err
+		})
+	}
+
+	return g.Wait()
+}
+
+type Percentile struct {
+	P     float64 `json:"p"`     // e.g. 0.99
+	Value float64 `json:"value"` // In seconds.
+}
+
+// MarshalJSON encodes the missing value, e.g. from the empty t-digest, as
+// null, since JSON does not support NaN.
+func (p Percentile) MarshalJSON() ([]byte, error) {
+	var value *float64
+	if !math.IsNaN(p.Value) && !math.IsInf(p.Value, 0) {
+		value = &p.Value
+	}
+
+	return json.Marshal(struct {
+		P     float64  `json:"p"`
+		Value *float64 `json:"value"`
+	}{p.P, value})
+}
+
+func (p Percentile) String() string {
+	return "p" + strconv.FormatFloat(p.P*100, 'f', -1, 64)
 }
 
 type Stats struct {
-	Path   string
-	P50    float64
-	P90    float64
-	P95    float64
-	Unique int64
-	Total  int64
+	Path        string       `json:"path"`
+	Percentiles []Percentile `json:"percentiles"`
+	Unique      int64        `json:"unique"`
+	Total       int64        `json:"total"`
+
+	// P50, P90 and P95 are set when the Tracker.Percentiles includes them,
+	// which is the default.
+	P50 float64 `json:"-"`
+	P90 float64 `json:"-"`
+	P95 float64 `json:"-"`
+}
+
+func newStats(path string, ps, vals []float64) Stats {
+	s := Stats{
+		Path:        path,
+		Percentiles: make([]Percentile, len(vals)),
+	}
+	for i, v := range vals {
+		s.Percentiles[i] = Percentile{
+			P:     ps[i],
+			Value: v,
+		}
+
+		switch ps[i] {
+		case 0.5:
+			s.P50 = v
+		case 0.9:
+			s.P90 = v
+		case 0.95:
+			s.P95 = v
+		}
+	}
+
+	return s
 }
 
 func (s *Stats) String() string {
+	names := make([]string, len(s.Percentiles))
+	values := make([]string, len(s.Percentiles))
+	for i, p := range s.Percentiles {
+		names[i] = p.String()
+		values[i] = seconds(p.Value).String()
+	}
+
 	return fmt.Sprintf(`%s
 unique/total: %d/%d
-p50/p90/p95 (in seconds): %v, %s, %s`,
+%s (in seconds): %s`,
 		s.Path,
 		s.Unique,
 		s.Total,
-		seconds(s.P50),
-		seconds(s.P90),
-		seconds(s.P95),
+		strings.Join(names, "/"),
+		strings.Join(values, ", "),
 	)
 }
 
@@ -232,6 +750,24 @@ func join(s ...string) string {
 	return strings.Join(s, ":")
 }
 
+func prefix(p string, s []string) []string {
+	res := make([]string, len(s))
+	for i, v := range s {
+		res[i] = join(p, v)
+	}
+
+	return res
+}
+
+func suffix(s []string, p string) []string {
+	res := make([]string, len(s))
+	for i, v := range s {
+		res[i] = join(v, p)
+	}
+
+	return res
+}
+
 func seconds(f float64) time.Duration {
 	return time.Duration(f * float64(time.Second))
 }
@@ -2,14 +2,17 @@ package metrics_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"math/rand/v2"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -43,6 +46,116 @@ func TestTracker(t *testing.T) {
 	}
 }
 
+func TestTracker_InMemory(t *testing.T) {
+	tracker := metrics.NewTrackerWithBackend(t.Name(), metrics.InMemoryBackend())
+	ctx := context.Background()
+
+	is := assert.New(t)
+	for i := range 100 {
+		userID := strconv.Itoa(i % 10)
+		is.Nil(tracker.Record(ctx, "GET /foo", userID, time.Second))
+		if i%2 == 0 {
+			is.Nil(tracker.Record(ctx, "GET /bar", userID, 2*time.Second))
+		}
+	}
+	stats, err := tracker.Stats(ctx, time.Now())
+	is.Nil(err)
+	is.Len(stats, 2)
+	is.Equal("GET /foo", stats[0].Path)
+	is.Equal(int64(100), stats[0].Total)
+	is.Equal(int64(10), stats[0].Unique)
+	is.Equal(metrics.Percentile{P: 0.5, Value: 1}, stats[0].Percentiles[0])
+	is.Equal(1.0, stats[0].P50)
+	is.Equal("GET /bar", stats[1].Path)
+	is.Equal(int64(50), stats[1].Total)
+	is.Equal(int64(5), stats[1].Unique)
+	is.Equal(metrics.Percentile{P: 0.95, Value: 2}, stats[1].Percentiles[2])
+}
+
+func TestTracker_StatsRange(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tracker := metrics.NewTrackerWithBackend(t.Name(), metrics.InMemoryBackend())
+	tracker.Granularity = metrics.Hourly
+	tracker.Percentiles = []float64{0.5, 0.99, 0.999}
+	tracker.TopK = 1
+	tracker.Now = func() time.Time {
+		return now
+	}
+	ctx := context.Background()
+
+	is := assert.New(t)
+	for i := range 100 {
+		is.Nil(tracker.Record(ctx, "GET /foo", strconv.Itoa(i%10), time.Duration(i+1)*time.Millisecond))
+	}
+
+	now = now.Add(time.Hour)
+	for i := range 200 {
+		is.Nil(tracker.Record(ctx, "GET /bar", strconv.Itoa(i%20), time.Second))
+		is.Nil(tracker.Record(ctx, "GET /foo", strconv.Itoa(i%20), time.Second))
+	}
+
+	// The top-k is scoped to the window.
+	stats, err := tracker.Stats(ctx, now.Add(-time.Hour))
+	is.Nil(err)
+	is.Len(stats, 1)
+	is.Equal("GET /foo", stats[0].Path)
+	is.Equal(int64(100), stats[0].Total)
+	is.Equal(int64(10), stats[0].Unique)
+	is.InDelta(0.05, stats[0].Percentiles[0].Value, 0.001)
+	is.Equal("p99.9", stats[0].Percentiles[2].String())
+
+	stats, err = tracker.StatsRange(ctx, now.Add(-time.Hour), now)
+	is.Nil(err)
+	is.Len(stats, 1)
+	is.Equal("GET /foo", stats[0].Path)
+	is.Equal(int64(300), stats[0].Total)
+	is.Equal(int64(20), stats[0].Unique)
+	is.Equal(1.0, stats[0].Percentiles[1].Value)
+
+	// Querying the range again gives the same result.
+	again, err := tracker.StatsRange(ctx, now.Add(-time.Hour), now)
+	is.Nil(err)
+	is.Equal(stats, again)
+
+	tracker.MaxBuckets = 24
+	_, err = tracker.StatsRange(ctx, now.Add(-24*time.Hour), now)
+	is.ErrorIs(err, metrics.ErrRangeTooLarge)
+}
+
+func TestTracker_StatsRangeLocation(t *testing.T) {
+	// The offset is not a whole hour, and the date differs from UTC.
+	ist := time.FixedZone("IST", 5*60*60+30*60)
+
+	for _, g := range []metrics.Granularity{metrics.Daily, metrics.Hourly} {
+		now := time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)
+
+		tracker := metrics.NewTrackerWithBackend(t.Name(), metrics.InMemoryBackend())
+		tracker.Granularity = g
+		tracker.Now = func() time.Time {
+			return now
+		}
+		ctx := context.Background()
+
+		is := assert.New(t)
+		for i := range 10 {
+			is.Nil(tracker.Record(ctx, "GET /foo", strconv.Itoa(i), time.Second))
+		}
+
+		// Recorded in another location, across the date boundary in UTC.
+		now = now.Add(4 * time.Hour).In(ist)
+		for i := range 20 {
+			is.Nil(tracker.Record(ctx, "GET /foo", strconv.Itoa(i), time.Second))
+		}
+
+		from := time.Date(2024, 5, 2, 1, 30, 0, 0, ist)
+		stats, err := tracker.StatsRange(ctx, from, now)
+		is.Nil(err)
+		is.Len(stats, 1)
+		is.Equal(int64(30), stats[0].Total)
+	}
+}
+
 func TestTrackerHandler(t *testing.T) {
 	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprint(w, "hello world")
@@ -80,6 +193,164 @@ func TestTrackerHandler(t *testing.T) {
 	}
 }
 
+func TestTrackerHandler_Cardinality(t *testing.T) {
+	tracker := metrics.NewTrackerWithBackend(t.Name(), metrics.InMemoryBackend())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	h := metrics.TrackerHandler(mux, tracker, func(r *http.Request) string {
+		return "user-id"
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for i := range 3 {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d", i), nil))
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown/1", nil))
+
+	stats, err := tracker.Stats(context.Background(), time.Now())
+	is := assert.New(t)
+	is.Nil(err)
+	is.Len(stats, 2)
+	is.Equal("GET /users/{id} - 200", stats[0].Path)
+	is.Equal(int64(3), stats[0].Total)
+	is.Equal(metrics.Other+" - 404", stats[1].Path, "the unmatched paths are collapsed")
+}
+
+func TestTrackerStatsHandler(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tracker := metrics.NewTrackerWithBackend("api", metrics.InMemoryBackend())
+	tracker.Percentiles = []float64{0.5, 0.99}
+	tracker.Now = func() time.Time {
+		return now
+	}
+
+	ctx := context.Background()
+	is := assert.New(t)
+	for i := range 10 {
+		is.Nil(tracker.Record(ctx, "GET /foo", strconv.Itoa(i), time.Second))
+		if i%2 == 0 {
+			is.Nil(tracker.Record(ctx, "GET /bar", strconv.Itoa(i), time.Second))
+		}
+	}
+
+	serve := func(target, accept string) *http.Response {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r.Header.Set("Accept", accept)
+		metrics.TrackerStatsHandler(tracker).ServeHTTP(w, r)
+
+		return w.Result()
+	}
+
+	t.Run("json", func(t *testing.T) {
+		res := serve("/?limit=1", "application/json")
+		is := assert.New(t)
+		is.Equal(http.StatusOK, res.StatusCode)
+
+		b, err := io.ReadAll(res.Body)
+		is.Nil(err)
+		is.JSONEq(`{
+			"data": {
+				"from": "2024-05-01T10:00:00Z",
+				"to": "2024-05-01T10:00:00Z",
+				"stats": [{
+					"path": "GET /foo",
+					"percentiles": [{"p": 0.5, "value": 1}, {"p": 0.99, "value": 1}],
+					"unique": 10,
+					"total": 10
+				}]
+			}
+		}`, string(b))
+	})
+
+	t.Run("negotiate", func(t *testing.T) {
+		tests := map[string]string{
+			"text/html;q=0.5, application/json":   "application/json",
+			"application/json;q=0.5, text/html":   "text/html",
+			"application/jsonl":                   "text/plain",
+			"application/json;q=0, text/plain":    "text/plain",
+			"application/openmetrics-text; q=0.9": "application/openmetrics-text",
+			"text/plain;version=0.0.4":            "text/plain; version=0.0.4",
+		}
+		for accept, want := range tests {
+			res := serve("/", accept)
+			assert.True(t, strings.HasPrefix(res.Header.Get("Content-Type"), want), accept)
+		}
+	})
+
+	t.Run("openmetrics", func(t *testing.T) {
+		res := serve("/?path=bar", "text/plain; version=0.0.4")
+		is := assert.New(t)
+		is.Equal(http.StatusOK, res.StatusCode)
+
+		b, err := io.ReadAll(res.Body)
+		is.Nil(err)
+		want := `# HELP tracker_latency_seconds The latency percentiles within the range.
+# TYPE tracker_latency_seconds gauge
+tracker_latency_seconds{path="GET /bar",quantile="0.5",tracker="api"} 1
+tracker_latency_seconds{path="GET /bar",quantile="0.99",tracker="api"} 1
+# HELP tracker_requests The number of requests within the range.
+# TYPE tracker_requests gauge
+tracker_requests{path="GET /bar",tracker="api"} 5
+# HELP tracker_unique_users The number of unique users within the range.
+# TYPE tracker_unique_users gauge
+tracker_unique_users{path="GET /bar",tracker="api"} 5
+`
+		is.Equal(want, string(b))
+	})
+
+	t.Run("html", func(t *testing.T) {
+		res := serve("/?range=1h", "text/html")
+		is := assert.New(t)
+		is.Equal(http.StatusOK, res.StatusCode)
+
+		b, err := io.ReadAll(res.Body)
+		is.Nil(err)
+		is.Contains(string(b), "<td>GET /foo</td>")
+		is.Contains(string(b), "<th>p99 (s)</th>")
+	})
+
+	t.Run("path outside of top k", func(t *testing.T) {
+		tracker.TopK = 1
+		t.Cleanup(func() {
+			tracker.TopK = 10
+		})
+
+		res := serve("/?path=bar", "application/json")
+		is := assert.New(t)
+		is.Equal(http.StatusOK, res.StatusCode)
+
+		b, err := io.ReadAll(res.Body)
+		is.Nil(err)
+		is.Contains(string(b), `"path":"GET /bar"`)
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		for _, target := range []string{
+			"/?from=2024-05-02&to=2024-05-01",
+			"/?range=8760h",
+			"/?limit=0",
+			"/?limit=1000",
+		} {
+			res := serve(target, "application/json")
+			is := assert.New(t)
+			is.Equal(http.StatusBadRequest, res.StatusCode, target)
+		}
+	})
+}
+
+func TestPercentile_MarshalJSON(t *testing.T) {
+	b, err := json.Marshal([]metrics.Percentile{
+		{P: 0.5, Value: 1},
+		{P: 0.9, Value: math.NaN()},
+	})
+
+	is := assert.New(t)
+	is.Nil(err)
+	is.JSONEq(`[{"p": 0.5, "value": 1}, {"p": 0.9, "value": null}]`, string(b))
+}
+
 func randDuration(duration time.Duration) time.Duration {
 	return time.Duration(rand.Int64N(duration.Milliseconds())) * time.Millisecond
 }
@@ -1,12 +1,12 @@
 package metrics
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-	"strings"
 	"time"
 
-	"github.com/alextanhongpin/core/http/httputil"
+	"github.com/alextanhongpin/core/http/response"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -16,7 +16,7 @@ import (
 	// Install the default prometheus collectors.
 	reg.MustRegister(collectors.NewGoCollector())
 	// Install the custom metrics.
-	reg.MustRegister(metrics.InFlightGauge, metrics.RequestDuration, metrics.ResponseSize)
+	reg.MustRegister(metrics.InFlightGauge, metrics.RequestDuration, metrics.ResponseSize, metrics.CardinalityDropped)
 
 	// ...
 	metrics.InFlightGauge.Inc()
@@ -54,6 +54,16 @@ var (
 		[]string{},
 	)
 
+	// CardinalityDropped counts the label values replaced with Other by the
+	// CardinalityLimiter.
+	CardinalityDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cardinality_dropped_total",
+			Help: "A counter of label values that exceeded the cardinality limit.",
+		},
+		[]string{"label"},
+	)
+
 	RED = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "red",
@@ -81,14 +91,12 @@ func NewRED(service, action string) *REDTracker {
 		service: service,
 		action:  action,
 		status:  OK,
-		Now:     time.Now(),
+		Now:     Now(),
 	}
 }
 
 func (r *REDTracker) Done() {
-	RED.
-		WithLabelValues(r.service, r.action, r.status).
-		Observe(float64(time.Since(r.Now).Milliseconds()))
+	observeRED(context.Background(), r.service, r.action, r.status, Now().Sub(r.Now))
 }
 
 func (r *REDTracker) Fail() {
@@ -101,12 +109,11 @@ func (r *REDTracker) SetStatus(status string) {
 
 func RequestDurationHandler(version string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		wr := httputil.NewResponseWriterRecorder(w)
+		wr := response.NewResponseWriterRecorder(w)
 
 		defer func(start time.Time) {
-			// Remove prefix http method, if any.
-			method := r.Method
-			path := tail(strings.Fields(r.Pattern))
+			method := Cardinality.Value("method", r.Method)
+			path := Cardinality.Path(r)
 			code := fmt.Sprintf("%d", wr.StatusCode())
 
 			RequestDuration.
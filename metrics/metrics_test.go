@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/alextanhongpin/core/metrics"
 	"github.com/prometheus/client_golang/prometheus"
@@ -99,13 +100,19 @@ request_duration_seconds_count{method="GET",path="/{path}",status="200",version=
 func TestRED(t *testing.T) {
 	prometheus.MustRegister(metrics.RED)
 
+	now := time.Now()
+	metrics.Now = func() time.Time { return now }
+	t.Cleanup(func() { metrics.Now = time.Now })
+
 	{
 		red := metrics.NewRED("user_service", "login")
+		now = now.Add(100 * time.Millisecond)
 		red.Done()
 	}
 
 	{
 		red := metrics.NewRED("user_service", "login")
+		now = now.Add(2 * time.Second)
 		red.Fail()
 		red.Done()
 	}
@@ -117,24 +124,24 @@ func TestRED(t *testing.T) {
 	is.Nil(err)
 	want := `# HELP red RED metrics
 # TYPE red histogram
-red_bucket{action="login",service="user_service",status="err",le="0.005"} 1
-red_bucket{action="login",service="user_service",status="err",le="0.01"} 1
-red_bucket{action="login",service="user_service",status="err",le="0.025"} 1
-red_bucket{action="login",service="user_service",status="err",le="0.05"} 1
-red_bucket{action="login",service="user_service",status="err",le="0.1"} 1
-red_bucket{action="login",service="user_service",status="err",le="0.25"} 1
-red_bucket{action="login",service="user_service",status="err",le="0.5"} 1
-red_bucket{action="login",service="user_service",status="err",le="1"} 1
+red_bucket{action="login",service="user_service",status="err",le="0.005"} 0
+red_bucket{action="login",service="user_service",status="err",le="0.01"} 0
+red_bucket{action="login",service="user_service",status="err",le="0.025"} 0
+red_bucket{action="login",service="user_service",status="err",le="0.05"} 0
+red_bucket{action="login",service="user_service",status="err",le="0.1"} 0
+red_bucket{action="login",service="user_service",status="err",le="0.25"} 0
+red_bucket{action="login",service="user_service",status="err",le="0.5"} 0
+red_bucket{action="login",service="user_service",status="err",le="1"} 0
 red_bucket{action="login",service="user_service",status="err",le="2.5"} 1
 red_bucket{action="login",service="user_service",status="err",le="5"} 1
 red_bucket{action="login",service="user_service",status="err",le="10"} 1
 red_bucket{action="login",service="user_service",status="err",le="+Inf"} 1
-red_sum{action="login",service="user_service",status="err"} 0
+red_sum{action="login",service="user_service",status="err"} 2
 red_count{action="login",service="user_service",status="err"} 1
-red_bucket{action="login",service="user_service",status="ok",le="0.005"} 1
-red_bucket{action="login",service="user_service",status="ok",le="0.01"} 1
-red_bucket{action="login",service="user_service",status="ok",le="0.025"} 1
-red_bucket{action="login",service="user_service",status="ok",le="0.05"} 1
+red_bucket{action="login",service="user_service",status="ok",le="0.005"} 0
+red_bucket{action="login",service="user_service",status="ok",le="0.01"} 0
+red_bucket{action="login",service="user_service",status="ok",le="0.025"} 0
+red_bucket{action="login",service="user_service",status="ok",le="0.05"} 0
 red_bucket{action="login",service="user_service",status="ok",le="0.1"} 1
 red_bucket{action="login",service="user_service",status="ok",le="0.25"} 1
 red_bucket{action="login",service="user_service",status="ok",le="0.5"} 1
@@ -143,7 +150,7 @@ red_bucket{action="login",service="user_service",status="ok",le="2.5"} 1
 red_bucket{action="login",service="user_service",status="ok",le="5"} 1
 red_bucket{action="login",service="user_service",status="ok",le="10"} 1
 red_bucket{action="login",service="user_service",status="ok",le="+Inf"} 1
-red_sum{action="login",service="user_service",status="ok"} 0
+red_sum{action="login",service="user_service",status="ok"} 0.1
 red_count{action="login",service="user_service",status="ok"} 1
 `
 	is.Equal(want, string(b))
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alextanhongpin/core/http/response"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Now returns the current time for the RED durations. Override it for
+// deterministic durations in tests.
+var Now = time.Now
+
+// StatusError is the error passed to ClassifyError by REDHandler when the
+// response status code is 4xx or 5xx.
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%d %s", e.Code, http.StatusText(e.Code))
+}
+
+// ClassifyError maps the error to the status label of the RED metrics.
+// Override it to distinguish errors, e.g. client errors from server errors
+// with errors.As and *StatusError.
+var ClassifyError = func(err error) string {
+	switch {
+	case err == nil:
+		return OK
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	default:
+		return Err
+	}
+}
+
+// Instrument wraps the function to record the rate, errors and duration.
+func Instrument[T any](service, action string, fn func(ctx context.Context) (T, error)) func(ctx context.Context) (T, error) {
+	return func(ctx context.Context) (T, error) {
+		start := Now()
+		v, err := fn(ctx)
+		observeRED(ctx, service, action, ClassifyError(err), Now().Sub(start))
+
+		return v, err
+	}
+}
+
+// REDMessageHandler is similar to Instrument, but for message handlers, e.g.
+// pubsub.Handler.
+func REDMessageHandler[M any](service, action string, h func(ctx context.Context, msg M) error) func(ctx context.Context, msg M) error {
+	return func(ctx context.Context, msg M) error {
+		start := Now()
+		err := h(ctx, msg)
+		observeRED(ctx, service, action, ClassifyError(err), Now().Sub(start))
+
+		return err
+	}
+}
+
+// REDHandler records the rate, errors and duration of the requests.
+// The action is the route pattern. The 4xx and 5xx status codes are
+// classified as *StatusError by ClassifyError, so that the status label is
+// the same as Instrument.
+func REDHandler(service string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := Now()
+		wr := response.NewResponseWriterRecorder(w)
+		h.ServeHTTP(wr, r)
+
+		var err error
+		if code := wr.StatusCode(); code >= http.StatusBadRequest {
+			err = &StatusError{Code: code}
+		}
+		observeRED(r.Context(), service, Cardinality.Pattern(r), ClassifyError(err), Now().Sub(start))
+	})
+}
+
+// observeRED attaches the trace id as exemplar when the context has a span.
+func observeRED(ctx context.Context, service, action, status string, d time.Duration) {
+	obs := RED.WithLabelValues(service, action, status)
+
+	sc := trace.SpanContextFromContext(ctx)
+	if eo, ok := obs.(prometheus.ExemplarObserver); ok && sc.HasTraceID() {
+		eo.ObserveWithExemplar(d.Seconds(), prometheus.Labels{
+			"trace_id": sc.TraceID().String(),
+			"span_id":  sc.SpanID().String(),
+		})
+
+		return
+	}
+
+	obs.Observe(d.Seconds())
+}
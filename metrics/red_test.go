@@ -0,0 +1,187 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/alextanhongpin/core/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestInstrument(t *testing.T) {
+	fn := metrics.Instrument("instrument_service", "find", func(ctx context.Context) (int, error) {
+		return 42, nil
+	})
+	failFn := metrics.Instrument("instrument_service", "find", func(ctx context.Context) (int, error) {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		return 0, errors.New("bad")
+	})
+
+	ctx := context.Background()
+	is := assert.New(t)
+	n, err := fn(ctx)
+	is.Nil(err)
+	is.Equal(42, n)
+
+	_, err = failFn(ctx)
+	is.NotNil(err)
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = failFn(ctx)
+	is.NotNil(err)
+
+	counts := collectRED(t, "instrument_service")
+	is.Equal(map[string]uint64{
+		"find/ok":       1,
+		"find/err":      1,
+		"find/canceled": 1,
+	}, counts)
+}
+
+func TestREDMessageHandler(t *testing.T) {
+	h := metrics.REDMessageHandler("message_service", "user_created", func(ctx context.Context, msg string) error {
+		if msg == "" {
+			return errors.New("empty")
+		}
+
+		return nil
+	})
+
+	is := assert.New(t)
+	is.Nil(h(context.Background(), "hello"))
+	is.NotNil(h(context.Background(), ""))
+	is.Equal(map[string]uint64{
+		"user_created/ok":  1,
+		"user_created/err": 1,
+	}, collectRED(t, "message_service"))
+}
+
+func TestREDHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("GET /{code}", metrics.REDHandler("http_service", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code, _ := strconv.Atoi(r.PathValue("code"))
+		w.WriteHeader(code)
+	})))
+
+	traceID, _ := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	spanID, _ := trace.SpanIDFromHex("0102030405060708")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	for _, path := range []string{"/200", "/404", "/500"} {
+		r := httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx)
+		mux.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	is := assert.New(t)
+	// The status codes are classified the same as the errors.
+	is.Equal(map[string]uint64{
+		"GET /{code}/ok":  1,
+		"GET /{code}/err": 2,
+	}, collectRED(t, "http_service"))
+
+	// The trace id is attached as exemplar.
+	var exemplar *dto.Exemplar
+	for _, m := range gatherRED(t, "http_service") {
+		for _, b := range m.GetHistogram().GetBucket() {
+			if b.GetExemplar() != nil {
+				exemplar = b.GetExemplar()
+			}
+		}
+	}
+	is.NotNil(exemplar)
+	is.Contains(exemplar.GetLabel(), &dto.LabelPair{
+		Name:  stringPtr("trace_id"),
+		Value: stringPtr(traceID.String()),
+	})
+}
+
+func TestREDHandler_ClassifyError(t *testing.T) {
+	classify := metrics.ClassifyError
+	metrics.ClassifyError = func(err error) string {
+		var statusErr *metrics.StatusError
+		if errors.As(err, &statusErr) && statusErr.Code < http.StatusInternalServerError {
+			return "client_error"
+		}
+
+		return classify(err)
+	}
+	t.Cleanup(func() { metrics.ClassifyError = classify })
+
+	h := metrics.REDHandler("classify_service", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code, _ := strconv.Atoi(r.URL.Query().Get("code"))
+		w.WriteHeader(code)
+	}))
+	for _, code := range []string{"200", "404", "500"} {
+		r := httptest.NewRequest(http.MethodGet, "/?code="+code, nil)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	// Requests without a route pattern are grouped as "other".
+	assert.Equal(t, map[string]uint64{
+		"other/ok":           1,
+		"other/client_error": 1,
+		"other/err":          1,
+	}, collectRED(t, "classify_service"))
+}
+
+func gatherRED(t *testing.T, service string) []*dto.Metric {
+	t.Helper()
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(metrics.RED)
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var res []*dto.Metric
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			if label(m, "service") == service {
+				res = append(res, m)
+			}
+		}
+	}
+
+	return res
+}
+
+// collectRED returns the count by action/status.
+func collectRED(t *testing.T, service string) map[string]uint64 {
+	t.Helper()
+
+	res := make(map[string]uint64)
+	for _, m := range gatherRED(t, service) {
+		res[label(m, "action")+"/"+label(m, "status")] = m.GetHistogram().GetSampleCount()
+	}
+
+	return res
+}
+
+func label(m *dto.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+
+	return ""
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,264 @@
+package metrics
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/alextanhongpin/core/http/response"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	sloBurnRateDesc = prometheus.NewDesc(
+		"slo_burn_rate",
+		"The rate the error budget is consumed within the window. A burn rate of 1 consumes the budget exactly at the end of the period.",
+		[]string{"route", "window"}, nil,
+	)
+	sloErrorBudgetRemainingDesc = prometheus.NewDesc(
+		"slo_error_budget_remaining",
+		"The ratio of the error budget remaining within the period. Negative when the budget is exhausted.",
+		[]string{"route"}, nil,
+	)
+)
+
+var ErrInvalidTarget = errors.New("metrics: target must be between 0 and 1")
+
+// Objective is the SLO of a route, e.g. 99.9% of requests succeeds under
+// 300ms.
+type Objective struct {
+	Route   string        // The pattern of the route, e.g. GET /users/{id}
+	Target  float64       // e.g. 0.999
+	Latency time.Duration // Requests slower than the latency are bad. Zero disables the latency check.
+}
+
+// SLO tracks the error budget burn rate of each route.
+// Events are counted in buckets of the Resolution, and each route keeps
+// Period/Resolution buckets.
+type SLO struct {
+	Now        func() time.Time
+	Period     time.Duration   // The period of the error budget.
+	Resolution time.Duration   // The size of each bucket.
+	Windows    []time.Duration // The windows of the burn rates.
+
+	mu     sync.RWMutex
+	routes map[string]*sloRoute
+}
+
+// NewSLO returns the SLO of the objectives.
+// Returns ErrInvalidTarget if the target is not between 0 and 1, exclusive,
+// since a target of 1 leaves no error budget.
+func NewSLO(objectives ...Objective) (*SLO, error) {
+	s := &SLO{
+		Now:        time.Now,
+		Period:     30 * 24 * time.Hour,
+		Resolution: time.Minute,
+		Windows: []time.Duration{
+			5 * time.Minute,
+			time.Hour,
+			6 * time.Hour,
+			3 * 24 * time.Hour,
+		},
+		routes: make(map[string]*sloRoute),
+	}
+	for _, o := range objectives {
+		if o.Target <= 0 || o.Target >= 1 {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidTarget, o.Route)
+		}
+
+		s.routes[o.Route] = &sloRoute{Objective: o}
+	}
+
+	return s, nil
+}
+
+// Observe records the request for the route. Routes without objectives are
+// ignored.
+func (s *SLO) Observe(route string, success bool, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.routes[route]
+	if !ok {
+		return
+	}
+
+	b := r.bucket(s.tick(), s.size())
+	b.total++
+	if r.good(success, latency) {
+		b.good++
+	}
+}
+
+// Status returns the SLO status of each route, sorted by the route.
+func (s *SLO) Status() []SLOStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.tick()
+	res := make([]SLOStatus, 0, len(s.routes))
+	for _, r := range s.routes {
+		good, total := r.sum(now, s.ticks(s.Period))
+		burnRates := make(map[string]float64, len(s.Windows))
+		for _, w := range s.Windows {
+			burnRates[formatWindow(w)] = r.burnRate(r.sum(now, s.ticks(w)))
+		}
+
+		res = append(res, SLOStatus{
+			Route:                r.Route,
+			Target:               r.Target,
+			Latency:              r.Latency.Seconds(),
+			Good:                 good,
+			Total:                total,
+			ErrorBudgetRemaining: 1 - r.burnRate(good, total),
+			BurnRates:            burnRates,
+		})
+	}
+	slices.SortFunc(res, func(a, b SLOStatus) int {
+		return cmp.Compare(a.Route, b.Route)
+	})
+
+	return res
+}
+
+// Describe implements prometheus.Collector.
+func (s *SLO) Describe(ch chan<- *prometheus.Desc) {
+	ch <- sloBurnRateDesc
+	ch <- sloErrorBudgetRemainingDesc
+}
+
+// Collect implements prometheus.Collector.
+func (s *SLO) Collect(ch chan<- prometheus.Metric) {
+	for _, st := range s.Status() {
+		ch <- prometheus.MustNewConstMetric(sloErrorBudgetRemainingDesc, prometheus.GaugeValue, st.ErrorBudgetRemaining, st.Route)
+		for window, rate := range st.BurnRates {
+			ch <- prometheus.MustNewConstMetric(sloBurnRateDesc, prometheus.GaugeValue, rate, st.Route, window)
+		}
+	}
+}
+
+func (s *SLO) tick() int64 {
+	return s.Now().UnixNano() / int64(s.Resolution)
+}
+
+func (s *SLO) ticks(d time.Duration) int64 {
+	return max(int64(d/s.Resolution), 1)
+}
+
+func (s *SLO) size() int64 {
+	n := s.Period
+	for _, w := range s.Windows {
+		n = max(n, w)
+	}
+
+	return s.ticks(n)
+}
+
+type SLOStatus struct {
+	Route                string             `json:"route"`
+	Target               float64            `json:"target"`
+	Latency              float64            `json:"latency"` // In seconds.
+	Good                 int64              `json:"good"`
+	Total                int64              `json:"total"`
+	ErrorBudgetRemaining float64            `json:"errorBudgetRemaining"`
+	BurnRates            map[string]float64 `json:"burnRates"`
+}
+
+type sloBucket struct {
+	tick  int64
+	good  int64
+	total int64
+}
+
+type sloRoute struct {
+	Objective
+	buckets []sloBucket
+}
+
+func (r *sloRoute) good(success bool, latency time.Duration) bool {
+	return success && (r.Latency == 0 || latency <= r.Latency)
+}
+
+// bucket returns the bucket of the tick, resetting it if it belongs to an
+// older tick.
+func (r *sloRoute) bucket(tick, size int64) *sloBucket {
+	if int64(len(r.buckets)) != size {
+		r.buckets = make([]sloBucket, size)
+	}
+
+	b := &r.buckets[tick%size]
+	if b.tick != tick {
+		*b = sloBucket{tick: tick}
+	}
+
+	return b
+}
+
+// sum returns the good and total count of the last n ticks.
+func (r *sloRoute) sum(now, n int64) (good, total int64) {
+	size := int64(len(r.buckets))
+	if size == 0 {
+		return
+	}
+
+	for tick := now - min(n, size) + 1; tick <= now; tick++ {
+		b := r.buckets[tick%size]
+		if b.tick == tick {
+			good += b.good
+			total += b.total
+		}
+	}
+
+	return
+}
+
+// burnRate returns the ratio of the error rate to the error budget.
+func (r *sloRoute) burnRate(good, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	errorRate := float64(total-good) / float64(total)
+
+	return errorRate / (1 - r.Target)
+}
+
+// SLOHandler observes the requests of the routes with objectives. Requests
+// with 5xx status code are considered failed.
+func SLOHandler(h http.Handler, slo *SLO) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		wr := response.NewResponseWriterRecorder(w)
+		h.ServeHTTP(wr, r)
+
+		slo.Observe(r.Pattern, wr.StatusCode() < http.StatusInternalServerError, time.Since(start))
+	})
+}
+
+// SLOStatusHandler reports the SLO status of each route.
+func SLOStatusHandler(slo *SLO) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := response.OK(w, slo.Status()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// formatWindow formats the window in the largest whole unit, e.g. 3d, 6h, 5m.
+func formatWindow(d time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case d%day == 0:
+		return fmt.Sprintf("%dd", d/day)
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return d.String()
+	}
+}
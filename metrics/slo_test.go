@@ -0,0 +1,120 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/core/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSLO(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	slo, err := metrics.NewSLO(metrics.Objective{
+		Route:   "GET /users",
+		Target:  0.99,
+		Latency: 300 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slo.Now = func() time.Time {
+		return now
+	}
+
+	// 1 hour ago, 1% of the requests failed.
+	now = now.Add(-time.Hour)
+	for i := range 100 {
+		slo.Observe("GET /users", i != 0, 100*time.Millisecond)
+	}
+
+	// In the last 5 minutes, 5% of the requests are slow.
+	now = now.Add(time.Hour)
+	for i := range 100 {
+		latency := 100 * time.Millisecond
+		if i < 5 {
+			latency = time.Second
+		}
+		slo.Observe("GET /users", true, latency)
+	}
+
+	// Routes without objectives are ignored.
+	slo.Observe("GET /unknown", false, 0)
+
+	is := assert.New(t)
+	status := slo.Status()
+	is.Len(status, 1)
+
+	s := status[0]
+	is.Equal("GET /users", s.Route)
+	is.Equal(0.3, s.Latency)
+	is.Equal(int64(194), s.Good)
+	is.Equal(int64(200), s.Total)
+	is.InDelta(-2.0, s.ErrorBudgetRemaining, 1e-9)
+	is.InDelta(5.0, s.BurnRates["5m"], 1e-9)
+	is.InDelta(5.0, s.BurnRates["1h"], 1e-9)
+	is.InDelta(3.0, s.BurnRates["6h"], 1e-9)
+	is.InDelta(3.0, s.BurnRates["3d"], 1e-9)
+
+	// 1 error budget remaining gauge, and 4 burn rate gauges.
+	is.Equal(5, testutil.CollectAndCount(slo))
+}
+
+func TestSLOHandler(t *testing.T) {
+	slo, err := metrics.NewSLO(metrics.Objective{
+		Route:  "GET /{code}",
+		Target: 0.5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /{code}", metrics.SLOHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("code") == "500" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}), slo))
+
+	for _, path := range []string{"/200", "/500"} {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	metrics.SLOStatusHandler(slo).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	is := assert.New(t)
+	res := w.Result()
+	is.Equal(http.StatusOK, res.StatusCode)
+
+	b, err := io.ReadAll(res.Body)
+	is.Nil(err)
+	is.JSONEq(`{
+		"data": [{
+			"route": "GET /{code}",
+			"target": 0.5,
+			"latency": 0,
+			"good": 1,
+			"total": 2,
+			"errorBudgetRemaining": 0,
+			"burnRates": {"5m": 1, "1h": 1, "6h": 1, "3d": 1}
+		}]
+	}`, string(b))
+}
+
+func TestNewSLO_InvalidTarget(t *testing.T) {
+	for _, target := range []float64{0, 1, 1.5} {
+		_, err := metrics.NewSLO(metrics.Objective{
+			Route:  "GET /users",
+			Target: target,
+		})
+
+		is := assert.New(t)
+		is.ErrorIs(err, metrics.ErrInvalidTarget, target)
+	}
+}
@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrInvalidInterval = errors.New("ratelimit: interval must be positive")
+
+type clearer interface {
+	Clear()
+}
+
+var (
+	_ clearer = (*MultiFixedWindow)(nil)
+	_ clearer = (*MultiGCRA)(nil)
+	_ clearer = (*MultiSlidingWindow)(nil)
+)
+
+// Janitor periodically clears the expired keys of the keyed limiters in the
+// background. Call the returned function to stop the janitor.
+// Returns ErrInvalidInterval if every is not positive.
+func Janitor(ctx context.Context, every time.Duration, cs ...clearer) (func(), error) {
+	if every <= 0 {
+		return nil, ErrInvalidInterval
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		t := time.NewTicker(every)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				for _, c := range cs {
+					c.Clear()
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}, nil
+}
@@ -0,0 +1,46 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/core/sync/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJanitor(t *testing.T) {
+	gcra := ratelimit.NewMultiGCRA(5, 10*time.Millisecond, 0)
+	fw := ratelimit.NewMultiFixedWindow(5, 10*time.Millisecond)
+	sw := ratelimit.NewMultiSlidingWindow(5, 10*time.Millisecond)
+
+	for _, key := range []string{"foo", "bar"} {
+		gcra.Allow(key)
+		fw.Allow(key)
+		sw.Allow(key)
+	}
+
+	is := assert.New(t)
+	is.Equal(2, gcra.Size())
+	is.Equal(2, fw.Size())
+	is.Equal(2, sw.Size())
+
+	stop, err := ratelimit.Janitor(context.Background(), 10*time.Millisecond, gcra, fw, sw)
+	is.Nil(err)
+	time.Sleep(50 * time.Millisecond)
+	stop()
+
+	is.Equal(0, gcra.Size())
+	is.Equal(0, fw.Size())
+	is.Equal(0, sw.Size())
+	is.Equal(2, gcra.Expired())
+	is.Equal(2, fw.Expired())
+	is.Equal(2, sw.Expired())
+}
+
+func TestJanitor_InvalidInterval(t *testing.T) {
+	_, err := ratelimit.Janitor(context.Background(), 0, ratelimit.NewMultiGCRA(5, time.Second, 0))
+
+	is := assert.New(t)
+	is.ErrorIs(err, ratelimit.ErrInvalidInterval)
+}
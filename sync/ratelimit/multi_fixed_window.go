@@ -1,7 +1,6 @@
 package ratelimit
 
 import (
-	"sync"
 	"time"
 )
 
@@ -13,19 +12,21 @@ type fixedWindowState struct {
 // MultiFixedWindow acts as a counter for a given time period.
 type MultiFixedWindow struct {
 	// State.
-	mu    sync.RWMutex
-	state map[string]fixedWindowState
+	state *store[fixedWindowState]
 	// Options.
 	limit  int
 	period int64
 	Now    func() time.Time
+	// MaxKeys is the maximum number of keys to keep. The least recently used
+	// keys are evicted when exceeded. Zero means no limit.
+	MaxKeys int
 }
 
 func NewMultiFixedWindow(limit int, period time.Duration) *MultiFixedWindow {
 	return &MultiFixedWindow{
 		limit:  limit,
 		period: period.Nanoseconds(),
-		state:  make(map[string]fixedWindowState),
+		state:  newStore[fixedWindowState](),
 		Now:    time.Now,
 	}
 }
@@ -39,46 +40,41 @@ func (r *MultiFixedWindow) Allow(key string) bool {
 // AllowN checks if a request is allowed. Consumes n token
 // if allowed.
 func (r *MultiFixedWindow) AllowN(key string, n int) bool {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	now := r.Now()
-	if r.isExpired(key, now) {
-		r.state[key] = fixedWindowState{count: 0, last: now.UnixNano()}
-	}
+	var allow bool
+	r.state.update(key, r.MaxKeys, func(s fixedWindowState) fixedWindowState {
+		now := r.Now()
+		if r.isExpired(s, now) {
+			s = fixedWindowState{count: 0, last: now.UnixNano()}
+		}
 
-	s := r.state[key]
-	if r.limit-s.count >= n {
-		s.count += n
-		r.state[key] = s
+		if r.limit-s.count >= n {
+			s.count += n
+			allow = true
+		}
 
-		return true
-	}
+		return s
+	})
 
-	return false
+	return allow
 }
 
 func (r *MultiFixedWindow) Remaining(key string) int {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	if r.isExpired(key, r.Now()) {
+	s := r.state.load(key)
+	if r.isExpired(s, r.Now()) {
 		return r.limit
 	}
 
-	return r.limit - r.state[key].count
+	return r.limit - s.count
 }
 
 func (r *MultiFixedWindow) RetryAt(key string) time.Time {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	s := r.state.load(key)
 
 	now := r.Now()
-	if r.isExpired(key, now) {
+	if r.isExpired(s, now) {
 		return now
 	}
 
-	s := r.state[key]
 	if r.limit > s.count {
 		return now
 	}
@@ -87,24 +83,27 @@ func (r *MultiFixedWindow) RetryAt(key string) time.Time {
 	return time.Unix(0, nsec)
 }
 
-func (r *MultiFixedWindow) isExpired(key string, at time.Time) bool {
-	return r.state[key].last+r.period <= at.UnixNano()
+func (r *MultiFixedWindow) isExpired(s fixedWindowState, at time.Time) bool {
+	return s.last+r.period <= at.UnixNano()
 }
 
 func (r *MultiFixedWindow) Clear() {
-	r.mu.Lock()
-	now := r.Now().UnixNano()
-	for k, v := range r.state {
-		if v.last+r.period <= now {
-			delete(r.state, k)
-		}
-	}
-	r.mu.Unlock()
+	now := r.Now()
+	r.state.deleteExpired(func(s fixedWindowState) bool {
+		return r.isExpired(s, now)
+	})
 }
 
 func (r *MultiFixedWindow) Size() int {
-	r.mu.RLock()
-	n := len(r.state)
-	r.mu.RUnlock()
-	return n
+	return r.state.len()
+}
+
+// Evicted returns the number of keys evicted because MaxKeys was exceeded.
+func (r *MultiFixedWindow) Evicted() int {
+	return int(r.state.evicted.Load())
+}
+
+// Expired returns the number of expired keys removed by Clear.
+func (r *MultiFixedWindow) Expired() int {
+	return int(r.state.expired.Load())
 }
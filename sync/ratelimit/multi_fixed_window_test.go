@@ -1,6 +1,7 @@
 package ratelimit_test
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -36,3 +37,51 @@ func TestMultiFixedWindow_Clear(t *testing.T) {
 	r.Clear()
 	is.Equal(0, r.Size())
 }
+
+func TestMultiFixedWindow_MaxKeys(t *testing.T) {
+	r := ratelimit.NewMultiFixedWindow(5, time.Second)
+	r.MaxKeys = 10
+
+	for i := range 100 {
+		r.Allow(fmt.Sprint(i))
+	}
+
+	is := assert.New(t)
+	is.Equal(10, r.Size())
+	is.Equal(90, r.Evicted())
+}
+
+func TestMultiFixedWindow_MaxKeysEvictsLeastRecentlyUsed(t *testing.T) {
+	r := ratelimit.NewMultiFixedWindow(1, time.Second)
+	r.MaxKeys = 10
+
+	is := assert.New(t)
+	for i := range 10 {
+		is.True(r.Allow(fmt.Sprint(i)))
+	}
+	// Use the first half again, so that the second half is evicted.
+	for i := range 5 {
+		is.False(r.Allow(fmt.Sprint(i)))
+	}
+	for i := range 5 {
+		is.True(r.Allow(fmt.Sprint(10 + i)))
+	}
+
+	is.Equal(5, r.Evicted())
+	for i := range 5 {
+		is.False(r.Allow(fmt.Sprint(i)), "recently used keys are kept")
+	}
+}
+
+func TestMultiFixedWindow_MaxKeysKeepsInserted(t *testing.T) {
+	r := ratelimit.NewMultiFixedWindow(1, time.Second)
+	r.MaxKeys = 1
+
+	is := assert.New(t)
+	for i := range 100 {
+		key := fmt.Sprint(i)
+		is.True(r.Allow(key))
+		is.False(r.Allow(key), "the inserted key is not evicted")
+		is.Equal(1, r.Size())
+	}
+}
@@ -1,20 +1,21 @@
 package ratelimit
 
 import (
-	"sync"
 	"time"
 )
 
 type MultiGCRA struct {
 	// State.
-	mu    sync.RWMutex
-	state map[string]int64
+	state *store[int64]
 
 	// Option.
 	interval int64
 	offset   int64
 	period   int64
 	Now      func() time.Time
+	// MaxKeys is the maximum number of keys to keep. The least recently used
+	// keys are evicted when exceeded. Zero means no limit.
+	MaxKeys int
 }
 
 func NewMultiGCRA(limit int, period time.Duration, burst int) *MultiGCRA {
@@ -22,7 +23,7 @@ func NewMultiGCRA(limit int, period time.Duration, burst int) *MultiGCRA {
 
 	return &MultiGCRA{
 		// NOTE: The burst is only applied once.
-		state:    make(map[string]int64),
+		state:    newStore[int64](),
 		interval: interval,
 		offset:   interval * int64(burst),
 		period:   period.Nanoseconds(),
@@ -35,46 +36,49 @@ func (r *MultiGCRA) Allow(key string) bool {
 }
 
 func (r *MultiGCRA) AllowN(key string, n int) bool {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	var allow bool
+	r.state.update(key, r.MaxKeys, func(last int64) int64 {
+		now := r.Now().UnixNano()
+		last = max(last, now)
+		if last-r.offset <= now {
+			last += int64(n) * r.interval
+			allow = true
+		}
 
-	now := r.Now().UnixNano()
-	r.state[key] = max(r.state[key], now)
-	if r.state[key]-r.offset <= now {
-		r.state[key] += int64(n) * r.interval
+		return last
+	})
 
-		return true
-	}
-
-	return false
+	return allow
 }
 
 func (r *MultiGCRA) RetryAt(key string) time.Time {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	last := r.state.load(key)
 
 	now := r.Now()
-	if r.state[key] < now.UnixNano() {
+	if last < now.UnixNano() {
 		return now
 	}
 
-	return time.Unix(0, r.state[key]+r.interval)
+	return time.Unix(0, last+r.interval)
 }
 
 func (r *MultiGCRA) Clear() {
-	r.mu.Lock()
 	now := r.Now().UnixNano()
-	for k, v := range r.state {
-		if v+r.period <= now {
-			delete(r.state, k)
-		}
-	}
-	r.mu.Unlock()
+	r.state.deleteExpired(func(last int64) bool {
+		return last+r.period <= now
+	})
 }
 
 func (r *MultiGCRA) Size() int {
-	r.mu.RLock()
-	n := len(r.state)
-	r.mu.RUnlock()
-	return n
+	return r.state.len()
+}
+
+// Evicted returns the number of keys evicted because MaxKeys was exceeded.
+func (r *MultiGCRA) Evicted() int {
+	return int(r.state.evicted.Load())
+}
+
+// Expired returns the number of expired keys removed by Clear.
+func (r *MultiGCRA) Expired() int {
+	return int(r.state.expired.Load())
 }
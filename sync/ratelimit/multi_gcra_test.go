@@ -1,6 +1,7 @@
 package ratelimit_test
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -36,3 +37,16 @@ func TestMultiGCRA_Clear(t *testing.T) {
 	r.Clear()
 	is.Equal(0, r.Size())
 }
+
+func TestMultiGCRA_MaxKeys(t *testing.T) {
+	r := ratelimit.NewMultiGCRA(5, time.Second, 1)
+	r.MaxKeys = 10
+
+	for i := range 100 {
+		r.Allow(fmt.Sprint(i))
+	}
+
+	is := assert.New(t)
+	is.Equal(10, r.Size())
+	is.Equal(90, r.Evicted())
+}
@@ -2,7 +2,6 @@ package ratelimit
 
 import (
 	"math"
-	"sync"
 	"time"
 )
 
@@ -14,19 +13,21 @@ type slidingWindowState struct {
 
 type MultiSlidingWindow struct {
 	// State.
-	mu    sync.RWMutex
-	state map[string]slidingWindowState
+	state *store[slidingWindowState]
 
 	// Options.
 	limit  int
 	period int64
 	Now    func() time.Time
+	// MaxKeys is the maximum number of keys to keep. The least recently used
+	// keys are evicted when exceeded. Zero means no limit.
+	MaxKeys int
 }
 
 func NewMultiSlidingWindow(limit int, period time.Duration) *MultiSlidingWindow {
 	return &MultiSlidingWindow{
 		// NOTE: The burst is only applied once.
-		state:  make(map[string]slidingWindowState),
+		state:  newStore[slidingWindowState](),
 		limit:  limit,
 		period: period.Nanoseconds(),
 		Now:    time.Now,
@@ -38,30 +39,27 @@ func (r *MultiSlidingWindow) Allow(key string) bool {
 }
 
 func (r *MultiSlidingWindow) AllowN(key string, n int) bool {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	var allow bool
+	r.state.update(key, r.MaxKeys, func(s slidingWindowState) slidingWindowState {
+		if r.remaining(s) >= n {
+			allow = true
 
-	if r.remaining(key) >= n {
-		r.add(key, n)
+			return r.add(s, n)
+		}
 
-		return true
-	}
+		return s
+	})
 
-	return false
+	return allow
 }
 
 func (r *MultiSlidingWindow) Remaining(key string) int {
-	r.mu.RLock()
-	n := r.remaining(key)
-	r.mu.RUnlock()
-
-	return n
+	return r.remaining(r.state.load(key))
 }
 
-func (r *MultiSlidingWindow) remaining(key string) int {
+func (r *MultiSlidingWindow) remaining(s slidingWindowState) int {
 	now := r.Now().UnixNano()
 
-	s := r.state[key]
 	prev := s.prev
 	curr := s.curr
 	window := s.window
@@ -84,9 +82,8 @@ func (r *MultiSlidingWindow) remaining(key string) int {
 	return r.limit - (int(math.Ceil(ratio*float64(prev))) + curr)
 }
 
-func (r *MultiSlidingWindow) add(key string, n int) {
+func (r *MultiSlidingWindow) add(s slidingWindowState, n int) slidingWindowState {
 	now := r.Now().UnixNano()
-	s := r.state[key]
 	if s.window+r.period > now {
 		// In current window
 	} else if s.window+2*r.period > now {
@@ -101,23 +98,27 @@ func (r *MultiSlidingWindow) add(key string, n int) {
 	}
 
 	s.curr += n
-	r.state[key] = s
+
+	return s
 }
 
 func (r *MultiSlidingWindow) Clear() {
-	r.mu.Lock()
 	now := r.Now().UnixNano()
-	for k, v := range r.state {
-		if v.window+r.period <= now {
-			delete(r.state, k)
-		}
-	}
-	r.mu.Unlock()
+	r.state.deleteExpired(func(s slidingWindowState) bool {
+		return s.window+r.period <= now
+	})
 }
 
 func (r *MultiSlidingWindow) Size() int {
-	r.mu.RLock()
-	n := len(r.state)
-	r.mu.RUnlock()
-	return n
+	return r.state.len()
+}
+
+// Evicted returns the number of keys evicted because MaxKeys was exceeded.
+func (r *MultiSlidingWindow) Evicted() int {
+	return int(r.state.evicted.Load())
+}
+
+// Expired returns the number of expired keys removed by Clear.
+func (r *MultiSlidingWindow) Expired() int {
+	return int(r.state.expired.Load())
 }
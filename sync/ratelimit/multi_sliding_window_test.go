@@ -1,6 +1,7 @@
 package ratelimit_test
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -36,3 +37,16 @@ func TestMultiSlidingWindow_Clear(t *testing.T) {
 	r.Clear()
 	is.Equal(0, r.Size())
 }
+
+func TestMultiSlidingWindow_MaxKeys(t *testing.T) {
+	r := ratelimit.NewMultiSlidingWindow(5, time.Second)
+	r.MaxKeys = 10
+
+	for i := range 100 {
+		r.Allow(fmt.Sprint(i))
+	}
+
+	is := assert.New(t)
+	is.Equal(10, r.Size())
+	is.Equal(90, r.Evicted())
+}
@@ -0,0 +1,170 @@
+package ratelimit
+
+import (
+	"container/list"
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+)
+
+// shards is the number of locks used to reduce contention.
+const shards = 32
+
+type entry[T any] struct {
+	key   string
+	value T
+	// used is the store clock when the key was last used.
+	used uint64
+}
+
+type shard[T any] struct {
+	mu    sync.RWMutex
+	items map[string]*list.Element
+	lru   *list.List
+}
+
+// store is a sharded key-value store, which evicts the least recently used
+// keys when the number of keys exceeds the limit.
+// Each key is stamped with a global clock when used, so that the least
+// recently used key across all shards is evicted, and not only within the
+// shard.
+type store[T any] struct {
+	seed    maphash.Seed
+	shards  [shards]*shard[T]
+	size    atomic.Int64
+	evicted atomic.Int64
+	expired atomic.Int64
+	clock   atomic.Uint64
+}
+
+func newStore[T any]() *store[T] {
+	s := &store[T]{
+		seed: maphash.MakeSeed(),
+	}
+	for i := range s.shards {
+		s.shards[i] = &shard[T]{
+			items: make(map[string]*list.Element),
+			lru:   list.New(),
+		}
+	}
+
+	return s
+}
+
+func (s *store[T]) shard(key string) *shard[T] {
+	return s.shards[maphash.String(s.seed, key)%shards]
+}
+
+// load returns the value for the key without marking it as recently used.
+func (s *store[T]) load(key string) T {
+	sh := s.shard(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	if e, ok := sh.items[key]; ok {
+		return e.Value.(*entry[T]).value
+	}
+
+	var v T
+	return v
+}
+
+// update replaces the value for the key with the result of fn, marking it as
+// recently used.
+// When limit is positive, the least recently used keys are evicted to keep
+// the size within the limit.
+func (s *store[T]) update(key string, limit int, fn func(T) T) {
+	sh := s.shard(key)
+	sh.mu.Lock()
+	if e, ok := sh.items[key]; ok {
+		ent := e.Value.(*entry[T])
+		ent.value = fn(ent.value)
+		ent.used = s.clock.Add(1)
+		sh.lru.MoveToFront(e)
+		sh.mu.Unlock()
+
+		return
+	}
+
+	var v T
+	sh.items[key] = sh.lru.PushFront(&entry[T]{key: key, value: fn(v), used: s.clock.Add(1)})
+	s.size.Add(1)
+	sh.mu.Unlock()
+
+	for limit > 0 && s.size.Load() > int64(limit) {
+		if !s.evictOne(key) {
+			break
+		}
+	}
+}
+
+// evictOne evicts the least recently used key across all shards.
+// The oldest key of each shard is compared, since the keys within a shard are
+// ordered by use.
+// The skipped key, i.e. the key that is just inserted, is never evicted.
+func (s *store[T]) evictOne(skip string) bool {
+	for {
+		var oldest *shard[T]
+		var used uint64
+		for _, sh := range s.shards {
+			sh.mu.RLock()
+			if ent, ok := sh.oldest(skip); ok && (oldest == nil || ent.used < used) {
+				oldest, used = sh, ent.used
+			}
+			sh.mu.RUnlock()
+		}
+		if oldest == nil {
+			return false
+		}
+
+		oldest.mu.Lock()
+		// The key may be used or removed after the shards are compared, so
+		// retry when it is no longer the oldest.
+		ent, ok := oldest.oldest(skip)
+		ok = ok && ent.used == used
+		if ok {
+			oldest.lru.Remove(oldest.lru.Back())
+			delete(oldest.items, ent.key)
+		}
+		oldest.mu.Unlock()
+		if ok {
+			s.size.Add(-1)
+			s.evicted.Add(1)
+
+			return true
+		}
+	}
+}
+
+// deleteExpired deletes all keys for which fn returns true.
+func (s *store[T]) deleteExpired(fn func(T) bool) {
+	var n int
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		for k, e := range sh.items {
+			if fn(e.Value.(*entry[T]).value) {
+				sh.lru.Remove(e)
+				delete(sh.items, k)
+				n++
+			}
+		}
+		sh.mu.Unlock()
+	}
+	s.size.Add(int64(-n))
+	s.expired.Add(int64(n))
+}
+
+func (s *store[T]) len() int {
+	return int(s.size.Load())
+}
+
+// oldest returns the least recently used entry, unless it is the skipped
+// key.
+func (sh *shard[T]) oldest(skip string) (*entry[T], bool) {
+	e := sh.lru.Back()
+	if e == nil || e.Value.(*entry[T]).key == skip {
+		return nil, false
+	}
+
+	return e.Value.(*entry[T]), true
+}
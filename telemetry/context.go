@@ -0,0 +1,102 @@
+package telemetry
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// valuer is implemented by contextkey.Key[T].
+type valuer[T any] interface {
+	Value(ctx context.Context) (T, bool)
+}
+
+type contextAttr func(ctx context.Context) (slog.Attr, bool)
+
+// ContextHandler adds the values of the registered context keys, as well as
+// the trace and span ids, to the log records.
+//
+//	h := telemetry.NewContextHandler(slog.NewJSONHandler(os.Stdout, nil))
+//	h = telemetry.WithContextKey(h, "requestId", requestid.Context, nil)
+//	h = telemetry.WithContextKey(h, "subject", auth.ClaimsContext, func(c *auth.Claims) slog.Value {
+//		return slog.StringValue(c.Subject)
+//	})
+//	logger := slog.New(h)
+type ContextHandler struct {
+	Handler slog.Handler
+	attrs   []contextAttr
+}
+
+var _ slog.Handler = (*ContextHandler)(nil)
+
+func NewContextHandler(h slog.Handler) *ContextHandler {
+	return &ContextHandler{
+		Handler: h,
+	}
+}
+
+// WithContextKey returns a new handler that logs the value of the key with
+// the given name. The format defaults to slog.AnyValue.
+func WithContextKey[T any](h *ContextHandler, name string, key valuer[T], format func(T) slog.Value) *ContextHandler {
+	if format == nil {
+		format = func(t T) slog.Value {
+			return slog.AnyValue(t)
+		}
+	}
+
+	attr := func(ctx context.Context) (slog.Attr, bool) {
+		t, ok := key.Value(ctx)
+		if !ok {
+			return slog.Attr{}, false
+		}
+
+		return slog.Attr{Key: name, Value: format(t)}, true
+	}
+
+	return &ContextHandler{
+		Handler: h.Handler,
+		attrs:   append(h.attrs[:len(h.attrs):len(h.attrs)], attr),
+	}
+}
+
+func (h *ContextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.Handler.Enabled(ctx, level)
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	r = r.Clone()
+	for _, fn := range h.attrs {
+		if a, ok := fn(ctx); ok {
+			r.AddAttrs(a)
+		}
+	}
+
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		spanCtx := span.SpanContext()
+		if spanCtx.HasTraceID() {
+			r.AddAttrs(slog.String("traceId", spanCtx.TraceID().String()))
+		}
+
+		if spanCtx.HasSpanID() {
+			r.AddAttrs(slog.String("spanId", spanCtx.SpanID().String()))
+		}
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{
+		Handler: h.Handler.WithAttrs(attrs),
+		attrs:   h.attrs,
+	}
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{
+		Handler: h.Handler.WithGroup(name),
+		attrs:   h.attrs,
+	}
+}
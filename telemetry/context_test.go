@@ -0,0 +1,83 @@
+package telemetry_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/alextanhongpin/core/telemetry"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// contextKey is similar to contextkey.Key[T].
+type contextKey[T any] string
+
+func (k contextKey[T]) WithValue(ctx context.Context, t T) context.Context {
+	return context.WithValue(ctx, k, t)
+}
+
+func (k contextKey[T]) Value(ctx context.Context) (T, bool) {
+	t, ok := ctx.Value(k).(T)
+	return t, ok
+}
+
+type claims struct {
+	Subject string
+}
+
+func TestContextHandler(t *testing.T) {
+	var (
+		requestIDContext contextKey[string]  = "request_id"
+		claimsContext    contextKey[*claims] = "claims"
+	)
+
+	var buf bytes.Buffer
+	h := telemetry.NewContextHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	h = telemetry.WithContextKey(h, "requestId", requestIDContext, nil)
+	h = telemetry.WithContextKey(h, "subject", claimsContext, func(c *claims) slog.Value {
+		return slog.StringValue(c.Subject)
+	})
+	logger := slog.New(h).With("service", "api")
+
+	t.Run("empty", func(t *testing.T) {
+		buf.Reset()
+		logger.InfoContext(ctx, "hello")
+
+		is := assert.New(t)
+		is.Equal("level=INFO msg=hello service=api\n", buf.String())
+	})
+
+	t.Run("values", func(t *testing.T) {
+		buf.Reset()
+		ctx := requestIDContext.WithValue(ctx, "req-123")
+		ctx = claimsContext.WithValue(ctx, &claims{Subject: "john"})
+		logger.InfoContext(ctx, "hello")
+
+		is := assert.New(t)
+		is.Equal("level=INFO msg=hello service=api requestId=req-123 subject=john\n", buf.String())
+	})
+
+	t.Run("span", func(t *testing.T) {
+		buf.Reset()
+		tp := sdktrace.NewTracerProvider()
+		ctx, span := tp.Tracer("test").Start(ctx, "hello")
+		defer span.End()
+
+		logger.InfoContext(ctx, "hello")
+
+		sc := span.SpanContext()
+		want := fmt.Sprintf("level=INFO msg=hello service=api traceId=%s spanId=%s\n", sc.TraceID(), sc.SpanID())
+		is := assert.New(t)
+		is.Equal(want, buf.String())
+	})
+}
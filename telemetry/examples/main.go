@@ -14,6 +14,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
 	"golang.org/x/exp/event"
 	"golang.org/x/exp/event/eventtest"
 	"golang.org/x/exp/slog"
@@ -46,11 +47,16 @@ func main() {
 	ctx = event.WithExporter(ctx, event.NewExporter(&telemetry.MultiHandler{
 		Metric: ph,
 		Log:    telemetry.NewSlogHandler(logger),
+		Trace:  telemetry.NewTraceHandler(otel.Tracer("instrumentation/package/name")),
 	}, opt))
 	event.Log(ctx, "my event", event.Int64("myInt", 6))
 	event.Log(ctx, "error event", event.String("myString", "some string value"))
 	event.Error(ctx, "hello", errors.New("unexpected error has occured"))
 
+	spanCtx := event.Start(ctx, "my span", event.String("myString", "some string value"))
+	event.Log(spanCtx, "inside span")
+	event.End(spanCtx)
+
 	c := event.NewCounter("hits", &event.MetricOptions{Description: "Earth meteorite hits"})
 	go func() {
 
@@ -0,0 +1,237 @@
+package telemetry
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"golang.org/x/exp/event"
+)
+
+// The kinds of the recorded events.
+const (
+	LogRecord    = "log"
+	MetricRecord = "metric"
+	StartRecord  = "start"
+	EndRecord    = "end"
+)
+
+// The types of the recorded metrics.
+const (
+	CounterMetric   = "counter"
+	GaugeMetric     = "gauge"
+	HistogramMetric = "histogram"
+)
+
+// Record is the event captured by the Recorder.
+// Durations are recorded in seconds.
+type Record struct {
+	Kind   string         `json:"kind"`
+	Name   string         `json:"name"`             // The message of the log, the name of the metric or the span.
+	Span   string         `json:"span,omitempty"`   // The name of the span the event belongs to.
+	Metric string         `json:"metric,omitempty"` // The type of the metric.
+	Value  float64        `json:"value,omitempty"`  // The value of the metric.
+	Labels map[string]any `json:"labels,omitempty"`
+}
+
+// Records is the list of records with query helpers, e.g.
+//
+//	rec.Records().Metrics().Name("hits").Label("version", "stable").Sum()
+type Records []Record
+
+func (rs Records) Filter(fn func(r Record) bool) Records {
+	var res Records
+	for _, r := range rs {
+		if fn(r) {
+			res = append(res, r)
+		}
+	}
+
+	return res
+}
+
+func (rs Records) Kind(kind string) Records {
+	return rs.Filter(func(r Record) bool {
+		return r.Kind == kind
+	})
+}
+
+func (rs Records) Logs() Records    { return rs.Kind(LogRecord) }
+func (rs Records) Metrics() Records { return rs.Kind(MetricRecord) }
+func (rs Records) Spans() Records   { return rs.Kind(StartRecord) }
+
+func (rs Records) Name(name string) Records {
+	return rs.Filter(func(r Record) bool {
+		return r.Name == name
+	})
+}
+
+// Label filters the records with the label value. Integer labels are stored
+// as int64, e.g. Label("age", int64(42)). The values are compared with
+// reflect.DeepEqual, so that uncomparable values, e.g. slices, do not panic.
+func (rs Records) Label(name string, value any) Records {
+	return rs.Filter(func(r Record) bool {
+		v, ok := r.Labels[name]
+		return ok && reflect.DeepEqual(v, value)
+	})
+}
+
+// HasLabel filters the records with the label, regardless of the value.
+func (rs Records) HasLabel(name string) Records {
+	return rs.Filter(func(r Record) bool {
+		_, ok := r.Labels[name]
+		return ok
+	})
+}
+
+// Values returns the metric values, e.g. the histogram observations.
+func (rs Records) Values() []float64 {
+	res := make([]float64, len(rs))
+	for i, r := range rs {
+		res[i] = r.Value
+	}
+
+	return res
+}
+
+// Sum returns the sum of the metric values, e.g. the counter total.
+func (rs Records) Sum() float64 {
+	var sum float64
+	for _, r := range rs {
+		sum += r.Value
+	}
+
+	return sum
+}
+
+// Recorder is an event.Handler that captures the events in memory, for
+// asserting the telemetry in tests. See the telemetrytest package for the
+// assertions.
+type Recorder struct {
+	mu      sync.Mutex
+	records Records
+}
+
+var _ event.Handler = (*Recorder)(nil)
+
+func NewRecorder() *Recorder {
+	return new(Recorder)
+}
+
+type recorderSpanKey struct{}
+
+func (rec *Recorder) Event(ctx context.Context, ev *event.Event) context.Context {
+	span, _ := ctx.Value(recorderSpanKey{}).(string)
+
+	r := Record{
+		Span:   span,
+		Labels: make(map[string]any),
+	}
+	switch ev.Kind {
+	case event.LogKind:
+		r.Kind = LogRecord
+		r.Name = ev.Find("msg").String()
+	case event.MetricKind:
+		r.Kind = MetricRecord
+		if v, ok := event.MetricKey.Find(ev); ok {
+			em := v.(event.Metric)
+			r.Name = em.Name()
+			r.Metric = metricType(em)
+		}
+		r.Value = metricValue(ev.Find(event.MetricVal))
+	case event.StartKind:
+		r.Kind = StartRecord
+		r.Name = ev.Find("name").String()
+		ctx = context.WithValue(ctx, recorderSpanKey{}, r.Name)
+	case event.EndKind:
+		r.Kind = EndRecord
+		r.Name = span
+		r.Span = ""
+	default:
+		return ctx
+	}
+
+	for _, l := range ev.Labels {
+		switch l.Name {
+		case "", "msg", string(event.MetricKey), event.MetricVal, string(event.DurationMetric):
+			continue
+		case "name":
+			if ev.Kind == event.StartKind {
+				continue
+			}
+		}
+		if !l.HasValue() {
+			continue
+		}
+
+		r.Labels[l.Name] = labelValue(l)
+	}
+	if len(r.Labels) == 0 {
+		r.Labels = nil
+	}
+
+	rec.mu.Lock()
+	rec.records = append(rec.records, r)
+	rec.mu.Unlock()
+
+	return ctx
+}
+
+// Records returns a copy of the recorded events.
+func (rec *Recorder) Records() Records {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	return append(Records(nil), rec.records...)
+}
+
+// Reset clears the recorded events.
+func (rec *Recorder) Reset() {
+	rec.mu.Lock()
+	rec.records = nil
+	rec.mu.Unlock()
+}
+
+func metricType(em event.Metric) string {
+	switch em.(type) {
+	case *event.Counter:
+		return CounterMetric
+	case *event.FloatGauge:
+		return GaugeMetric
+	default:
+		return HistogramMetric
+	}
+}
+
+func metricValue(l event.Label) float64 {
+	switch {
+	case l.IsDuration():
+		return l.Duration().Seconds()
+	case l.IsInt64():
+		return float64(l.Int64())
+	case l.IsUint64():
+		return float64(l.Uint64())
+	case l.IsFloat64():
+		return l.Float64()
+	default:
+		return 0
+	}
+}
+
+// labelValue returns the label value that can be compared and encoded as
+// JSON. Errors are converted to their messages.
+func labelValue(l event.Label) any {
+	switch {
+	case l.IsBytes():
+		return string(l.Bytes())
+	case l.IsDuration():
+		return l.Duration().String()
+	}
+
+	switch v := l.Interface().(type) {
+	case error:
+		return v.Error()
+	default:
+		return v
+	}
+}
@@ -0,0 +1,54 @@
+package telemetry_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/core/telemetry"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/event"
+	"golang.org/x/exp/event/eventtest"
+)
+
+func TestRecorder(t *testing.T) {
+	rec := telemetry.NewRecorder()
+	ctx := event.WithExporter(ctx, event.NewExporter(rec, eventtest.ExporterOptions()))
+
+	hits := event.NewCounter("hits", &event.MetricOptions{Description: "Earth meteorite hits"})
+	cpu := event.NewFloatGauge("cpu", &event.MetricOptions{Description: "cpu usage"})
+	latency := event.NewDuration("latency", &event.MetricOptions{Description: "request latency"})
+
+	ctx = event.Start(ctx, "checkout", event.String("user", "john"))
+	event.Log(ctx, "hello", event.Int64("age", 42), event.Value("tags", []string{"a", "b"}))
+	event.Error(ctx, "failed", errors.New("bad request"))
+	hits.Record(ctx, 1, event.String("version", "stable"))
+	hits.Record(ctx, 2, event.String("version", "stable"))
+	hits.Record(ctx, 3, event.String("version", "canary"))
+	cpu.Record(ctx, 0.5)
+	latency.Record(ctx, 100*time.Millisecond, event.String("path", "/users"))
+	latency.Record(ctx, 250*time.Millisecond, event.String("path", "/users"))
+	event.End(ctx)
+
+	t.Run("query", func(t *testing.T) {
+		rs := rec.Records()
+
+		is := assert.New(t)
+		is.Len(rs.Logs(), 2)
+		is.Len(rs.Logs().Label("age", int64(42)), 1)
+		is.Len(rs.Logs().Label("tags", []string{"a", "b"}), 1, "uncomparable values do not panic")
+		is.Len(rs.Logs().HasLabel("error"), 1)
+		is.Equal("bad request", rs.Logs().Name("failed")[0].Labels["error"])
+		is.Len(rs.Spans().Name("checkout"), 1)
+		is.Equal(6.0, rs.Metrics().Name("hits").Sum())
+		is.Equal([]float64{0.5}, rs.Metrics().Name("cpu").Values())
+
+		for _, r := range rs.Kind(telemetry.EndRecord) {
+			is.Equal("checkout", r.Name)
+		}
+		for _, r := range rs.Logs() {
+			is.Equal("checkout", r.Span)
+		}
+	})
+
+}
@@ -0,0 +1,209 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+
+	"golang.org/x/exp/event"
+)
+
+// Redacted is the replacement of the redacted values in Mask mode.
+const Redacted = "[REDACTED]"
+
+// RedactMode controls how the sensitive values are replaced.
+type RedactMode int
+
+const (
+	// Mask replaces the value with Redacted.
+	Mask RedactMode = iota
+	// Hash replaces the value with the truncated sha256 hash, so that the same
+	// values can still be correlated, e.g. sha256:2c26b46b68ff.
+	Hash
+	// Truncate keeps the first few characters of the value, e.g. john***.
+	Truncate
+)
+
+// Pattern matches the sensitive substrings in the label values.
+type Pattern struct {
+	Regexp *regexp.Regexp
+	Valid  func(s string) bool // Optional. Filters false positives from the matches.
+}
+
+var (
+	EmailPattern = &Pattern{
+		Regexp: regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`),
+	}
+	JWTPattern = &Pattern{
+		Regexp: regexp.MustCompile(`eyJ[a-zA-Z0-9_\-]+\.eyJ[a-zA-Z0-9_\-]+\.[a-zA-Z0-9_\-]*`),
+	}
+	CardNumberPattern = &Pattern{
+		Regexp: regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`),
+		Valid:  luhn,
+	}
+)
+
+// RedactHandler redacts the sensitive labels before forwarding the event to
+// the handler. Wrap the MultiHandler to redact the events for all the
+// downstream handlers:
+//
+//	h := telemetry.NewRedactHandler(&telemetry.MultiHandler{...}, "password", "token")
+type RedactHandler struct {
+	Handler  handler
+	Deny     []string   // The names of the labels to redact entirely, case-insensitive.
+	Patterns []*Pattern // Scrubs the matching substrings of the formatted values.
+	Mode     RedactMode
+	Keep     int    // The number of characters kept in Truncate mode.
+	Salt     []byte // Prepended to the value in Hash mode.
+}
+
+var _ event.Handler = (*RedactHandler)(nil)
+
+// NewRedactHandler creates a new RedactHandler that masks the denied labels,
+// as well as emails, JWTs and card numbers in any values.
+func NewRedactHandler(h handler, deny ...string) *RedactHandler {
+	return &RedactHandler{
+		Handler:  h,
+		Deny:     deny,
+		Patterns: []*Pattern{EmailPattern, JWTPattern, CardNumberPattern},
+		Mode:     Mask,
+		Keep:     4,
+	}
+}
+
+func (h *RedactHandler) Event(ctx context.Context, ev *event.Event) context.Context {
+	// Copy the event, since the labels may be shared with the caller.
+	cp := *ev
+	cp.Labels = make([]event.Label, len(ev.Labels))
+	for i, l := range ev.Labels {
+		cp.Labels[i] = h.Label(l)
+	}
+
+	return h.Handler.Event(ctx, &cp)
+}
+
+// Label returns the redacted label.
+func (h *RedactHandler) Label(l event.Label) event.Label {
+	if !l.HasValue() || l.Name == "" {
+		return l
+	}
+
+	if h.denied(l.Name) {
+		return event.String(l.Name, h.redact(labelString(l)))
+	}
+
+	switch {
+	case l.IsString():
+		if s, ok := h.scrub(l.String()); ok {
+			return event.String(l.Name, s)
+		}
+	case l.IsBytes():
+		if s, ok := h.scrub(string(l.Bytes())); ok {
+			return event.Bytes(l.Name, []byte(s))
+		}
+	default:
+		if err, ok := l.Interface().(error); ok {
+			if s, ok := h.scrub(err.Error()); ok {
+				return event.Value(l.Name, &redactedError{msg: s, err: err})
+			}
+
+			return l
+		}
+
+		// Other values, e.g. numbers, fmt.Stringer and structs, are scrubbed
+		// in the formatted form, since that is what the handlers output.
+		if s, ok := h.scrub(l.String()); ok {
+			return event.String(l.Name, s)
+		}
+	}
+
+	return l
+}
+
+// String scrubs the sensitive substrings in the string.
+func (h *RedactHandler) String(s string) string {
+	s, _ = h.scrub(s)
+	return s
+}
+
+func (h *RedactHandler) denied(name string) bool {
+	for _, d := range h.Deny {
+		if strings.EqualFold(d, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h *RedactHandler) scrub(s string) (string, bool) {
+	var changed bool
+	for _, p := range h.Patterns {
+		s = p.Regexp.ReplaceAllStringFunc(s, func(match string) string {
+			if p.Valid != nil && !p.Valid(match) {
+				return match
+			}
+
+			changed = true
+			return h.redact(match)
+		})
+	}
+
+	return s, changed
+}
+
+func (h *RedactHandler) redact(s string) string {
+	switch h.Mode {
+	case Hash:
+		b := sha256.Sum256(append(h.Salt[:len(h.Salt):len(h.Salt)], s...))
+		return "sha256:" + hex.EncodeToString(b[:6])
+	case Truncate:
+		r := []rune(s)
+		return string(r[:min(h.Keep, len(r))]) + "***"
+	default:
+		return Redacted
+	}
+}
+
+// redactedError keeps the original error for errors.Is and errors.As, while
+// reporting the redacted message.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+func labelString(l event.Label) string {
+	if l.IsBytes() {
+		return string(l.Bytes())
+	}
+
+	return l.String()
+}
+
+// luhn validates the card number checksum.
+func luhn(s string) bool {
+	var sum, n int
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+
+	return n > 0 && sum%10 == 0
+}
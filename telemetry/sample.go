@@ -0,0 +1,307 @@
+package telemetry
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/event"
+)
+
+// Sampler keeps the First events of each fingerprint per interval, and then 1
+// in every Thereafter events.
+//
+// The dropped counts are summarized by the first event after the interval
+// rolls over. Since the events may stop, the handlers can also flush the
+// dropped counts every interval with Start.
+type Sampler struct {
+	First      int
+	Thereafter int // Zero drops all events after the First.
+	Interval   time.Duration
+	KeepErrors bool // Error events are never dropped.
+	Now        func() time.Time
+
+	mu      sync.Mutex
+	start   time.Time
+	counts  map[string]int
+	dropped map[string]int64
+}
+
+func NewSampler(first, thereafter int, interval time.Duration) *Sampler {
+	return &Sampler{
+		First:      first,
+		Thereafter: thereafter,
+		Interval:   interval,
+		KeepErrors: true,
+		Now:        time.Now,
+		counts:     make(map[string]int),
+		dropped:    make(map[string]int64),
+	}
+}
+
+// Dropped is the number of events dropped for the fingerprint.
+type Dropped struct {
+	Fingerprint string
+	Count       int64
+}
+
+// Allow reports whether the event with the fingerprint is kept.
+// When the interval has rolled over, the dropped counts of the previous
+// interval are returned.
+func (s *Sampler) Allow(fingerprint string, isError bool) (bool, []Dropped) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var dropped []Dropped
+	if now := s.Now(); now.Sub(s.start) >= s.Interval {
+		dropped = s.flush()
+		s.start = now
+		clear(s.counts)
+	}
+
+	s.counts[fingerprint]++
+	n := s.counts[fingerprint]
+	if isError && s.KeepErrors {
+		return true, dropped
+	}
+	if n <= s.First || (s.Thereafter > 0 && (n-s.First)%s.Thereafter == 0) {
+		return true, dropped
+	}
+	s.dropped[fingerprint]++
+
+	return false, dropped
+}
+
+// Flush returns and resets the dropped counts, sorted by the fingerprint.
+func (s *Sampler) Flush() []Dropped {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.flush()
+}
+
+// tick calls fn with the dropped counts every interval, until stopped. The
+// remaining counts are passed to fn when stopped.
+func (s *Sampler) tick(ctx context.Context, fn func(context.Context, []Dropped)) func() {
+	ctx, cancel := context.WithCancel(ctx)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		// Without interval, the counts are only flushed when stopped.
+		var c <-chan time.Time
+		if s.Interval > 0 {
+			t := time.NewTicker(s.Interval)
+			defer t.Stop()
+			c = t.C
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				fn(context.WithoutCancel(ctx), s.Flush())
+
+				return
+			case <-c:
+				fn(ctx, s.Flush())
+			}
+		}
+	}()
+
+	return sync.OnceFunc(func() {
+		cancel()
+		wg.Wait()
+	})
+}
+
+func (s *Sampler) flush() []Dropped {
+	res := make([]Dropped, 0, len(s.dropped))
+	for k, n := range s.dropped {
+		res = append(res, Dropped{Fingerprint: k, Count: n})
+	}
+	clear(s.dropped)
+
+	slices.SortFunc(res, func(a, b Dropped) int {
+		return cmp.Compare(a.Fingerprint, b.Fingerprint)
+	})
+
+	return res
+}
+
+// DroppedMessage is the message of the logs that summarize the dropped
+// counts. The summaries are logged at the info level, with the fingerprint and
+// the dropped count.
+const DroppedMessage = "logs dropped by sampler"
+
+// SampleHandler samples the log events before forwarding them to the handler.
+// Other events are always forwarded.
+// The dropped counts are forwarded as log events, see Sampler.
+type SampleHandler struct {
+	Handler     handler
+	Sampler     *Sampler
+	Fingerprint func(ev *event.Event) string // Defaults to the message.
+}
+
+var _ event.Handler = (*SampleHandler)(nil)
+
+func NewSampleHandler(h handler, s *Sampler) *SampleHandler {
+	return &SampleHandler{
+		Handler: h,
+		Sampler: s,
+		Fingerprint: func(ev *event.Event) string {
+			return ev.Find("msg").String()
+		},
+	}
+}
+
+func (h *SampleHandler) Event(ctx context.Context, ev *event.Event) context.Context {
+	if ev.Kind != event.LogKind {
+		return h.Handler.Event(ctx, ev)
+	}
+
+	ok, dropped := h.Sampler.Allow(h.Fingerprint(ev), ev.Find("error").HasValue())
+	h.summarize(ctx, dropped)
+
+	if ok {
+		return h.Handler.Event(ctx, ev)
+	}
+
+	return ctx
+}
+
+// Flush forwards the dropped counts, e.g. before shutdown.
+func (h *SampleHandler) Flush(ctx context.Context) {
+	h.summarize(ctx, h.Sampler.Flush())
+}
+
+// Start forwards the dropped counts every interval of the sampler, so that
+// they are reported even when the events stop. The returned function stops
+// the flushing, and forwards the remaining counts.
+func (h *SampleHandler) Start(ctx context.Context) (stop func()) {
+	return h.Sampler.tick(ctx, h.summarize)
+}
+
+func (h *SampleHandler) summarize(ctx context.Context, dropped []Dropped) {
+	for _, d := range dropped {
+		h.Handler.Event(ctx, &event.Event{
+			At:   h.Sampler.Now(),
+			Kind: event.LogKind,
+			Labels: []event.Label{
+				event.String("fingerprint", d.Fingerprint),
+				event.Int64("dropped", d.Count),
+				event.String("msg", DroppedMessage),
+			},
+		})
+	}
+}
+
+// SampleSlogHandler is similar to SampleHandler, but for slog.Handler.
+// The dropped counts are logged through the handler passed to the
+// constructor, without the attrs and groups of the derived handlers, since the
+// sampler is shared by them.
+type SampleSlogHandler struct {
+	Handler     slog.Handler
+	Sampler     *Sampler
+	Fingerprint func(r slog.Record) string // Defaults to the message.
+
+	root slog.Handler
+}
+
+var _ slog.Handler = (*SampleSlogHandler)(nil)
+
+func NewSampleSlogHandler(h slog.Handler, s *Sampler) *SampleSlogHandler {
+	return &SampleSlogHandler{
+		Handler: h,
+		Sampler: s,
+		Fingerprint: func(r slog.Record) string {
+			return r.Message
+		},
+		root: h,
+	}
+}
+
+func (h *SampleSlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.Handler.Enabled(ctx, level)
+}
+
+func (h *SampleSlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	ok, dropped := h.Sampler.Allow(h.Fingerprint(r), r.Level >= slog.LevelError)
+	if err := h.summarize(ctx, dropped); err != nil {
+		return err
+	}
+
+	if ok {
+		return h.Handler.Handle(ctx, r)
+	}
+
+	return nil
+}
+
+// WithAttrs shares the sampler with the new handler.
+func (h *SampleSlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &SampleSlogHandler{
+		Handler:     h.Handler.WithAttrs(attrs),
+		Sampler:     h.Sampler,
+		Fingerprint: h.Fingerprint,
+		root:        h.rootHandler(),
+	}
+}
+
+// WithGroup shares the sampler with the new handler.
+func (h *SampleSlogHandler) WithGroup(name string) slog.Handler {
+	return &SampleSlogHandler{
+		Handler:     h.Handler.WithGroup(name),
+		Sampler:     h.Sampler,
+		Fingerprint: h.Fingerprint,
+		root:        h.rootHandler(),
+	}
+}
+
+// Flush logs the dropped counts, e.g. before shutdown.
+func (h *SampleSlogHandler) Flush(ctx context.Context) error {
+	return h.summarize(ctx, h.Sampler.Flush())
+}
+
+// Start logs the dropped counts every interval of the sampler, so that they
+// are reported even when the logs stop. The returned function stops the
+// flushing, and logs the remaining counts. The errors of the handler are
+// ignored, since there is no caller to return them to.
+func (h *SampleSlogHandler) Start(ctx context.Context) (stop func()) {
+	return h.Sampler.tick(ctx, func(ctx context.Context, dropped []Dropped) {
+		_ = h.summarize(ctx, dropped)
+	})
+}
+
+func (h *SampleSlogHandler) summarize(ctx context.Context, dropped []Dropped) error {
+	root := h.rootHandler()
+	if len(dropped) == 0 || !root.Enabled(ctx, slog.LevelInfo) {
+		return nil
+	}
+
+	for _, d := range dropped {
+		r := slog.NewRecord(h.Sampler.Now(), slog.LevelInfo, DroppedMessage, 0)
+		r.AddAttrs(
+			slog.String("fingerprint", d.Fingerprint),
+			slog.Int64("dropped", d.Count),
+		)
+		if err := root.Handle(ctx, r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rootHandler returns the handler without the attrs and groups.
+func (h *SampleSlogHandler) rootHandler() slog.Handler {
+	if h.root == nil {
+		return h.Handler
+	}
+
+	return h.root
+}
@@ -0,0 +1,192 @@
+package telemetry_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/core/telemetry"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/event"
+	"golang.org/x/exp/event/eventtest"
+	xslog "golang.org/x/exp/slog"
+)
+
+func TestSampler(t *testing.T) {
+	now := time.Now()
+	s := telemetry.NewSampler(2, 3, time.Second)
+	s.Now = func() time.Time { return now }
+
+	var kept []bool
+	for range 8 {
+		ok, _ := s.Allow("hello", false)
+		kept = append(kept, ok)
+	}
+
+	is := assert.New(t)
+	is.Equal([]bool{true, true, false, false, true, false, false, true}, kept)
+
+	ok, _ := s.Allow("hello", true)
+	is.True(ok, "errors are kept")
+
+	ok, _ = s.Allow("world", false)
+	is.True(ok, "fingerprints are sampled separately")
+
+	now = now.Add(time.Second)
+	ok, dropped := s.Allow("hello", false)
+	is.True(ok, "counts are reset after the interval")
+	is.Equal([]telemetry.Dropped{{Fingerprint: "hello", Count: 4}}, dropped)
+	is.Empty(s.Flush())
+}
+
+func TestSampleHandler(t *testing.T) {
+	now := time.Now()
+	s := telemetry.NewSampler(1, 0, time.Minute)
+	s.Now = func() time.Time { return now }
+
+	var buf bytes.Buffer
+	logger := xslog.New(xslog.NewTextHandler(&buf, &xslog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a xslog.Attr) xslog.Attr {
+			if a.Key == xslog.TimeKey || a.Key == xslog.SourceKey {
+				return xslog.Attr{}
+			}
+			return a
+		},
+	}))
+	h := telemetry.NewSampleHandler(&telemetry.MultiHandler{
+		Log: telemetry.NewSlogHandler(logger),
+	}, s)
+	ctx := event.WithExporter(ctx, event.NewExporter(h, eventtest.ExporterOptions()))
+
+	for range 3 {
+		event.Log(ctx, "hello")
+		event.Error(ctx, "failed", errors.New("bad request"))
+	}
+	h.Flush(ctx)
+
+	is := assert.New(t)
+	want := `level=INFO msg=hello
+level=ERROR msg=failed error="bad request"
+level=ERROR msg=failed error="bad request"
+level=ERROR msg=failed error="bad request"
+level=INFO msg="logs dropped by sampler" fingerprint=hello dropped=2
+`
+	is.Equal(want, buf.String())
+}
+
+func TestSampleSlogHandler(t *testing.T) {
+	now := time.Now()
+	s := telemetry.NewSampler(1, 0, time.Minute)
+	s.Now = func() time.Time { return now }
+
+	var buf bytes.Buffer
+	h := telemetry.NewSampleSlogHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}), s)
+	logger := slog.New(h).With("service", "api")
+
+	for range 3 {
+		logger.Info("hello")
+		logger.Error("failed")
+	}
+
+	now = now.Add(time.Minute)
+	logger.Info("hello")
+
+	is := assert.New(t)
+	want := `level=INFO msg=hello service=api
+level=ERROR msg=failed service=api
+level=ERROR msg=failed service=api
+level=ERROR msg=failed service=api
+level=INFO msg="logs dropped by sampler" fingerprint=hello dropped=2
+level=INFO msg=hello service=api
+`
+	is.Equal(want, buf.String())
+}
+
+func TestSampleSlogHandler_Start(t *testing.T) {
+	s := telemetry.NewSampler(1, 0, 10*time.Millisecond)
+
+	var mu sync.Mutex
+	var buf bytes.Buffer
+	h := telemetry.NewSampleSlogHandler(slog.NewTextHandler(&syncWriter{mu: &mu, w: &buf}, nil), s)
+	stop := h.Start(ctx)
+	defer stop()
+
+	logger := slog.New(h)
+	for range 3 {
+		logger.Info("hello")
+	}
+
+	// The dropped counts are logged without further logs. The ticker may
+	// split the counts, so only the total is compared.
+	dropped := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var n int
+		for _, m := range regexp.MustCompile(`dropped=(\d+)`).FindAllStringSubmatch(buf.String(), -1) {
+			i, _ := strconv.Atoi(m[1])
+			n += i
+		}
+
+		return n
+	}
+	assert.Eventually(t, func() bool {
+		return dropped() == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSampleHandler_Stop(t *testing.T) {
+	s := telemetry.NewSampler(1, 0, time.Hour)
+
+	var buf bytes.Buffer
+	logger := xslog.New(xslog.NewTextHandler(&buf, &xslog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a xslog.Attr) xslog.Attr {
+			if a.Key == xslog.TimeKey || a.Key == xslog.SourceKey {
+				return xslog.Attr{}
+			}
+			return a
+		},
+	}))
+	h := telemetry.NewSampleHandler(&telemetry.MultiHandler{
+		Log: telemetry.NewSlogHandler(logger),
+	}, s)
+	stop := h.Start(ctx)
+	ctx := event.WithExporter(ctx, event.NewExporter(h, eventtest.ExporterOptions()))
+
+	for range 3 {
+		event.Log(ctx, "hello")
+	}
+
+	// The remaining counts are flushed when stopped.
+	stop()
+	stop()
+
+	want := `level=INFO msg=hello
+level=INFO msg="logs dropped by sampler" fingerprint=hello dropped=2
+`
+	assert.Equal(t, want, buf.String())
+}
+
+type syncWriter struct {
+	mu *sync.Mutex
+	w  *bytes.Buffer
+}
+
+func (w *syncWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.w.Write(b)
+}
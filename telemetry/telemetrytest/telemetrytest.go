@@ -0,0 +1,98 @@
+// Package telemetrytest provides the assertions for the events captured by
+// the telemetry.Recorder.
+package telemetrytest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/alextanhongpin/core/telemetry"
+)
+
+// UpdateEnv is the environment variable to create or update the golden
+// files, e.g. UPDATE_SNAPSHOT=true go test ./...
+const UpdateEnv = "UPDATE_SNAPSHOT"
+
+// AssertCounter asserts the total of the counter with the labels, e.g.
+//
+//	telemetrytest.AssertCounter(t, rec, "hits", 3, "version", "stable")
+func AssertCounter(t testing.TB, rec *telemetry.Recorder, name string, want float64, labelValues ...any) {
+	t.Helper()
+
+	got := metric(rec, telemetry.CounterMetric, name, labelValues...).Sum()
+	if got != want {
+		t.Errorf("counter %q: want %v, got %v", name, want, got)
+	}
+}
+
+// AssertHistogram asserts the observations of the histogram with the labels,
+// in the order they are recorded.
+func AssertHistogram(t testing.TB, rec *telemetry.Recorder, name string, want []float64, labelValues ...any) {
+	t.Helper()
+
+	got := metric(rec, telemetry.HistogramMetric, name, labelValues...).Values()
+	if !slices.Equal(want, got) {
+		t.Errorf("histogram %q: want %v, got %v", name, want, got)
+	}
+}
+
+// Snapshot compares the recorded events with the golden file at
+// testdata/<test name>.json.
+// The test fails if the golden file does not exist, unless UpdateEnv is set,
+// which writes the recorded events to the golden file instead.
+func Snapshot(t testing.TB, rec *telemetry.Recorder) {
+	t.Helper()
+
+	b, err := json.MarshalIndent(rec.Records(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = append(b, '\n')
+
+	name := filepath.Join("testdata", t.Name()+".json")
+	if update() {
+		if err := os.MkdirAll(filepath.Dir(name), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		return
+	}
+
+	want, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("snapshot %s does not exist, run the test with %s=true to create it", name, UpdateEnv)
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(want, b) {
+		t.Errorf("snapshot %s mismatch:\nwant:\n%s\ngot:\n%s", name, want, b)
+	}
+}
+
+func update() bool {
+	ok, _ := strconv.ParseBool(os.Getenv(UpdateEnv))
+	return ok
+}
+
+func metric(rec *telemetry.Recorder, typ, name string, labelValues ...any) telemetry.Records {
+	rs := rec.Records().Metrics().Name(name).Filter(func(r telemetry.Record) bool {
+		return r.Metric == typ
+	})
+	for i := 0; i+1 < len(labelValues); i += 2 {
+		rs = rs.Label(labelValues[i].(string), labelValues[i+1])
+	}
+
+	return rs
+}
@@ -0,0 +1,102 @@
+package telemetrytest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/core/telemetry"
+	"github.com/alextanhongpin/core/telemetry/telemetrytest"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/event"
+	"golang.org/x/exp/event/eventtest"
+)
+
+func TestAssert(t *testing.T) {
+	rec := record(t)
+
+	t.Run("counter", func(t *testing.T) {
+		telemetrytest.AssertCounter(t, rec, "hits", 6)
+		telemetrytest.AssertCounter(t, rec, "hits", 3, "version", "stable")
+	})
+
+	t.Run("histogram", func(t *testing.T) {
+		telemetrytest.AssertHistogram(t, rec, "latency", []float64{0.1, 0.25}, "path", "/users")
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		ft := &fakeT{TB: t}
+		telemetrytest.AssertCounter(ft, rec, "hits", 1)
+		telemetrytest.AssertHistogram(ft, rec, "latency", []float64{0.1})
+
+		is := assert.New(t)
+		is.Equal([]string{
+			`counter "hits": want 1, got 6`,
+			`histogram "latency": want [0.1], got [0.1 0.25]`,
+		}, ft.errors)
+	})
+}
+
+func TestSnapshot(t *testing.T) {
+	t.Setenv(telemetrytest.UpdateEnv, "")
+	rec := record(t)
+
+	t.Run("recorded", func(t *testing.T) {
+		telemetrytest.Snapshot(t, rec)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		ft := &fakeT{TB: t}
+		telemetrytest.Snapshot(ft, rec)
+
+		is := assert.New(t)
+		is.Len(ft.errors, 1)
+		is.Contains(ft.errors[0], "does not exist")
+	})
+}
+
+func record(t *testing.T) *telemetry.Recorder {
+	t.Helper()
+
+	rec := telemetry.NewRecorder()
+	ctx := event.WithExporter(context.Background(), event.NewExporter(rec, eventtest.ExporterOptions()))
+
+	hits := event.NewCounter("hits", &event.MetricOptions{Description: "Earth meteorite hits"})
+	cpu := event.NewFloatGauge("cpu", &event.MetricOptions{Description: "cpu usage"})
+	latency := event.NewDuration("latency", &event.MetricOptions{Description: "request latency"})
+
+	ctx = event.Start(ctx, "checkout", event.String("user", "john"))
+	event.Log(ctx, "hello", event.Int64("age", 42))
+	event.Error(ctx, "failed", errors.New("bad request"))
+	hits.Record(ctx, 1, event.String("version", "stable"))
+	hits.Record(ctx, 2, event.String("version", "stable"))
+	hits.Record(ctx, 3, event.String("version", "canary"))
+	cpu.Record(ctx, 0.5)
+	latency.Record(ctx, 100*time.Millisecond, event.String("path", "/users"))
+	latency.Record(ctx, 250*time.Millisecond, event.String("path", "/users"))
+	event.End(ctx)
+
+	return rec
+}
+
+// fakeT records the failures instead of failing the test.
+type fakeT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) Fatalf(format string, args ...any) {
+	t.Errorf(format, args...)
+}
+
+func (t *fakeT) Fatal(args ...any) {
+	t.errors = append(t.errors, fmt.Sprint(args...))
+}
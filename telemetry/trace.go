@@ -0,0 +1,147 @@
+// This is a modified version of https://pkg.go.dev/golang.org/x/exp/event@v0.0.0-20230817173708-d852ddb80c63/otel, since the supported OTEL package is no longer the latest.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/event"
+)
+
+// TraceHandler is an event.Handler for OpenTelemetry traces.
+// Start events starts a span, and End events ends it. Log events within the
+// span are added as span events, and errors are recorded.
+type TraceHandler struct {
+	tracer trace.Tracer
+}
+
+var _ event.Handler = (*TraceHandler)(nil)
+
+// NewTraceHandler creates a new TraceHandler.
+func NewTraceHandler(t trace.Tracer) *TraceHandler {
+	return &TraceHandler{tracer: t}
+}
+
+type spanKey struct{}
+
+func (t *TraceHandler) Event(ctx context.Context, ev *event.Event) context.Context {
+	switch ev.Kind {
+	case event.StartKind:
+		name, opts := labelsToSpanStartOptions(ev.Labels)
+		ctx, span := t.tracer.Start(ctx, name, opts...)
+		return context.WithValue(ctx, spanKey{}, span)
+	case event.EndKind:
+		// Unlike the original version, End without Start is ignored instead
+		// of panicking.
+		span, ok := ctx.Value(spanKey{}).(trace.Span)
+		if !ok {
+			return ctx
+		}
+		span.SetAttributes(labelsToSpanAttributes(ev.Labels)...)
+		recordError(span, ev.Labels)
+		span.End()
+		return ctx
+	case event.LogKind:
+		span := trace.SpanFromContext(ctx)
+		if !span.IsRecording() {
+			return ctx
+		}
+		span.AddEvent(ev.Find("msg").String(), trace.WithAttributes(labelsToSpanAttributes(ev.Labels)...))
+		recordError(span, ev.Labels)
+		return ctx
+	default:
+		return ctx
+	}
+}
+
+func recordError(span trace.Span, ls []event.Label) {
+	l := findLabel(ls, "error")
+	if !l.HasValue() {
+		return
+	}
+
+	err, ok := l.Interface().(error)
+	if !ok {
+		err = fmt.Errorf("%v", l.Interface())
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+func labelsToSpanStartOptions(ls []event.Label) (string, []trace.SpanStartOption) {
+	var opts []trace.SpanStartOption
+	var name string
+	var attrs []attribute.KeyValue
+	for _, l := range ls {
+		switch l.Name {
+		case "link":
+			opts = append(opts, trace.WithLinks(l.Interface().(trace.Link)))
+		case "newRoot":
+			opts = append(opts, trace.WithNewRoot())
+		case "spanKind":
+			opts = append(opts, trace.WithSpanKind(l.Interface().(trace.SpanKind)))
+		case "name":
+			name = l.String()
+		default:
+			if attr, ok := spanAttribute(l); ok {
+				attrs = append(attrs, attr)
+			}
+		}
+	}
+	if len(attrs) > 0 {
+		opts = append(opts, trace.WithAttributes(attrs...))
+	}
+	return name, opts
+}
+
+func labelsToSpanAttributes(ls []event.Label) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	for _, l := range ls {
+		switch l.Name {
+		case "msg", "error", string(event.DurationMetric):
+			continue
+		}
+		if attr, ok := spanAttribute(l); ok {
+			attrs = append(attrs, attr)
+		}
+	}
+	return attrs
+}
+
+// spanAttribute is similar to labelToAttribute, but formats the unsupported
+// types instead of panicking.
+func spanAttribute(l event.Label) (attribute.KeyValue, bool) {
+	if !l.HasValue() || l.Name == "" {
+		return attribute.KeyValue{}, false
+	}
+
+	switch {
+	case l.IsString():
+		return attribute.String(l.Name, l.String()), true
+	case l.IsBytes():
+		return attribute.String(l.Name, string(l.Bytes())), true
+	case l.IsInt64():
+		return attribute.Int64(l.Name, l.Int64()), true
+	case l.IsUint64():
+		return attribute.Int64(l.Name, int64(l.Uint64())), true
+	case l.IsFloat64():
+		return attribute.Float64(l.Name, l.Float64()), true
+	case l.IsBool():
+		return attribute.Bool(l.Name, l.Bool()), true
+	default:
+		return attribute.String(l.Name, fmt.Sprint(l.Interface())), true
+	}
+}
+
+// findLabel finds the last label with the given name.
+func findLabel(ls []event.Label, name string) event.Label {
+	for i := len(ls) - 1; i >= 0; i-- {
+		if ls[i].Name == name {
+			return ls[i]
+		}
+	}
+	return event.Label{}
+}
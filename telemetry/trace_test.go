@@ -0,0 +1,90 @@
+package telemetry_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alextanhongpin/core/telemetry"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang.org/x/exp/event"
+	"golang.org/x/exp/event/eventtest"
+)
+
+func TestTrace(t *testing.T) {
+	newTracer := func() (*telemetry.TraceHandler, *tracetest.SpanRecorder) {
+		sr := tracetest.NewSpanRecorder()
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+		return telemetry.NewTraceHandler(tp.Tracer("test")), sr
+	}
+
+	t.Run("span", func(t *testing.T) {
+		h, sr := newTracer()
+		ctx := event.WithExporter(ctx, event.NewExporter(h, eventtest.ExporterOptions()))
+		ctx = event.Start(ctx, "parent", event.String("user", "john"))
+		child := event.Start(ctx, "child", event.Int64("n", 42))
+		event.Log(child, "hello", event.String("greeting", "world"))
+		event.End(child)
+		event.End(ctx, event.Bool("ok", true))
+
+		spans := sr.Ended()
+		is := assert.New(t)
+		is.Len(spans, 2)
+
+		is.Equal("child", spans[0].Name())
+		is.Equal([]attribute.KeyValue{attribute.Int64("n", 42)}, spans[0].Attributes())
+		is.Len(spans[0].Events(), 1)
+		is.Equal("hello", spans[0].Events()[0].Name)
+		is.Equal([]attribute.KeyValue{attribute.String("greeting", "world")}, spans[0].Events()[0].Attributes)
+
+		is.Equal("parent", spans[1].Name())
+		is.Equal([]attribute.KeyValue{
+			attribute.String("user", "john"),
+			attribute.Bool("ok", true),
+		}, spans[1].Attributes())
+		is.Equal(spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+		is.Equal(spans[1].SpanContext().TraceID(), spans[0].SpanContext().TraceID())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		h, sr := newTracer()
+		ctx := event.WithExporter(ctx, event.NewExporter(h, eventtest.ExporterOptions()))
+		ctx = event.Start(ctx, "error")
+		event.Error(ctx, "failed", errors.New("bad request"))
+		event.End(ctx)
+
+		spans := sr.Ended()
+		is := assert.New(t)
+		is.Len(spans, 1)
+		is.Equal(codes.Error, spans[0].Status().Code)
+		is.Equal("bad request", spans[0].Status().Description)
+
+		events := spans[0].Events()
+		is.Len(events, 2)
+		is.Equal("failed", events[0].Name)
+		is.Equal("exception", events[1].Name)
+	})
+
+	t.Run("end without start", func(t *testing.T) {
+		h, sr := newTracer()
+		ctx := event.WithExporter(ctx, event.NewExporter(h, eventtest.ExporterOptions()))
+		event.End(ctx)
+
+		is := assert.New(t)
+		is.Empty(sr.Ended())
+	})
+
+	t.Run("multi handler", func(t *testing.T) {
+		h, sr := newTracer()
+		multi := &telemetry.MultiHandler{Trace: h}
+		ctx := event.WithExporter(ctx, event.NewExporter(multi, eventtest.ExporterOptions()))
+		ctx = event.Start(ctx, "multi")
+		event.End(ctx)
+
+		is := assert.New(t)
+		is.Len(sr.Ended(), 1)
+	})
+}
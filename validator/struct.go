@@ -0,0 +1,104 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// TagName is the struct tag of the field expressions, e.g.
+//
+//	type CreateUserRequest struct {
+//		Email string `json:"email" validate:"email,max=100"`
+//		Age   int    `json:"age" validate:"optional,min=13"`
+//	}
+//
+//	func (r *CreateUserRequest) Validate() error {
+//		return validator.Struct(r)
+//	}
+//
+// The http/openapi package reads the same tag to describe the constraints,
+// so that the validation and the document share the same rules.
+const TagName = "validate"
+
+// Struct validates the fields of the struct with the expressions in the
+// validate tag. The errors are keyed by the json name of the fields.
+// Fields without the tag are skipped.
+func Struct(v any) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validator: expected struct, got %T", v))
+	}
+
+	errs := make(map[string]error)
+	validateFields(rv, errs)
+
+	return NewErrors(errs)
+}
+
+func validateFields(rv reflect.Value, errs map[string]error) {
+	t := rv.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		fv := rv.Field(i)
+		if f.Anonymous && f.Tag.Get("json") == "" && indirect(f.Type).Kind() == reflect.Struct {
+			if fv = reflect.Indirect(fv); fv.IsValid() {
+				validateFields(fv, errs)
+			}
+
+			continue
+		}
+
+		expr, ok := f.Tag.Lookup(TagName)
+		if !ok || !f.IsExported() {
+			continue
+		}
+
+		errs[jsonName(f)] = validateField(f, reflect.Indirect(fv), expr)
+	}
+}
+
+func validateField(f reflect.StructField, fv reflect.Value, expr string) error {
+	t := indirect(f.Type)
+	if !fv.IsValid() {
+		// Nil pointers are validated as the zero value.
+		fv = reflect.Zero(t)
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return StringExpr(expr).Validate(fv.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NumberExpr[int64](expr).Validate(fv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return NumberExpr[uint64](expr).Validate(fv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return NumberExpr[float64](expr).Validate(fv.Float())
+	case reflect.Slice, reflect.Array:
+		vs := make([]any, fv.Len())
+		for i := range vs {
+			vs[i] = fv.Index(i).Interface()
+		}
+
+		return SliceExpr[any](expr).Validate(vs)
+	default:
+		panic(fmt.Sprintf("validator: unsupported type %s of field %q", f.Type, f.Name))
+	}
+}
+
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return f.Name
+	}
+
+	return name
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t
+}
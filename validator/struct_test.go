@@ -0,0 +1,55 @@
+package validator_test
+
+import (
+	"testing"
+
+	"github.com/alextanhongpin/core/validator"
+	"github.com/stretchr/testify/assert"
+)
+
+type address struct {
+	Country string `json:"country" validate:"len=2"`
+}
+
+type createUserRequest struct {
+	address
+	Email   string   `json:"email" validate:"email,max=100"`
+	Age     *int     `json:"age" validate:"optional,min=13"`
+	Score   float64  `json:"score" validate:"optional,between=0 1"`
+	Tags    []string `json:"tags" validate:"optional,max=2"`
+	Comment string   `json:"comment"`
+}
+
+func TestStruct(t *testing.T) {
+	age := 18
+	valid := createUserRequest{
+		address: address{Country: "MY"},
+		Email:   "john.doe@mail.com",
+		Age:     &age,
+		Tags:    []string{"a"},
+	}
+
+	is := assert.New(t)
+	is.Nil(validator.Struct(valid))
+	is.Nil(validator.Struct(&valid))
+
+	age = 12
+	invalid := createUserRequest{
+		Age:   &age,
+		Score: 2,
+		Tags:  []string{"a", "b", "c"},
+	}
+	is.Equal(validator.Errors{
+		"age":     "min 13",
+		"country": "must not be empty",
+		"email":   "must not be empty",
+		"score":   "must be between 0 and 1",
+		"tags":    "max items is 2",
+	}, validator.Struct(invalid))
+}
+
+func TestStruct_NotStruct(t *testing.T) {
+	assert.Panics(t, func() {
+		_ = validator.Struct("foo")
+	})
+}